@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+	_ "github.com/sniperkit/colly-storage/plugin/backend/badger"
+	_ "github.com/sniperkit/colly-storage/plugin/backend/boltdb"
+	_ "github.com/sniperkit/colly-storage/plugin/backend/boltdb_bbolt"
+)
+
+const usage = `Usage: storage <command> [flags] <dsn>
+
+Commands:
+  advise    measure the codecs on sampled values and recommend a policy
+
+The dsn selects the store, e.g. "badger:///var/lib/colly?read-only=true",
+see storage.ParseDSN. Run "storage <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "advise":
+		err = advise(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "storage: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "storage:", err)
+		os.Exit(1)
+	}
+}
+
+// advise runs compress.Advise on the store of the dsn.
+func advise(args []string) error {
+	fs := flag.NewFlagSet("advise", flag.ExitOnError)
+	samples := fs.Int("samples", compress.DefaultAdviseSamples, "number of values sampled")
+	codecs := fs.String("codecs", "", "comma separated codecs to try, e.g. gzip,zstd:3 (default all)")
+	minSize := fs.Int("min-size", compress.DefaultMinSize, "size under which the values are stored raw")
+	minThroughput := fs.Float64("min-throughput", compress.DefaultMinThroughput, "encoding throughput of the codecs recommended, in bytes/s")
+	minSaving := fs.Float64("min-saving", compress.DefaultMinSaving, "fraction of the size the codecs recommended save")
+	asJSON := fs.Bool("json", false, "print the recommended policy as JSON only")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: storage advise [flags] <dsn>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	store, err := storage.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	opts := compress.AdviseOptions{
+		Samples:       *samples,
+		MinSize:       *minSize,
+		MinThroughput: *minThroughput,
+		MinSaving:     *minSaving,
+	}
+	if *codecs != "" {
+		opts.Codecs = strings.Split(*codecs, ",")
+	}
+	report, err := compress.Advise(store, opts)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report.Policy)
+	}
+	return report.Print(os.Stdout)
+}
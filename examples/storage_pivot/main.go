@@ -69,8 +69,19 @@ func main() {
 	if storageDebug {
 		pp.Println("Storage=", store)
 	} else {
-		store.Action("ping", storagePingDuration)
-		store.Action("list_collections", nil)
+		for _, action := range storage.ListActions(store) {
+			fmt.Println("action:", action)
+		}
+		if _, err := storage.DoAction(store, "ping", storagePingDuration); err != nil {
+			fmt.Println("error while pinging the storage backend... error=", err)
+			os.Exit(1)
+		}
+		collections, err := storage.DoAction(store, "getCollections")
+		if err != nil {
+			fmt.Println("error while listing the collections... error=", err)
+			os.Exit(1)
+		}
+		fmt.Println("collections:", collections.([]string))
 	}
 
 }
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ActionSpec describes an action supported by a store.
+type ActionSpec struct {
+	// Name is the name the action is called with, e.g. "getKeys".
+	Name string
+	// Description is a one-line summary of what the action does.
+	Description string
+	// Args are the types of the arguments, in order.
+	Args []reflect.Type
+	// Result is the type of the value returned by the action, nil if it
+	// returns nothing.
+	Result reflect.Type
+}
+
+// String returns the signature of the action, e.g. "ping(time.Duration)".
+func (a ActionSpec) String() string {
+	s := a.Name + "("
+	for i, arg := range a.Args {
+		if i > 0 {
+			s += ", "
+		}
+		s += arg.String()
+	}
+	s += ")"
+	if a.Result != nil {
+		s += " " + a.Result.String()
+	}
+	return s
+}
+
+// ActionFunc runs an action. The arguments have been checked against the
+// ActionSpec it is registered with.
+type ActionFunc func(args ...interface{}) (interface{}, error)
+
+// Actioner is implemented by the stores declaring their actions.
+type Actioner interface {
+	// Actions lists the actions supported by the store, sorted by name.
+	Actions() []ActionSpec
+	// Do runs an action and returns its typed result.
+	Do(name string, args ...interface{}) (interface{}, error)
+}
+
+type action struct {
+	spec ActionSpec
+	fn   ActionFunc
+}
+
+// ActionRegistry holds the actions of a store. Backends embed one and
+// register their actions when they are created.
+type ActionRegistry struct {
+	mu      sync.RWMutex
+	actions map[string]action
+}
+
+// NewActionRegistry returns an empty registry.
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{actions: make(map[string]action)}
+}
+
+// Register adds an action to the registry.
+func (r *ActionRegistry) Register(spec ActionSpec, fn ActionFunc) error {
+	if spec.Name == "" {
+		return fmt.Errorf("storage: action without name")
+	}
+	if fn == nil {
+		return fmt.Errorf("storage: action %q has no function", spec.Name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.actions[spec.Name]; ok {
+		return fmt.Errorf("storage: action %q is already registered", spec.Name)
+	}
+	r.actions[spec.Name] = action{spec: spec, fn: fn}
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (r *ActionRegistry) MustRegister(spec ActionSpec, fn ActionFunc) {
+	if err := r.Register(spec, fn); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the spec of an action.
+func (r *ActionRegistry) Lookup(name string) (ActionSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.actions[name]
+	return a.spec, ok
+}
+
+// Actions implements Actioner.Actions().
+func (r *ActionRegistry) Actions() []ActionSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	specs := make([]ActionSpec, 0, len(r.actions))
+	for _, a := range r.actions {
+		specs = append(specs, a.spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Do implements Actioner.Do(). It returns ErrUnknownAction for the names
+// which are not registered and ErrInvalidArgs when the arguments don't
+// match the spec of the action.
+func (r *ActionRegistry) Do(name string, args ...interface{}) (interface{}, error) {
+	r.mu.RLock()
+	a, ok := r.actions[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAction, name)
+	}
+	if err := checkArgs(a.spec, args); err != nil {
+		return nil, err
+	}
+	return a.fn(args...)
+}
+
+// Action runs an action through the legacy Storage.Action() signature, the
+// result is returned under the name of the action.
+func (r *ActionRegistry) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	result, err := r.Do(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*interface{}{name: &result}, nil
+}
+
+// ActionKeys returns keys in the shape of the legacy Storage.Action("getKeys")
+// result: the keys of the map, with nil values.
+func ActionKeys(keys []string) map[string]*interface{} {
+	resp := make(map[string]*interface{}, len(keys))
+	for _, key := range keys {
+		resp[key] = nil
+	}
+	return resp
+}
+
+func checkArgs(spec ActionSpec, args []interface{}) error {
+	if len(args) != len(spec.Args) {
+		return fmt.Errorf("%w: %s expects %d arguments, got %d", ErrInvalidArgs, spec, len(spec.Args), len(args))
+	}
+	for i, arg := range args {
+		want := spec.Args[i]
+		if arg == nil {
+			switch want.Kind() {
+			case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+				continue
+			}
+			return fmt.Errorf("%w: %s argument %d can't be nil", ErrInvalidArgs, spec, i)
+		}
+		if got := reflect.TypeOf(arg); !got.AssignableTo(want) {
+			return fmt.Errorf("%w: %s argument %d is a %s", ErrInvalidArgs, spec, i, got)
+		}
+	}
+	return nil
+}
+
+// ListActions returns the actions supported by s, nil if it doesn't declare
+// them.
+func ListActions(s Storage) []ActionSpec {
+	if a, ok := s.(Actioner); ok {
+		return a.Actions()
+	}
+	return nil
+}
+
+// DoAction runs an action on s. It returns ErrUnknownAction if s doesn't
+// declare its actions.
+func DoAction(s Storage, name string, args ...interface{}) (interface{}, error) {
+	if a, ok := s.(Actioner); ok {
+		return a.Do(name, args...)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownAction, name)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestActionRegistry(t *testing.T) {
+	r := NewActionRegistry()
+	r.MustRegister(ActionSpec{
+		Name:   "double",
+		Args:   []reflect.Type{reflect.TypeOf(0)},
+		Result: reflect.TypeOf(0),
+	}, func(args ...interface{}) (interface{}, error) {
+		return args[0].(int) * 2, nil
+	})
+	r.MustRegister(ActionSpec{
+		Name: "ping",
+		Args: []reflect.Type{reflect.TypeOf(time.Duration(0))},
+	}, func(args ...interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	if err := r.Register(ActionSpec{Name: "ping"}, func(args ...interface{}) (interface{}, error) { return nil, nil }); err == nil {
+		t.Error("expected an error for a duplicated action")
+	}
+
+	specs := r.Actions()
+	if len(specs) != 2 || specs[0].Name != "double" || specs[1].Name != "ping" {
+		t.Errorf("unexpected actions: %v", specs)
+	}
+	if s := specs[0].String(); s != "double(int) int" {
+		t.Errorf("unexpected signature: %q", s)
+	}
+
+	v, err := r.Do("double", 21)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.(int) != 42 {
+		t.Errorf("expected 42, got %v", v)
+	}
+	if _, err := r.Do("double", "21"); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+	if _, err := r.Do("double"); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+	if _, err := r.Do("ping", nil); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs for a nil duration, got %v", err)
+	}
+	if _, err := r.Do("list_collections"); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("expected ErrUnknownAction, got %v", err)
+	}
+
+	legacy, err := r.Action("double", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res := legacy["double"]; res == nil || (*res).(int) != 2 {
+		t.Errorf("unexpected legacy result: %v", legacy)
+	}
+}
+
+func TestInMemoryActions(t *testing.T) {
+	s, _ := NewInMemoryStorage(nil)
+	s.Set("b", []byte("2"))
+	s.Set("a", []byte("1"))
+
+	names := []string{}
+	for _, spec := range ListActions(s) {
+		names = append(names, spec.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"getKeys", "getStats"}) {
+		t.Errorf("unexpected actions: %v", names)
+	}
+	keys, err := DoAction(s, "getKeys")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(keys.([]string), []string{"a", "b"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	if ListActions(newMapStorage()) != nil {
+		t.Error("expected no actions for a store without registry")
+	}
+	if _, err := DoAction(newMapStorage(), "getKeys"); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("expected ErrUnknownAction, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BatchOp is a write or a delete of a Batch.
+type BatchOp struct {
+	Key    string
+	Value  []byte
+	Delete bool
+	// Persist stores the value without expiry, whatever the default TTL of
+	// the store, e.g. for the internal keys shared by expiring entries.
+	Persist bool
+}
+
+// TTL returns the time to live of the value of op in a store expiring its
+// entries after ttl by default.
+func (op BatchOp) TTL(ttl time.Duration) time.Duration {
+	if op.Persist {
+		return 0
+	}
+	return ttl
+}
+
+// Batch groups writes and deletes applied together by a Batcher. The
+// operations are applied in order, the last one wins for a key present
+// several times.
+type Batch struct {
+	Ops []BatchOp
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// SetBatch returns a batch storing kvs.
+func SetBatch(kvs []KV) *Batch {
+	b := &Batch{Ops: make([]BatchOp, 0, len(kvs))}
+	for _, kv := range kvs {
+		b.Set(kv.Key, kv.Value)
+	}
+	return b
+}
+
+// DeleteBatch returns a batch deleting keys.
+func DeleteBatch(keys []string) *Batch {
+	b := &Batch{Ops: make([]BatchOp, 0, len(keys))}
+	for _, key := range keys {
+		b.Delete(key)
+	}
+	return b
+}
+
+// Set adds a write to the batch.
+func (b *Batch) Set(key string, value []byte) {
+	b.Ops = append(b.Ops, BatchOp{Key: key, Value: value})
+}
+
+// SetPersistent adds a write without expiry to the batch, see
+// BatchOp.Persist.
+func (b *Batch) SetPersistent(key string, value []byte) {
+	b.Ops = append(b.Ops, BatchOp{Key: key, Value: value, Persist: true})
+}
+
+// Delete adds a delete to the batch.
+func (b *Batch) Delete(key string) {
+	b.Ops = append(b.Ops, BatchOp{Key: key, Delete: true})
+}
+
+// Len returns the number of operations of the batch.
+func (b *Batch) Len() int {
+	return len(b.Ops)
+}
+
+// Batcher is implemented by the stores able to apply a group of operations
+// at once.
+type Batcher interface {
+	// WriteBatch applies the operations of b atomically: on error none of
+	// them is applied, unless the store documents it splits large batches.
+	// The error is a BatchError when it can be tied to keys.
+	WriteBatch(b *Batch) error
+	// SetMany stores kvs in one batch.
+	SetMany(kvs []KV) error
+	// GetMany returns the values of the keys found, read from one snapshot
+	// when the store supports it. The keys which failed to be read are
+	// reported with a BatchError.
+	GetMany(keys []string) (map[string][]byte, error)
+	// DeleteMany deletes keys in one batch.
+	DeleteMany(keys []string) error
+}
+
+// BatchError maps the keys of a batch to the error they failed with. The
+// keys rolled back because of another key fail with ErrBatchAborted.
+type BatchError map[string]error
+
+// Error implements error.
+func (e BatchError) Error() string {
+	keys := make([]string, 0, len(e))
+	for key, err := range e {
+		if err != ErrBatchAborted {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = fmt.Sprintf("%q: %s", key, e[key])
+	}
+	return fmt.Sprintf("storage: batch failed on %d keys: %s", len(keys), strings.Join(msgs, ", "))
+}
+
+// AbortBatch returns the BatchError of a batch rolled back because of errs,
+// the other keys of the batch fail with ErrBatchAborted.
+func AbortBatch(b *Batch, errs map[string]error) error {
+	e := make(BatchError, len(b.Ops))
+	for _, op := range b.Ops {
+		e[op.Key] = ErrBatchAborted
+	}
+	for key, err := range errs {
+		e[key] = err
+	}
+	return e
+}
+
+// WriteBatch applies b to s, in one batch if s is a Batcher, or one
+// operation at a time otherwise. In the latter case the batch is not atomic
+// and the error reports the keys which failed, the persistent values are
+// written with SetWithTTL if s is an Expirer.
+func WriteBatch(s Storage, b *Batch) error {
+	if batcher, ok := s.(Batcher); ok {
+		return batcher.WriteBatch(b)
+	}
+	errs := make(BatchError)
+	for _, op := range b.Ops {
+		var err error
+		if op.Delete {
+			err = s.Delete(op.Key)
+		} else if op.Persist {
+			err = SetPersistent(s, op.Key, op.Value, "")
+		} else {
+			err = s.Set(op.Key, op.Value)
+		}
+		if err != nil {
+			errs[op.Key] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
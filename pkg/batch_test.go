@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryBatch(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{Compress: true})
+	s.Set("old", []byte("value"))
+
+	b := NewBatch()
+	b.Set("a", []byte("1"))
+	b.Set("b", []byte("2"))
+	b.Delete("old")
+	if err := s.WriteBatch(b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	values, err := s.GetMany([]string{"a", "b", "old"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(values) != 2 || string(values["a"]) != "1" || string(values["b"]) != "2" {
+		t.Errorf("unexpected values: %q", values)
+	}
+
+	if err := s.DeleteMany([]string{"a", "b"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Len(); n != 0 {
+		t.Errorf("expected an empty store, got %d rows", n)
+	}
+}
+
+func TestInMemoryBatchAtomic(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{StrictMode: true})
+	s.Set("a", []byte("1"))
+
+	b := NewBatch()
+	b.Set("b", []byte("2"))
+	b.Delete("a")
+	// a has just been deleted by the batch
+	b.Delete("a")
+	b.Set("", []byte("empty"))
+	err := s.WriteBatch(b)
+
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a BatchError, got %v", err)
+	}
+	if !errors.Is(batchErr["a"], ErrNotFound) || batchErr[""] != errEmptyKey {
+		t.Errorf("unexpected key errors: %v", batchErr)
+	}
+	if batchErr["b"] != ErrBatchAborted {
+		t.Errorf("expected b to be aborted, got %v", batchErr["b"])
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Error("expected the batch to be rolled back")
+	}
+	if _, ok := s.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+}
+
+func TestInMemoryBatchPersist(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{TTL: 50 * time.Millisecond})
+	b := NewBatch()
+	b.Set("a", []byte("1"))
+	b.SetPersistent("b", []byte("2"))
+	if err := s.WriteBatch(b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, ok := s.Get("a"); ok {
+		t.Error("expected a to expire")
+	}
+	if v, ok := s.Get("b"); !ok || string(v) != "2" {
+		t.Errorf("expected b to be kept, got %q", v)
+	}
+}
+
+func TestWriteBatchFallback(t *testing.T) {
+	m := newMapStorage()
+	if err := WriteBatch(m, SetBatch([]KV{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}})); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(m.data) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(m.data))
+	}
+
+	ro := Downgrade(Upgrade(m, &V2Config{ReadOnly: true}))
+	err := WriteBatch(ro, DeleteBatch([]string{"a"}))
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(batchErr["a"], ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly for a, got %v", err)
+	}
+}
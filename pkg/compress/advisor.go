@@ -0,0 +1,334 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/helper/format"
+)
+
+const (
+	// DefaultAdviseSamples is the number of values sampled by Advise.
+	DefaultAdviseSamples = 100
+	// DefaultMinThroughput is the encoding throughput, in bytes per second,
+	// under which Advise doesn't recommend a codec.
+	DefaultMinThroughput = 10 << 20
+	// DefaultMinSaving is the fraction of the size a codec must save for
+	// Advise to recommend it.
+	DefaultMinSaving = 0.1
+)
+
+// AdviseOptions configures Advise, the zero value uses the defaults.
+type AdviseOptions struct {
+	// Samples is the number of values sampled, DefaultAdviseSamples if zero.
+	Samples int
+	// Codecs are the names of the codecs tried, all the registered ones if
+	// empty. The ones with levels are tried at each of their Levels.
+	Codecs []string
+	// MinSize is the MinSize of the recommended policy, DefaultMinSize if
+	// zero. The smaller values are not measured.
+	MinSize int
+	// MinThroughput is the encoding throughput, in bytes per second, of the
+	// codecs recommended, DefaultMinThroughput if zero.
+	MinThroughput float64
+	// MinSaving is the fraction of the size the codecs recommended save,
+	// DefaultMinSaving if zero.
+	MinSaving float64
+}
+
+// Result is the measure of a codec on the sampled values of a content type.
+type Result struct {
+	ContentType string
+	// Codec is the name of the codec, with its level for the codecs having
+	// levels, e.g. "gzip:9", see Lookup.
+	Codec        string
+	Values       int
+	RawBytes     int64
+	EncodedBytes int64
+	// EncodeTime and DecodeTime are the wall times spent by the codec,
+	// EncodeCPU and DecodeCPU the CPU times of the process meanwhile.
+	EncodeTime time.Duration
+	DecodeTime time.Duration
+	EncodeCPU  time.Duration
+	DecodeCPU  time.Duration
+}
+
+// Ratio returns the compression ratio, the raw size over the encoded one.
+func (r Result) Ratio() float64 {
+	if r.EncodedBytes == 0 {
+		return 0
+	}
+	return float64(r.RawBytes) / float64(r.EncodedBytes)
+}
+
+// Saving returns the fraction of the raw size saved by the codec.
+func (r Result) Saving() float64 {
+	if r.RawBytes == 0 {
+		return 0
+	}
+	return 1 - float64(r.EncodedBytes)/float64(r.RawBytes)
+}
+
+// EncodeThroughput returns the encoding throughput of the raw bytes, e.g.
+// "52.3 MiB/s".
+func (r Result) EncodeThroughput() string {
+	return throughput(r.RawBytes, r.EncodeTime)
+}
+
+// DecodeThroughput returns the decoding throughput of the raw bytes.
+func (r Result) DecodeThroughput() string {
+	return throughput(r.RawBytes, r.DecodeTime)
+}
+
+func (r *Result) add(o Result) {
+	r.Values += o.Values
+	r.RawBytes += o.RawBytes
+	r.EncodedBytes += o.EncodedBytes
+	r.EncodeTime += o.EncodeTime
+	r.DecodeTime += o.DecodeTime
+	r.EncodeCPU += o.EncodeCPU
+	r.DecodeCPU += o.DecodeCPU
+}
+
+// Report is the outcome of Advise.
+type Report struct {
+	// Samples is the number of values sampled, Skipped the number of them
+	// smaller than MinSize.
+	Samples int
+	Skipped int
+	// Results are sorted by content type, the codecs in their order.
+	Results []Result
+	// Policy is the recommended policy.
+	Policy *Policy
+}
+
+// Advise samples values of a store, measures the codecs on them and
+// recommends a policy picking the codec of each content type: the one
+// compressing the most among the fast enough ones, see AdviseOptions. The
+// store must be a storage.Scanner, the samples are read with storage.Peek:
+// they aren't counted in the metadata of the entries.
+func Advise(s storage.Storage, opts AdviseOptions) (*Report, error) {
+	scanner, ok := s.(storage.Scanner)
+	if !ok {
+		return nil, ErrNotScanner
+	}
+	if opts.Samples <= 0 {
+		opts.Samples = DefaultAdviseSamples
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultMinSize
+	}
+	if opts.MinThroughput <= 0 {
+		opts.MinThroughput = DefaultMinThroughput
+	}
+	if opts.MinSaving <= 0 {
+		opts.MinSaving = DefaultMinSaving
+	}
+	codecs, err := adviseCodecs(opts.Codecs)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := sampleKeys(scanner, opts.Samples)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Policy: &Policy{MinSize: opts.MinSize, Codecs: make(map[string]string)}}
+	samples := make(map[string][][]byte)
+	for _, key := range keys {
+		value, ok := storage.Peek(s, key)
+		if !ok {
+			continue
+		}
+		report.Samples++
+		if len(value) < opts.MinSize {
+			report.Skipped++
+			continue
+		}
+		ct := mediaType(http.DetectContentType(value))
+		samples[ct] = append(samples[ct], value)
+	}
+	types := make([]string, 0, len(samples))
+	for ct := range samples {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+
+	// the totals of the compressible types pick the default codec
+	totals := make([]Result, len(codecs))
+	for _, ct := range types {
+		results := make([]Result, len(codecs))
+		for i, c := range codecs {
+			if results[i], err = measure(c.name, c.codec, samples[ct]); err != nil {
+				return nil, err
+			}
+			results[i].ContentType = ct
+			if IsCompressible(ct) {
+				totals[i].Codec = c.name
+				totals[i].add(results[i])
+			}
+		}
+		report.Results = append(report.Results, results...)
+		report.Policy.Codecs[ct] = opts.best(results)
+	}
+	report.Policy.Codec = opts.best(totals)
+	if report.Policy.Codec == "raw" {
+		// none of the compressible types compresses well, the types with
+		// no sample default to the codec of the store
+		report.Policy.Codec = DefaultPolicy().Codec
+	}
+	for ct, name := range report.Policy.Codecs {
+		// the policy decides the same without the rule
+		if (IsCompressible(ct) && name == report.Policy.Codec) || (!IsCompressible(ct) && name == "raw") {
+			delete(report.Policy.Codecs, ct)
+		}
+	}
+	return report, nil
+}
+
+// Print writes the results and the recommended policy as text.
+func (r *Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d values sampled, %d under %d bytes skipped\n\n", r.Samples, r.Skipped, r.Policy.MinSize)
+	fmt.Fprintln(tw, "TYPE\tCODEC\tVALUES\tRATIO\tENCODE\tDECODE\tENCODE CPU\tDECODE CPU")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\t%s\t%s\t%s\t%s\n", res.ContentType, res.Codec, res.Values, res.Ratio(),
+			res.EncodeThroughput(), res.DecodeThroughput(), cpu(res.EncodeCPU), cpu(res.DecodeCPU))
+	}
+	fmt.Fprintf(tw, "\nRecommended policy: values of at least %d bytes, %s by default\n", r.Policy.MinSize, r.Policy.Codec)
+	types := make([]string, 0, len(r.Policy.Codecs))
+	for ct := range r.Policy.Codecs {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+	for _, ct := range types {
+		fmt.Fprintf(tw, "  %s\t%s\n", ct, r.Policy.Codecs[ct])
+	}
+	return tw.Flush()
+}
+
+// best returns the codec recommended from the results of a content type, or
+// "raw". The ratios within 1% are close enough for the fastest to win.
+func (o AdviseOptions) best(results []Result) string {
+	best := Result{Codec: "raw"}
+	for _, r := range results {
+		if r.Saving() < o.MinSaving || float64(r.RawBytes)/r.EncodeTime.Seconds() < o.MinThroughput {
+			continue
+		}
+		ratio, bestRatio := r.Ratio(), best.Ratio()
+		if ratio > bestRatio*1.01 || (ratio >= bestRatio*0.99 && r.EncodeTime < best.EncodeTime) {
+			best = r
+		}
+	}
+	return best.Codec
+}
+
+type namedCodec struct {
+	name  string
+	codec Codec
+}
+
+// adviseCodecs returns the codecs measured by Advise, at each of their
+// levels.
+func adviseCodecs(names []string) ([]namedCodec, error) {
+	if len(names) == 0 {
+		names = Codecs()
+	}
+	var codecs []namedCodec
+	for _, name := range names {
+		c, err := Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		if c.ID() == CodecRaw {
+			continue
+		}
+		l, ok := c.(Leveler)
+		if !ok || strings.ContainsRune(name, ':') {
+			codecs = append(codecs, namedCodec{name, c})
+			continue
+		}
+		for _, level := range l.Levels() {
+			lc, err := l.WithLevel(level)
+			if err != nil {
+				return nil, err
+			}
+			codecs = append(codecs, namedCodec{fmt.Sprintf("%s:%d", name, level), lc})
+		}
+	}
+	return codecs, nil
+}
+
+// sampleKeys returns n keys of s picked at random, all of them if it has
+// fewer.
+func sampleKeys(s storage.Scanner, n int) ([]string, error) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	keys := make([]string, 0, n)
+	seen := 0
+	err := storage.Walk(s, "", 0, func(key string) error {
+		seen++
+		if len(keys) < n {
+			keys = append(keys, key)
+		} else if i := rnd.Intn(seen); i < n {
+			keys[i] = key
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// measure encodes and decodes values with c.
+func measure(name string, c Codec, values [][]byte) (Result, error) {
+	r := Result{Codec: name, Values: len(values)}
+	encoded := make([][]byte, len(values))
+	cpu, start := cpuTime(), time.Now()
+	for i, value := range values {
+		var err error
+		if encoded[i], err = c.Encode(value); err != nil {
+			return r, fmt.Errorf("compress: %s: %s", name, err)
+		}
+	}
+	r.EncodeTime, r.EncodeCPU = time.Since(start), cpuTime()-cpu
+
+	decoded := make([][]byte, len(values))
+	cpu, start = cpuTime(), time.Now()
+	for i := range encoded {
+		var err error
+		if decoded[i], err = c.Decode(encoded[i]); err != nil {
+			return r, fmt.Errorf("compress: %s: %s", name, err)
+		}
+	}
+	r.DecodeTime, r.DecodeCPU = time.Since(start), cpuTime()-cpu
+
+	for i, value := range values {
+		if !bytes.Equal(decoded[i], value) {
+			return r, fmt.Errorf("compress: %s: the values don't round-trip", name)
+		}
+		r.RawBytes += int64(len(value))
+		r.EncodedBytes += int64(len(encoded[i]))
+	}
+	return r, nil
+}
+
+func throughput(n int64, d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return format.FormatBytesBinary(float64(n), d, 1)
+}
+
+func cpu(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,107 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+func TestAdvise(t *testing.T) {
+	s, _ := storage.NewInMemoryStorage(nil)
+	for i := 0; i < 30; i++ {
+		s.Set(fmt.Sprintf("page-%d", i), testPage(i))
+		s.Set(fmt.Sprintf("text-%d", i), bytes.Repeat(testVal, 10+i))
+		s.Set(fmt.Sprintf("small-%d", i), []byte("small"))
+	}
+	jpeg := make([]byte, 4096)
+	copy(jpeg, "\xff\xd8\xff\xe0")
+	rand.New(rand.NewSource(1)).Read(jpeg[4:])
+	s.Set("jpeg", jpeg)
+
+	report, err := Advise(s, AdviseOptions{Samples: 1000, Codecs: []string{"snappy", "gzip", "zstd:3"}, MinSize: 256, MinThroughput: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.Samples != 91 || report.Skipped != 30 {
+		t.Errorf("unexpected samples %d, %d skipped", report.Samples, report.Skipped)
+	}
+	// snappy, gzip at its 3 levels and zstd:3, for 3 types
+	if len(report.Results) != 15 {
+		t.Errorf("expected 15 results, got %d", len(report.Results))
+	}
+	for _, r := range report.Results {
+		if r.Values == 0 || r.EncodedBytes == 0 || r.Ratio() <= 0 {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+	p := report.Policy
+	if p.MinSize != 256 || p.Codec == "raw" || p.Codecs["image/jpeg"] != "" {
+		t.Errorf("unexpected policy %+v", p)
+	}
+	if _, err := Lookup(p.Codec); err != nil {
+		t.Errorf("unexpected codec %q: %s", p.Codec, err)
+	}
+
+	var b bytes.Buffer
+	if err := report.Print(&b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(b.String(), "gzip:9") || !strings.Contains(b.String(), "/s") {
+		t.Errorf("unexpected report:\n%s", b.String())
+	}
+
+	if _, err := Advise(s, AdviseOptions{Codecs: []string{"unknown"}}); err == nil {
+		t.Error("expected an error for an unknown codec")
+	}
+
+	// the samples are peeked, not read
+	ps := &peekStore{Store: s}
+	if _, err := Advise(ps, AdviseOptions{Samples: 10, Codecs: []string{"snappy"}, MinThroughput: 1}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ps.gets != 0 || ps.peeks != 10 {
+		t.Errorf("expected 10 peeks and no get, got %d and %d", ps.peeks, ps.gets)
+	}
+}
+
+// peekStore counts the reads of the values of a store.
+type peekStore struct {
+	*storage.Store
+	gets, peeks int
+}
+
+func (s *peekStore) Get(key string) ([]byte, bool) {
+	s.gets++
+	return s.Store.Get(key)
+}
+
+func (s *peekStore) Peek(key string) ([]byte, bool) {
+	s.peeks++
+	return s.Store.Get(key)
+}
+
+func TestLevels(t *testing.T) {
+	for _, name := range []string{"gzip:1", "gzip:9", "bzip2:1", "brotli:1", "zstd:19"} {
+		c, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		encoded, err := Encode(c, testVal)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if decoded, err := Decode(encoded, nil); err != nil || !bytes.Equal(decoded, testVal) {
+			t.Errorf("%s: unexpected value %q (%v)", name, decoded, err)
+		}
+	}
+	for _, name := range []string{"gzip:10", "snappy:1", "gzip:x"} {
+		if _, err := Lookup(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
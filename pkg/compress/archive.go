@@ -0,0 +1,201 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+const (
+	// ManifestName is the name of the first entry of the archives written
+	// by Export, the manifest mapping the other entries to their keys.
+	ManifestName = "manifest.json"
+	// ManifestVersion is the version of the manifests written by Export.
+	ManifestVersion = 1
+)
+
+// Manifest describes the entries of an archive written by Export.
+type Manifest struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry maps an archive entry to its key and metadata.
+type ManifestEntry struct {
+	Key string `json:"key"`
+	// Name is the name of the archive entry holding the value, the keys
+	// are not valid file names.
+	Name string `json:"name"`
+	// Check is the metadata of the entry, nil if the store doesn't keep
+	// it.
+	Check *storage.Check `json:"check,omitempty"`
+}
+
+// archiveWriter writes the entries of an archive.
+type archiveWriter interface {
+	WriteEntry(name string, data []byte, modTime time.Time) error
+	Close() error
+}
+
+// archiveReader reads the entries of an archive, Next returns io.EOF after
+// the last one.
+type archiveReader interface {
+	Next() (name string, data []byte, err error)
+}
+
+// Export writes all the entries of a store to w as an archive in format:
+// Tar, GZip for a gzipped tar or Zip. The values are written decoded, one
+// archive entry each, after the manifest holding their keys and metadata.
+// The store must be a storage.Scanner, the values are read with
+// storage.Peek: the export isn't counted in their metadata.
+func Export(s storage.Storage, w io.Writer, format Format) error {
+	scanner, ok := s.(storage.Scanner)
+	if !ok {
+		return ErrNotScanner
+	}
+	var aw archiveWriter
+	switch format {
+	case Tar:
+		aw = newTarWriter(w)
+	case GZip:
+		aw = newTarGzWriter(w)
+	case Zip:
+		aw = newZipWriter(w)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedArchive, format)
+	}
+
+	m := Manifest{Version: ManifestVersion, CreatedAt: time.Now().UTC()}
+	stater, _ := s.(storage.Stater)
+	err := storage.Walk(scanner, "", 0, func(key string) error {
+		e := ManifestEntry{Key: key, Name: fmt.Sprintf("data/%08d", len(m.Entries))}
+		if stater != nil {
+			c, err := stater.Stat(key)
+			switch {
+			case err == storage.ErrNotFound:
+				// expired since the scan
+				return nil
+			case err != nil:
+				return err
+			case c.Enabled:
+				e.Check = c
+			}
+		}
+		m.Entries = append(m.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := aw.WriteEntry(ManifestName, manifest, m.CreatedAt); err != nil {
+		return err
+	}
+	for _, e := range m.Entries {
+		value, ok := storage.Peek(s, e.Key)
+		if !ok {
+			// expired since the manifest was written, Import skips it
+			continue
+		}
+		modTime := m.CreatedAt
+		if e.Check != nil {
+			modTime = e.Check.UpdatedAt
+		}
+		if err := aw.WriteEntry(e.Name, value, modTime); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+// Import stores the entries of an archive written by Export, whatever its
+// format, into a store, skipping the ones expired since. Their metadata is
+// restored if the store is a storage.CheckSetter, only their expiry if it
+// is a storage.Expirer. The content types are not archived, the policies
+// sniff them again from the values.
+func Import(s storage.Storage, r io.Reader) error {
+	ar, err := newArchiveReader(r)
+	if err != nil {
+		return err
+	}
+	name, data, err := ar.Next()
+	if err == io.EOF || (err == nil && name != ManifestName) {
+		return ErrNoManifest
+	}
+	if err != nil {
+		return err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("compress: invalid manifest: %s", err)
+	}
+	if m.Version > ManifestVersion {
+		return fmt.Errorf("compress: unsupported manifest version %d", m.Version)
+	}
+	entries := make(map[string]ManifestEntry, len(m.Entries))
+	for _, e := range m.Entries {
+		entries[e.Name] = e
+	}
+
+	expirer, _ := s.(storage.Expirer)
+	setter, _ := s.(storage.CheckSetter)
+	now := time.Now()
+	for {
+		name, data, err := ar.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e, ok := entries[name]
+		if !ok {
+			return fmt.Errorf("compress: archive entry %q not in the manifest", name)
+		}
+		switch {
+		case e.Check != nil && !e.Check.ExpiredAt.IsZero() && !e.Check.ExpiredAt.After(now):
+			// expired since the export
+			continue
+		case e.Check != nil && setter != nil:
+			err = setter.SetWithCheck(e.Key, data, e.Check)
+		case e.Check != nil && expirer != nil && !e.Check.ExpiredAt.IsZero():
+			err = expirer.SetWithTTL(e.Key, data, e.Check.ExpiredAt.Sub(now))
+		default:
+			err = s.Set(e.Key, data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newArchiveReader returns the reader of the archive format of r.
+func newArchiveReader(r io.Reader) (archiveReader, error) {
+	br := bufio.NewReader(r)
+	// the tar magic number ends at 265
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	ra := bytes.NewReader(head)
+	if ok, _ := IsGZip(ra); ok {
+		return newTarGzReader(br)
+	}
+	if ok, _ := IsZip(ra); ok {
+		return newZipReader(br)
+	}
+	if ok, _ := IsTar(ra); ok {
+		return newTarReader(br), nil
+	}
+	return nil, ErrUnsupportedArchive
+}
@@ -0,0 +1,91 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+func TestExportImport(t *testing.T) {
+	src, _ := storage.NewInMemoryStorage(nil)
+	values := map[string]string{
+		"example.com/page":        "<html>page</html>",
+		"http://example.com/?a=1": strings.Repeat("large body ", 1000),
+		"../../etc/passwd":        "not a path",
+		"empty":                   "",
+	}
+	for key, value := range values {
+		src.Set(key, []byte(value))
+	}
+	src.SetWithTTL("expiring", []byte("soon"), time.Hour)
+
+	for _, format := range []Format{Tar, GZip, Zip} {
+		var b bytes.Buffer
+		if err := Export(src, &b, format); err != nil {
+			t.Fatalf("%s: unexpected error: %s", format, err)
+		}
+		if f, _ := GetFormat(bytes.NewReader(b.Bytes())); f != format {
+			t.Errorf("%s: unexpected archive format %s", format, f)
+		}
+
+		dst, _ := storage.NewInMemoryStorage(nil)
+		if err := Import(dst, &b); err != nil {
+			t.Fatalf("%s: unexpected error: %s", format, err)
+		}
+		for key, value := range values {
+			if v, ok := dst.Get(key); !ok || string(v) != value {
+				t.Errorf("%s: %s: expected %d bytes, got %d", format, key, len(value), len(v))
+			}
+		}
+		c, err := dst.Stat("expiring")
+		if err != nil || c.ExpiredAt.IsZero() || time.Until(c.ExpiredAt) > time.Hour {
+			t.Errorf("%s: unexpected expiry %+v (%v)", format, c, err)
+		}
+	}
+
+	if err := Export(src, &bytes.Buffer{}, LZ4); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	if err := Import(src, strings.NewReader("not an archive")); err != ErrUnsupportedArchive {
+		t.Errorf("expected %s, got %v", ErrUnsupportedArchive, err)
+	}
+	var b bytes.Buffer
+	w := newTarWriter(&b)
+	w.WriteEntry("data/00000000", []byte("value"), time.Now())
+	w.Close()
+	if err := Import(src, &b); err != ErrNoManifest {
+		t.Errorf("expected %s, got %v", ErrNoManifest, err)
+	}
+}
+
+func TestImportExpired(t *testing.T) {
+	m := Manifest{Version: ManifestVersion, CreatedAt: time.Now(), Entries: []ManifestEntry{
+		{Key: "expired", Name: "data/00000000", Check: &storage.Check{Enabled: true, ExpiredAt: time.Now().Add(-time.Minute)}},
+		{Key: "live", Name: "data/00000001", Check: &storage.Check{Enabled: true}},
+	}}
+	manifest, _ := json.Marshal(m)
+	var b bytes.Buffer
+	w := newTarWriter(&b)
+	w.WriteEntry(ManifestName, manifest, m.CreatedAt)
+	w.WriteEntry("data/00000000", []byte("old"), m.CreatedAt)
+	w.WriteEntry("data/00000001", []byte("new"), m.CreatedAt)
+	w.Close()
+
+	// hides the storage.Expirer of the store
+	mem, _ := storage.NewInMemoryStorage(nil)
+	dst := struct{ storage.Storage }{mem}
+	if err := Import(dst, &b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := dst.Get("expired"); ok {
+		t.Error("expected the expired entry to be skipped")
+	}
+	if v, ok := dst.Get("live"); !ok || string(v) != "new" {
+		t.Errorf("unexpected value %q", v)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
 
+	// external
+	"github.com/golang/snappy"
 	compressible "github.com/sniperkit/compressible/pkg"
+	"gopkg.in/kothar/brotli-go.v0/dec"
 )
 
 // Magic numbers for compression and archive formats
@@ -20,15 +24,26 @@ var (
 	magicnumZip        = []byte{0x50, 0x4b, 0x03, 0x04}
 	magicnumZipEmpty   = []byte{0x50, 0x4b, 0x05, 0x06}
 	magicnumZipSpanned = []byte{0x50, 0x4b, 0x07, 0x08}
-	//magicnumLZW        = []byte{0x1F, 0x9d}
+	magicnumLZW        = []byte{0x1f, 0x9d}
+	magicnumSnappy     = []byte{0xff, 0x06, 0x00, 0x00, 0x73, 0x4e, 0x61, 0x50, 0x70, 0x59} // stream identifier chunk
+	magicnumZstd       = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicnumXZ         = []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}
 )
 
+// detectLimit bounds the data decoded by the checks of the formats without
+// magic number.
+const detectLimit = 1 << 20
+
 // IsBZip2 checks to see if the received reader's contents are in bzip2 format
 // by checking the magic numbers.
 func IsBZip2(r io.ReaderAt) (bool, error) {
 	h := make([]byte, 3)
 	// Read the first 3 bytes
 	_, err := r.ReadAt(h, 0)
+	if err == io.EOF {
+		// too short to hold the magic number
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -57,6 +72,10 @@ func IsGZip(r io.ReaderAt) (bool, error) {
 	h := make([]byte, 2)
 	// Read the first 2 bytes
 	_, err := r.ReadAt(h, 0)
+	if err == io.EOF {
+		// too short to hold the magic number
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -85,6 +104,10 @@ func IsLZ4(r io.ReaderAt) (bool, error) {
 	h := make([]byte, 4)
 	// Read the first 4 bytes
 	_, err := r.ReadAt(h, 0)
+	if err == io.EOF {
+		// too short to hold the magic number
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -107,37 +130,117 @@ func IsLZ4(r io.ReaderAt) (bool, error) {
 	return false, nil
 }
 
-// IsLZW checks to see if the received reader's contents are in LZ4 format by
-// checking the magic numbers.
-//
-// TODO: unsupported until I have a better understanding of how to handle LZW
-/*
+// IsLZW checks to see if the received reader's contents are in the LZW
+// format of compress(1), the .Z files, by checking the magic numbers.
+// compress/lzw doesn't write the header, its output is not detected.
 func IsLZW(r io.ReaderAt) (bool, error) {
-	h := make([]byte, 2)
-	// Reat the first 8 bytes since that's where most magic numbers are
-	_, err := r.ReadAt(h, 0)
-	if err != nil {
+	return hasMagic(r, magicnumLZW, 0)
+}
+
+// IsSnappyFramed checks to see if the received reader's contents are in the
+// snappy framing format by checking the stream identifier.
+func IsSnappyFramed(r io.ReaderAt) (bool, error) {
+	return hasMagic(r, magicnumSnappy, 0)
+}
+
+// IsZstd checks to see if the received reader's contents are in the zstd
+// format by checking the magic numbers.
+func IsZstd(r io.ReaderAt) (bool, error) {
+	return hasMagic(r, magicnumZstd, 0)
+}
+
+// IsXZ checks to see if the received reader's contents are in the xz format
+// by checking the magic numbers.
+func IsXZ(r io.ReaderAt) (bool, error) {
+	return hasMagic(r, magicnumXZ, 0)
+}
+
+// IsSnappy checks to see if the received reader's contents are in the snappy
+// block format. The format has no magic number, the contents are decoded:
+// this is a heuristic, only applied to contents up to 1MiB.
+func IsSnappy(r io.ReaderAt) (bool, error) {
+	data, err := readLimit(r)
+	if err != nil || data == nil {
 		return false, err
 	}
-	var h16 uint16
-	// check for lzw
-	hbuf := bytes.NewReader(h)
-	err = binary.Read(hbuf, binary.LittleEndian, &h16)
+	n, err := snappy.DecodedLen(data)
+	if err != nil || n == 0 {
+		return false, nil
+	}
+	_, err = snappy.Decode(nil, data)
+	return err == nil, nil
+}
+
+// IsBrotli checks to see if the received reader's contents are in the brotli
+// format. The format has no magic number, the contents are decoded: this is
+// a heuristic, tightened against the random data. The contents up to 1MiB
+// must decode completely to more bytes than they hold, the stream ending
+// with their last byte. The first 1MiB of the larger ones must decode to
+// more than 1MiB.
+func IsBrotli(r io.ReaderAt) (bool, error) {
+	data, err := readLimit(r)
 	if err != nil {
-		return false, fmt.Errorf("error while checking if input matched LZW's magic number: %s", err)
+		return false, err
+	}
+	if data == nil {
+		br := dec.NewBrotliReader(io.NewSectionReader(r, 0, detectLimit))
+		defer br.Close()
+		n, _ := io.Copy(ioutil.Discard, io.LimitReader(br, detectLimit+1))
+		return n > detectLimit, nil
+	}
+	if len(data) == 0 {
+		return false, nil
+	}
+	// the input is fed byte by byte: the decoder doesn't read past the end
+	// of the stream, the trailing bytes are left
+	in := &byteReader{data: data}
+	br := dec.NewBrotliReader(in)
+	defer br.Close()
+	n, err := io.Copy(ioutil.Discard, io.LimitReader(br, 64*detectLimit))
+	return err == nil && in.off == len(data) && n > int64(len(data)), nil
+}
+
+// byteReader reads data a byte at a time.
+type byteReader struct {
+	data []byte
+	off  int
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	if r.off == len(r.data) {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	p[0] = r.data[r.off]
+	r.off++
+	return 1, nil
+}
+
+// hasMagic reports whether the contents of r hold magic at offset.
+func hasMagic(r io.ReaderAt, magic []byte, offset int64) (bool, error) {
+	h := make([]byte, len(magic))
+	_, err := r.ReadAt(h, offset)
+	if err == io.EOF {
+		// too short to hold the magic number
+		return false, nil
 	}
-	var c16 uint16
-	cbuf := bytes.NewBuffer(magicnumLZW)
-	err = binary.Read(cbuf, binary.BigEndian, &c16)
 	if err != nil {
-		return false, fmt.Errorf("error while converting LZW magic number for comparison: %s", err)
+		return false, err
 	}
-	if h16 == c16 {
-		return true, nil
+	return bytes.Equal(h, magic), nil
+}
+
+// readLimit returns the contents of r, nil if they are larger than
+// detectLimit.
+func readLimit(r io.ReaderAt) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.NewSectionReader(r, 0, detectLimit+1))
+	if err != nil || len(data) > detectLimit {
+		return nil, err
 	}
-	return false, nil
+	return data, nil
 }
-*/
 
 // IsTar checks to see if the received reader's contents are in the tar format
 // by checking the magic numbers. This evaluates using both tar1 and tar2 magic
@@ -146,6 +249,10 @@ func IsTar(r io.ReaderAt) (bool, error) {
 	h := make([]byte, 8)
 	// Read the first 8 bytes at offset 257
 	_, err := r.ReadAt(h, 257)
+	if err == io.EOF {
+		// too short to hold the magic number
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -184,6 +291,10 @@ func IsZip(r io.ReaderAt) (bool, error) {
 	h := make([]byte, 4)
 	// Read the first 4 bytes
 	_, err := r.ReadAt(h, 0)
+	if err == io.EOF {
+		// too short to hold the magic number
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -226,8 +337,8 @@ func IsCompressible(ct string) bool {
 	return compressible.Is(ct)
 }
 
-func IsCompressibleWithThreshold(ct string, wt compressible.WithThreshold) bool {
-	return wt.Compressible(ct, wt)
+func IsCompressibleWithThreshold(ct string, length int, wt compressible.WithThreshold) bool {
+	return wt.Compressible(ct, length)
 }
 
 // GetChecksum gets the checksum.
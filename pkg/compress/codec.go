@@ -0,0 +1,280 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CodecID identifies a codec in the value headers. The IDs are stored with
+// the values and must never be reused for another codec.
+type CodecID byte
+
+const (
+	CodecRaw          CodecID = iota // values stored as they are
+	CodecGZip                        // gzip
+	CodecSnappy                      // snappy block format
+	CodecLZ4                         // LZ4 frame format
+	CodecBZip2                       // bzip2
+	CodecBrotli                      // brotli
+	CodecSnappyFramed                // snappy framing format, streamable
+	CodecZstd                        // Zstandard, with a dictionary if the header has its ID
+)
+
+// Codec compresses and decompresses values.
+type Codec interface {
+	// ID identifies the codec in the value headers.
+	ID() CodecID
+	// Name is the name the codec is looked up with, e.g. "gzip".
+	Name() string
+	// Encode returns the compressed form of data.
+	Encode(data []byte) ([]byte, error)
+	// Decode returns the data compressed by Encode.
+	Decode(data []byte) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+	codecIDs = make(map[CodecID]Codec)
+)
+
+// Register makes a codec available to Lookup and Decode. It panics if the
+// name or the ID of the codec is already registered.
+func Register(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if _, dup := codecs[c.Name()]; dup {
+		panic("compress: Register called twice for codec " + c.Name())
+	}
+	if _, dup := codecIDs[c.ID()]; dup {
+		panic(fmt.Sprintf("compress: Register called twice for codec ID %d", c.ID()))
+	}
+	codecs[c.Name()] = c
+	codecIDs[c.ID()] = c
+}
+
+// Leveler is implemented by the codecs with compression levels, the values
+// are decoded whatever their level.
+type Leveler interface {
+	// Levels returns the levels worth trying, from the fastest to the most
+	// compressing.
+	Levels() []int
+	// WithLevel returns the codec compressing at level.
+	WithLevel(level int) (Codec, error)
+}
+
+// WithLevel returns c compressing at level, c must be a Leveler.
+func WithLevel(c Codec, level int) (Codec, error) {
+	l, ok := c.(Leveler)
+	if !ok {
+		return nil, fmt.Errorf("compress: codec %q has no levels", c.Name())
+	}
+	return l.WithLevel(level)
+}
+
+// Windower is implemented by the codecs with a configurable window.
+type Windower interface {
+	// WithWindow returns the codec compressing with window, as the base 2
+	// logarithm of its size.
+	WithWindow(window int) (Codec, error)
+}
+
+// WithWindow returns c compressing with window, c must be a Windower.
+func WithWindow(c Codec, window int) (Codec, error) {
+	w, ok := c.(Windower)
+	if !ok {
+		return nil, fmt.Errorf("compress: codec %q has no window", c.Name())
+	}
+	return w.WithWindow(window)
+}
+
+// errLevel is returned by WithLevel for the levels out of range.
+func errLevel(c Codec, level int) error {
+	return fmt.Errorf("compress: invalid %s level %d", c.Name(), level)
+}
+
+// CheckCodec returns the error of Lookup for name, the DSNs of the stores
+// are checked with it, see storage.DSN.CompressCodec.
+func CheckCodec(name string) error {
+	_, err := Lookup(name)
+	return err
+}
+
+// Lookup returns the codec registered under name, "name:level" returns it
+// at level, e.g. "gzip:9", and "name:level:window" with a window too, e.g.
+// "brotli:9:18".
+func Lookup(name string) (Codec, error) {
+	if parts := strings.Split(name, ":"); len(parts) > 1 {
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("%w %q", ErrUnknownCodec, name)
+		}
+		params := make([]int, len(parts)-1)
+		for i, p := range parts[1:] {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q", ErrUnknownCodec, name)
+			}
+			params[i] = n
+		}
+		c, err := Lookup(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		if c, err = WithLevel(c, params[0]); err != nil || len(params) == 1 {
+			return c, err
+		}
+		return WithWindow(c, params[1])
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if c, ok := codecs[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownCodec, name)
+}
+
+// LookupID returns the codec registered with id.
+func LookupID(id CodecID) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if c, ok := codecIDs[id]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w %d", ErrUnknownCodec, id)
+}
+
+// Codecs returns the sorted names of the registered codecs.
+func Codecs() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// headerMagic starts the value headers. The values written before them
+// start with a NUL byte very rarely, and never with the three bytes.
+var headerMagic = []byte("\x00CZ")
+
+// Header describes how a value is encoded. It is written before the value
+// as the magic, the codec ID, the length of Meta as an uvarint and Meta.
+type Header struct {
+	Codec CodecID
+	// Meta holds the parameters the codec needs to decode the value, it is
+	// empty for most of them.
+	Meta []byte
+}
+
+// HasHeader reports whether data starts with a value header.
+func HasHeader(data []byte) bool {
+	return bytes.HasPrefix(data, headerMagic) && len(data) > len(headerMagic)
+}
+
+// AppendHeader appends h to buf.
+func AppendHeader(buf []byte, h Header) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	buf = append(buf, headerMagic...)
+	buf = append(buf, byte(h.Codec))
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(h.Meta)))]...)
+	return append(buf, h.Meta...)
+}
+
+// ReadHeader returns the header of data and the encoded value following it,
+// which shares the memory of data.
+func ReadHeader(data []byte) (Header, []byte, error) {
+	if !HasHeader(data) {
+		return Header{}, nil, ErrNoHeader
+	}
+	data = data[len(headerMagic):]
+	h := Header{Codec: CodecID(data[0])}
+	data = data[1:]
+	n, l := binary.Uvarint(data)
+	if l <= 0 || n > uint64(len(data)-l) {
+		return Header{}, nil, ErrInvalidHeader
+	}
+	data = data[l:]
+	if n > 0 {
+		h.Meta = data[:n]
+	}
+	return h, data[n:], nil
+}
+
+// Encode compresses data with c and prefixes it with its header.
+func Encode(c Codec, data []byte) ([]byte, error) {
+	encoded, err := c.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(headerMagic)+2+len(encoded))
+	buf = AppendHeader(buf, Header{Codec: c.ID()})
+	return append(buf, encoded...), nil
+}
+
+// EncodeWith is like Encode with the codec registered under name.
+func EncodeWith(name string, data []byte) ([]byte, error) {
+	c, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(c, data)
+}
+
+// Decode decompresses a value written by Encode, whatever its codec. The
+// values without a header, written before them, are decoded with legacy or
+// returned as they are if legacy is nil.
+func Decode(data []byte, legacy Codec) ([]byte, error) {
+	if !HasHeader(data) {
+		if legacy == nil {
+			return data, nil
+		}
+		return legacy.Decode(data)
+	}
+	h, encoded, err := ReadHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := LookupID(h.Codec)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decode(encoded)
+}
+
+// rawCodec stores the values as they are, the header still lets the codec
+// of the store change later.
+type rawCodec struct{}
+
+func (rawCodec) ID() CodecID                        { return CodecRaw }
+func (rawCodec) Name() string                       { return "raw" }
+func (rawCodec) Encode(data []byte) ([]byte, error) { return data, nil }
+func (rawCodec) Decode(data []byte) ([]byte, error) { return data, nil }
+
+func (rawCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (rawCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(r), nil
+}
+
+func init() {
+	Register(rawCodec{})
+	Register(gzipCodec{})
+	Register(snappyCodec{})
+	Register(snappyFramedCodec{})
+	Register(lz4Codec{})
+	Register(bzip2Codec{})
+	Register(brotliCodec{})
+	Register(zstdCodec{})
+}
@@ -0,0 +1,107 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	names := Codecs()
+	if len(names) != 8 {
+		t.Errorf("expected 8 codecs, got %v", names)
+	}
+	for _, name := range names {
+		c, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		encoded, err := Encode(c, testVal)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		h, _, err := ReadHeader(encoded)
+		if err != nil || h.Codec != c.ID() {
+			t.Errorf("%s: unexpected header %+v (%v)", name, h, err)
+		}
+		decoded, err := Decode(encoded, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if !bytes.Equal(decoded, testVal) {
+			t.Errorf("%s: expected %q, got %q", name, testVal, decoded)
+		}
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Error("expected an error for an unknown codec")
+	}
+	if _, err := Decode(AppendHeader(nil, Header{Codec: 200}), nil); err == nil {
+		t.Error("expected an error for an unknown codec ID")
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	snappy, _ := Lookup("snappy")
+	legacy, _ := snappy.Encode(testVal)
+	decoded, err := Decode(legacy, snappy)
+	if err != nil || !bytes.Equal(decoded, testVal) {
+		t.Errorf("expected the legacy value, got %q (%v)", decoded, err)
+	}
+	if decoded, _ := Decode(testVal, nil); !bytes.Equal(decoded, testVal) {
+		t.Errorf("expected the raw value, got %q", decoded)
+	}
+}
+
+func TestHeaderMeta(t *testing.T) {
+	buf := AppendHeader(nil, Header{Codec: CodecGZip, Meta: []byte("meta")})
+	buf = append(buf, "value"...)
+	h, value, err := ReadHeader(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h.Codec != CodecGZip || string(h.Meta) != "meta" || string(value) != "value" {
+		t.Errorf("unexpected header %+v, value %q", h, value)
+	}
+	if _, _, err := ReadHeader(buf[:6]); err != ErrInvalidHeader {
+		t.Errorf("expected %s, got %v", ErrInvalidHeader, err)
+	}
+}
+
+func TestBrotliCodec(t *testing.T) {
+	for _, window := range []int{0, BrotliMinWindow, BrotliMaxWindow} {
+		c, err := NewBrotliCodec(1, window)
+		if err != nil {
+			t.Fatalf("window %d: unexpected error: %s", window, err)
+		}
+		encoded, err := Encode(c, testVal)
+		if err != nil {
+			t.Fatalf("window %d: unexpected error: %s", window, err)
+		}
+		// decoded by the registered codec, whatever the parameters
+		decoded, err := Decode(encoded, nil)
+		if err != nil || !bytes.Equal(decoded, testVal) {
+			t.Errorf("window %d: expected %q, got %q (%v)", window, testVal, decoded, err)
+		}
+	}
+
+	for _, params := range [][2]int{{-1, 0}, {BrotliMaxQuality + 1, 0}, {1, BrotliMinWindow - 1}, {1, BrotliMaxWindow + 1}} {
+		if _, err := NewBrotliCodec(params[0], params[1]); err == nil {
+			t.Errorf("quality %d, window %d: expected an error", params[0], params[1])
+		}
+	}
+
+	c, err := Lookup("brotli:5:18")
+	if p, ok := c.(brotliParamsCodec); err != nil || !ok || p.quality != 5 || p.window != 18 {
+		t.Errorf("expected quality 5 and window 18, got %+v (%v)", c, err)
+	}
+	for _, name := range []string{"brotli:5:30", "brotli:5:18:1", "gzip:5:18", "brotli::18"} {
+		if _, err := Lookup(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	brotli, _ := Lookup("brotli")
+	if _, err := brotli.Decode([]byte("not brotli")); err == nil {
+		t.Error("expected an error for an invalid stream")
+	}
+}
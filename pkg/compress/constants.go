@@ -1,16 +1,40 @@
 package compress
 
 const (
-	Unknown    Format = iota // unknown format
-	GZip                     // Gzip compression format
-	BZip2                    // Bzip2 compression
-	LZ4                      // LZ4 compression
-	Tar                      // Tar format; normally used
-	Tar1                     // Tar1 magicnum format; normalizes to Tar
-	Tar2                     // Tar1 magicnum format; normalizes to Tar
-	Zip                      // Zip archive
-	ZipEmpty                 // Empty Zip Archive
-	ZipSpanned               // Spanned Zip Archive
+	Unknown      Format = iota // unknown format
+	GZip                       // Gzip compression format
+	BZip2                      // Bzip2 compression
+	LZ4                        // LZ4 compression
+	Tar                        // Tar format; normally used
+	Tar1                       // Tar1 magicnum format; normalizes to Tar
+	Tar2                       // Tar1 magicnum format; normalizes to Tar
+	Zip                        // Zip archive
+	ZipEmpty                   // Empty Zip Archive
+	ZipSpanned                 // Spanned Zip Archive
+	SnappyFramed               // Snappy framing format
+	Snappy                     // Snappy block format, detected by decoding it
+	Zstd                       // Zstandard compression
+	XZ                         // XZ compression
+	LZW                        // LZW compression, as written by compress(1)
+	Brotli                     // Brotli compression, detected by decoding it
 )
 
-const formatName = "UnknownGZipBZip2LZ4TarTar1Tar2ZipEmpty ZipSpanned Zip"
+// formatNames holds the names of the formats, indexed by Format.
+var formatNames = [...]string{
+	Unknown:      "Unknown",
+	GZip:         "GZip",
+	BZip2:        "BZip2",
+	LZ4:          "LZ4",
+	Tar:          "Tar",
+	Tar1:         "Tar1",
+	Tar2:         "Tar2",
+	Zip:          "Zip",
+	ZipEmpty:     "Empty Zip",
+	ZipSpanned:   "Spanned Zip",
+	SnappyFramed: "Snappy Framed",
+	Snappy:       "Snappy",
+	Zstd:         "Zstd",
+	XZ:           "XZ",
+	LZW:          "LZW",
+	Brotli:       "Brotli",
+}
@@ -0,0 +1,52 @@
+package compress
+
+import (
+	"fmt"
+	"strings"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// ContentEncodingCodec returns the codec decoding the bodies of an HTTP
+// Content-Encoding: "br", "gzip", "zstd" or "identity". The bodies encoded
+// several times, e.g. "gzip, br", are not supported.
+func ContentEncodingCodec(contentEncoding string) (Codec, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return rawCodec{}, nil
+	case "br":
+		return brotliCodec{}, nil
+	case "gzip", "x-gzip":
+		return gzipCodec{}, nil
+	case "zstd":
+		return zstdCodec{}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, contentEncoding)
+}
+
+// WrapEncoded prefixes data, already compressed by c, with its header. The
+// value is stored as it was received and decoded by Decode.
+func WrapEncoded(c Codec, data []byte) []byte {
+	buf := make([]byte, 0, len(headerMagic)+2+len(data))
+	buf = AppendHeader(buf, Header{Codec: c.ID()})
+	return append(buf, data...)
+}
+
+// SetEncoded stores value, a body received with contentEncoding, at key. It
+// is stored as it is if s is a storage.EncodedSetter, decoded and stored
+// with Set otherwise.
+func SetEncoded(s storage.Storage, key string, value []byte, contentEncoding string) error {
+	if setter, ok := s.(storage.EncodedSetter); ok {
+		return setter.SetEncoded(key, value, contentEncoding)
+	}
+	c, err := ContentEncodingCodec(contentEncoding)
+	if err != nil {
+		return err
+	}
+	decoded, err := c.Decode(value)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, decoded)
+}
@@ -0,0 +1,55 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+
+	// external
+	"gopkg.in/kothar/brotli-go.v0/enc"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+func TestContentEncoding(t *testing.T) {
+	for encoding, id := range map[string]CodecID{
+		"":         CodecRaw,
+		"identity": CodecRaw,
+		"br":       CodecBrotli,
+		"gzip":     CodecGZip,
+		"X-Gzip":   CodecGZip,
+		"zstd":     CodecZstd,
+	} {
+		c, err := ContentEncodingCodec(encoding)
+		if err != nil || c.ID() != id {
+			t.Errorf("%q: expected codec %d, got %v (%v)", encoding, id, c, err)
+		}
+	}
+	for _, encoding := range []string{"deflate", "gzip, br"} {
+		if _, err := ContentEncodingCodec(encoding); err == nil {
+			t.Errorf("%q: expected an error", encoding)
+		}
+	}
+
+	body, err := enc.CompressBuffer(enc.NewBrotliParams(), testVal, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c, _ := ContentEncodingCodec("br")
+	wrapped := WrapEncoded(c, body)
+	if !bytes.HasSuffix(wrapped, body) {
+		t.Error("expected the body as it was received")
+	}
+	if decoded, err := Decode(wrapped, nil); err != nil || !bytes.Equal(decoded, testVal) {
+		t.Errorf("expected %q, got %q (%v)", testVal, decoded, err)
+	}
+
+	// decoded before Set by the stores keeping the bodies as they are
+	s, _ := storage.NewInMemoryStorage(nil)
+	if err := SetEncoded(s, "key", body, "br"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if value, ok := s.Get("key"); !ok || !bytes.Equal(value, testVal) {
+		t.Errorf("expected %q, got %q", testVal, value)
+	}
+}
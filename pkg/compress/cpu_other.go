@@ -0,0 +1,13 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package compress
+
+import (
+	"time"
+)
+
+// cpuTime is not measured on this platform, the reports show no CPU time.
+func cpuTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,414 @@
+package compress
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
+
+	// external
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// DefaultDictSize is the size of the history of the dictionaries
+	// trained by Dicts.Train.
+	DefaultDictSize = 64 << 10
+	// DefaultDictSamples is the number of values the stores sample to train
+	// a dictionary.
+	DefaultDictSamples = 100
+)
+
+// Dict is a zstd dictionary trained for the values of a namespace, e.g. the
+// pages of a host. The dictionaries of a namespace are versioned by their
+// ID: the last one compresses the new values, the previous ones are kept to
+// decode the values written before.
+type Dict struct {
+	ID        uint32
+	Namespace string
+	// Data is the dictionary in the zstd format.
+	Data []byte
+}
+
+// MarshalBinary encodes d as its ID and the length of its namespace as
+// uvarints, followed by its namespace and its data.
+func (d Dict) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 2*binary.MaxVarintLen32+len(d.Namespace)+len(d.Data))
+	buf = appendUvarint(buf, uint64(d.ID))
+	buf = appendUvarint(buf, uint64(len(d.Namespace)))
+	buf = append(buf, d.Namespace...)
+	return append(buf, d.Data...), nil
+}
+
+// UnmarshalBinary decodes a dictionary encoded by MarshalBinary, the data
+// is copied.
+func (d *Dict) UnmarshalBinary(data []byte) error {
+	id, n := binary.Uvarint(data)
+	if n <= 0 || id > math.MaxUint32 {
+		return ErrInvalidDict
+	}
+	data = data[n:]
+	l, n := binary.Uvarint(data)
+	if n <= 0 || l > uint64(len(data)-n) {
+		return ErrInvalidDict
+	}
+	data = data[n:]
+	d.ID = uint32(id)
+	d.Namespace = string(data[:l])
+	d.Data = append([]byte(nil), data[l:]...)
+	return nil
+}
+
+// DictStore persists the dictionaries of a Dicts, the backends keep them
+// with their values.
+type DictStore interface {
+	// LoadDicts returns all the dictionaries saved.
+	LoadDicts() ([]Dict, error)
+	// SaveDict saves a new dictionary.
+	SaveDict(d Dict) error
+}
+
+// TrainDict builds a dictionary from samples of the values. The history of
+// the dictionary is made of the first size bytes of the first half of the
+// samples, its entropy tables of the samples left: zstd can't build them
+// from the samples found whole in the history.
+func TrainDict(id uint32, samples [][]byte, size int) (dict []byte, err error) {
+	// BuildDict panics when the samples have no literal left
+	defer func() {
+		if r := recover(); r != nil {
+			dict, err = nil, fmt.Errorf("compress: can't train a dictionary: %v", r)
+		}
+	}()
+
+	var nonEmpty [][]byte
+	for _, sample := range samples {
+		if len(sample) > 0 {
+			nonEmpty = append(nonEmpty, sample)
+		}
+	}
+	if len(nonEmpty) < 2 {
+		return nil, ErrNoSamples
+	}
+	history := make([]byte, 0, size)
+	n := (len(nonEmpty) + 1) / 2
+	for _, sample := range nonEmpty[:n] {
+		if left := size - len(history); len(sample) > left {
+			sample = sample[:left]
+		}
+		history = append(history, sample...)
+	}
+	// zstd needs a few bytes of history
+	if len(history) < 8 {
+		return nil, ErrNoSamples
+	}
+	return zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: nonEmpty[n:],
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+		Level:    zstd.SpeedDefault,
+	})
+}
+
+// KeySampler picks up to N keys uniformly among the keys offered, in a
+// single pass (reservoir sampling): the stores sample the values training a
+// dictionary without loading all the values of the namespace.
+type KeySampler struct {
+	N int
+	// Keys holds the keys picked, in no particular order.
+	Keys []string
+	seen int
+}
+
+// Offer offers key to the sample.
+func (s *KeySampler) Offer(key string) {
+	s.seen++
+	if len(s.Keys) < s.N {
+		s.Keys = append(s.Keys, key)
+		return
+	}
+	if i := rand.Intn(s.seen); i < s.N {
+		s.Keys[i] = key
+	}
+}
+
+// Dicts compresses the values with zstd and the last dictionary of their
+// namespace, or without dictionary if it has none yet. The ID of the
+// dictionary is written in the Meta of the value header, Decode finds it
+// whatever the namespace. It is safe for concurrent use, Close releases
+// its encoders and decoders.
+type Dicts struct {
+	store DictStore
+
+	mu sync.RWMutex
+	// byID holds all the dictionaries, current the last one of each
+	// namespace
+	byID    map[uint32]*dictCoder
+	current map[string]*dictCoder
+	lastID  uint32
+}
+
+// dictCoder compresses and decompresses the values with a dictionary.
+type dictCoder struct {
+	Dict
+
+	mu sync.Mutex
+	// encs holds the encoders by level, created on first use while the
+	// dictionary is current
+	encs map[zstd.EncoderLevel]*zstd.Encoder
+	// dec is created on the first value decompressed with the dictionary
+	dec *zstd.Decoder
+}
+
+// encoder returns the encoder of c at level.
+func (c *dictCoder) encoder(level zstd.EncoderLevel) (*zstd.Encoder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if enc, ok := c.encs[level]; ok {
+		return enc, nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(c.Data), zstd.WithEncoderLevel(level))
+	if err != nil {
+		return nil, fmt.Errorf("compress: dictionary %d: %s", c.ID, err)
+	}
+	if c.encs == nil {
+		c.encs = make(map[zstd.EncoderLevel]*zstd.Encoder)
+	}
+	c.encs[level] = enc
+	return enc, nil
+}
+
+// decoder returns the decoder of c.
+func (c *dictCoder) decoder() (*zstd.Decoder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dec != nil {
+		return c.dec, nil
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(c.Data))
+	if err != nil {
+		return nil, fmt.Errorf("compress: dictionary %d: %s", c.ID, err)
+	}
+	c.dec = dec
+	return dec, nil
+}
+
+// closeEncoders closes the encoders of c, when it is no longer current.
+func (c *dictCoder) closeEncoders() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, enc := range c.encs {
+		if e := enc.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	c.encs = nil
+	return err
+}
+
+// close closes the encoders and the decoder of c.
+func (c *dictCoder) close() error {
+	err := c.closeEncoders()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dec != nil {
+		c.dec.Close()
+		c.dec = nil
+	}
+	return err
+}
+
+// NewDicts returns the Dicts of the dictionaries saved in store.
+func NewDicts(store DictStore) (*Dicts, error) {
+	dicts, err := store.LoadDicts()
+	if err != nil {
+		return nil, err
+	}
+	d := &Dicts{
+		store:   store,
+		byID:    make(map[uint32]*dictCoder, len(dicts)),
+		current: make(map[string]*dictCoder),
+	}
+	for _, dict := range dicts {
+		if err := d.add(dict); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Current returns the dictionary compressing the values of namespace.
+func (d *Dicts) Current(namespace string) (Dict, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	c, ok := d.current[namespace]
+	if !ok {
+		return Dict{}, false
+	}
+	return c.Dict, true
+}
+
+// Train trains a new version of the dictionary of namespace from samples
+// of its values and saves it, the next values are compressed with it.
+// The dictionary is trained without holding the lock of d, the values are
+// compressed meanwhile with the previous one.
+func (d *Dicts) Train(namespace string, samples [][]byte) (Dict, error) {
+	// the ID is reserved first, the concurrent trainings don't share it
+	d.mu.Lock()
+	d.lastID++
+	dict := Dict{ID: d.lastID, Namespace: namespace}
+	d.mu.Unlock()
+
+	var err error
+	if dict.Data, err = TrainDict(dict.ID, samples, DefaultDictSize); err == nil {
+		err = d.store.SaveDict(dict)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		if d.lastID == dict.ID {
+			// not reserved by another training since
+			d.lastID--
+		}
+		return Dict{}, err
+	}
+	return dict, d.add(dict)
+}
+
+// add makes a dictionary available, the caller holds mu if d is shared.
+// The encoders of the dictionary it replaces are closed, its decoder is
+// kept for the values compressed with it.
+func (d *Dicts) add(dict Dict) error {
+	c := &dictCoder{Dict: dict}
+	d.byID[dict.ID] = c
+	if dict.ID > d.lastID {
+		d.lastID = dict.ID
+	}
+	cur, ok := d.current[dict.Namespace]
+	if ok && cur.ID > dict.ID {
+		return nil
+	}
+	d.current[dict.Namespace] = c
+	if ok {
+		return cur.closeEncoders()
+	}
+	return nil
+}
+
+// Close closes the encoders and the decoders of the dictionaries, d must
+// not be used afterwards.
+func (d *Dicts) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var err error
+	for _, c := range d.byID {
+		if e := c.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Encode compresses data with codec and the dictionary of namespace, and
+// prefixes it with its header. codec is zstd at the level of the store, or
+// of the policy rule of the value: it compresses data alone if namespace
+// has no dictionary yet.
+func (d *Dicts) Encode(namespace string, codec Codec, data []byte) ([]byte, error) {
+	// held while encoding, add closes the encoders of the dictionary it
+	// replaces
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	c, ok := d.current[namespace]
+	if !ok {
+		return Encode(codec, data)
+	}
+	enc, err := c.encoder(zstdLevel(codec))
+	if err != nil {
+		return nil, err
+	}
+	buf := AppendHeader(nil, Header{Codec: CodecZstd, Meta: dictMeta(c.ID)})
+	return enc.EncodeAll(data, buf), nil
+}
+
+// EncodeStream is the streaming version of Encode, see EncodeStream.
+func (d *Dicts) EncodeStream(namespace string, codec Codec, w io.Writer) (io.WriteCloser, error) {
+	d.mu.RLock()
+	c, ok := d.current[namespace]
+	d.mu.RUnlock()
+	if !ok {
+		return EncodeStream(codec, w)
+	}
+	if _, err := w.Write(AppendHeader(nil, Header{Codec: CodecZstd, Meta: dictMeta(c.ID)})); err != nil {
+		return nil, err
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderDict(c.Data), zstd.WithEncoderLevel(zstdLevel(codec)), zstd.WithEncoderConcurrency(1))
+}
+
+// Decode is like the Decode function, the values compressed with a
+// dictionary are decompressed with it.
+func (d *Dicts) Decode(data []byte, legacy Codec) ([]byte, error) {
+	h, encoded, err := ReadHeader(data)
+	if err != nil || h.Codec != CodecZstd || len(h.Meta) == 0 {
+		return Decode(data, legacy)
+	}
+	c, err := d.coder(h.Meta)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := c.decoder()
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(encoded, nil)
+}
+
+// DecodeStream is the streaming version of Decode.
+func (d *Dicts) DecodeStream(r io.Reader, legacy Codec) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	// the header of the values with a dictionary, the uvarints included
+	prefix, _ := br.Peek(len(headerMagic) + 2 + binary.MaxVarintLen32)
+	h, encoded, err := ReadHeader(prefix)
+	if err != nil || h.Codec != CodecZstd || len(h.Meta) == 0 {
+		return DecodeStream(br, legacy)
+	}
+	c, err := d.coder(h.Meta)
+	if err != nil {
+		return nil, err
+	}
+	br.Discard(len(prefix) - len(encoded))
+	dec, err := zstd.NewReader(br, zstd.WithDecoderDicts(c.Data), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+// coder returns the dictionary of the Meta of a value header.
+func (d *Dicts) coder(meta []byte) (*dictCoder, error) {
+	id, n := binary.Uvarint(meta)
+	if n <= 0 || id > math.MaxUint32 {
+		return nil, ErrInvalidHeader
+	}
+	d.mu.RLock()
+	c, ok := d.byID[uint32(id)]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownDict, id)
+	}
+	return c, nil
+}
+
+// dictMeta returns the Meta of the values compressed with dictionary id.
+func dictMeta(id uint32) []byte {
+	return appendUvarint(nil, uint64(id))
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
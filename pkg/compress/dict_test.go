@@ -0,0 +1,220 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// memDictStore keeps the dictionaries in memory, marshaled.
+type memDictStore [][]byte
+
+func (s *memDictStore) LoadDicts() ([]Dict, error) {
+	dicts := make([]Dict, len(*s))
+	for i, data := range *s {
+		if err := dicts[i].UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+	}
+	return dicts, nil
+}
+
+func (s *memDictStore) SaveDict(d Dict) error {
+	data, err := d.MarshalBinary()
+	*s = append(*s, data)
+	return err
+}
+
+func testPage(i int) []byte {
+	return []byte(fmt.Sprintf(`<!DOCTYPE html><html><head><title>Item %d</title>
+<link rel="stylesheet" href="/static/site.css"></head><body><nav class="menu">
+<a href="/">Home</a><a href="/items">Items</a><a href="/about">About</a></nav>
+<div class="item" id="item-%d"><h1>Item %d</h1><p class="price">%d.99</p></div>
+<footer class="footer">Copyright example.com, all rights reserved</footer></body></html>`, i, i, i, i))
+}
+
+func TestDicts(t *testing.T) {
+	store := &memDictStore{}
+	dicts, err := NewDicts(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	page := testPage(1000)
+	codec, _ := Lookup("zstd")
+	plain, err := dicts.Encode("example.com", codec, page)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h, _, _ := ReadHeader(plain); h.Codec != CodecZstd || len(h.Meta) != 0 {
+		t.Errorf("expected zstd without dictionary, got %+v", h)
+	}
+
+	var samples [][]byte
+	for i := 0; i < 50; i++ {
+		samples = append(samples, testPage(i))
+	}
+	if _, err := dicts.Train("example.com", nil); err != ErrNoSamples {
+		t.Errorf("expected %s, got %v", ErrNoSamples, err)
+	}
+	dict, err := dicts.Train("example.com", samples)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	first, _ := dicts.Encode("example.com", codec, page)
+	if len(first) >= len(plain) {
+		t.Errorf("expected the dictionary to help, got %d bytes instead of %d", len(first), len(plain))
+	}
+	if _, err := Decode(first, nil); err == nil {
+		t.Error("expected an error without the dictionary")
+	}
+
+	// retrained, the values of the first dictionary stay readable
+	if d, _ := dicts.Train("example.com", samples[25:]); d.ID == dict.ID {
+		t.Errorf("expected a new version, got %d", d.ID)
+	}
+	second, _ := dicts.Encode("example.com", codec, page)
+	reloaded, err := NewDicts(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d, ok := reloaded.Current("example.com"); !ok || d.ID != dict.ID+1 || d.Namespace != "example.com" {
+		t.Errorf("unexpected current dictionary %d of %q", d.ID, d.Namespace)
+	}
+	for _, encoded := range [][]byte{plain, first, second} {
+		decoded, err := reloaded.Decode(encoded, nil)
+		if err != nil || !bytes.Equal(decoded, page) {
+			t.Errorf("unexpected value %q (%v)", decoded, err)
+		}
+		r, err := reloaded.DecodeStream(bytes.NewReader(encoded), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		decoded, err = ioutil.ReadAll(r)
+		r.Close()
+		if err != nil || !bytes.Equal(decoded, page) {
+			t.Errorf("unexpected streamed value %q (%v)", decoded, err)
+		}
+	}
+
+	var b bytes.Buffer
+	w, err := reloaded.EncodeStream("example.com", codec, &b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	w.Write(page)
+	w.Close()
+	if decoded, err := reloaded.Decode(b.Bytes(), nil); err != nil || !bytes.Equal(decoded, page) {
+		t.Errorf("unexpected value %q (%v)", decoded, err)
+	}
+}
+
+func TestDictsLevel(t *testing.T) {
+	dicts, _ := NewDicts(&memDictStore{})
+	page := testPage(1000)
+	fastest, _ := Lookup("zstd:1")
+	best, _ := Lookup("zstd:19")
+
+	// the values are compressed at the level of the codec, with or without
+	// dictionary
+	encoded, err := dicts.Encode("example.com", best, page)
+	if expected, _ := Encode(best, page); err != nil || !bytes.Equal(encoded, expected) {
+		t.Errorf("expected the value compressed at level 19, got %v", err)
+	}
+	var samples [][]byte
+	for i := 0; i < 50; i++ {
+		samples = append(samples, testPage(i))
+	}
+	if _, err := dicts.Train("example.com", samples); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fast, _ := dicts.Encode("example.com", fastest, page)
+	slow, _ := dicts.Encode("example.com", best, page)
+	if bytes.Equal(fast, slow) {
+		t.Error("expected the level to be applied with the dictionary")
+	}
+	for _, encoded := range [][]byte{fast, slow} {
+		if decoded, err := dicts.Decode(encoded, nil); err != nil || !bytes.Equal(decoded, page) {
+			t.Errorf("unexpected value %q (%v)", decoded, err)
+		}
+	}
+
+	var b bytes.Buffer
+	w, _ := dicts.EncodeStream("example.com", best, &b)
+	w.Write(page)
+	w.Close()
+	if !bytes.Equal(b.Bytes(), slow) {
+		t.Error("expected the stream compressed at level 19")
+	}
+}
+
+func TestTrainDictSameSamples(t *testing.T) {
+	page := testPage(1)
+	if _, err := TrainDict(1, [][]byte{page, page}, DefaultDictSize); err == nil {
+		t.Error("expected an error for samples without literals")
+	}
+}
+
+func TestKeySampler(t *testing.T) {
+	s := KeySampler{N: 10}
+	picked := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		s.Offer(fmt.Sprint(i))
+	}
+	for _, key := range s.Keys {
+		picked[key] = true
+	}
+	if len(s.Keys) != 10 || len(picked) != 10 {
+		t.Errorf("expected 10 distinct keys, got %v", s.Keys)
+	}
+	// the first keys are not all kept
+	first := 0
+	for i := 0; i < 10; i++ {
+		if picked[fmt.Sprint(i)] {
+			first++
+		}
+	}
+	if first == 10 {
+		t.Errorf("expected a sample of all the keys, got %v", s.Keys)
+	}
+}
+
+func TestDictsClose(t *testing.T) {
+	dicts, _ := NewDicts(&memDictStore{})
+	page := testPage(1000)
+	codec, _ := Lookup("zstd")
+	var samples [][]byte
+	for i := 0; i < 50; i++ {
+		samples = append(samples, testPage(i))
+	}
+	first, _ := dicts.Train("example.com", samples)
+	encoded, _ := dicts.Encode("example.com", codec, page)
+	old := dicts.byID[first.ID]
+	if len(old.encs) != 1 || old.dec != nil {
+		t.Fatalf("expected an encoder and no decoder, got %d and %v", len(old.encs), old.dec)
+	}
+
+	// the encoders of the dictionary replaced are closed, its decoder is
+	// created for the values compressed with it
+	if _, err := dicts.Train("example.com", samples); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(old.encs) != 0 {
+		t.Errorf("expected the encoders of dictionary %d to be closed", first.ID)
+	}
+	if decoded, err := dicts.Decode(encoded, nil); err != nil || !bytes.Equal(decoded, page) {
+		t.Fatalf("unexpected value %q (%v)", decoded, err)
+	}
+	if old.dec == nil {
+		t.Error("expected a decoder")
+	}
+
+	if err := dicts.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for id, c := range dicts.byID {
+		if len(c.encs) != 0 || c.dec != nil {
+			t.Errorf("expected dictionary %d to be closed", id)
+		}
+	}
+}
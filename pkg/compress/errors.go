@@ -5,6 +5,18 @@ import (
 )
 
 var (
-	ErrUnknown = errors.New("unknown compression format")
-	ErrEmpty   = errors.New("no data to read")
+	ErrUnknown       = errors.New("unknown compression format")
+	ErrEmpty         = errors.New("no data to read")
+	ErrUnknownCodec  = errors.New("unknown codec")
+	ErrNoHeader      = errors.New("no value header")
+	ErrInvalidHeader = errors.New("invalid value header")
+	ErrUnknownDict   = errors.New("unknown dictionary")
+	ErrInvalidDict   = errors.New("invalid dictionary")
+	ErrNoSamples     = errors.New("no samples to train a dictionary")
+
+	ErrNotScanner         = errors.New("the store can't list its keys")
+	ErrUnsupportedArchive = errors.New("unsupported archive format")
+	ErrNoManifest         = errors.New("archive without manifest")
+
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
 )
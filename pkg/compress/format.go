@@ -2,7 +2,6 @@ package compress
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -16,13 +15,11 @@ type compressed struct {
 
 type Format int
 
-var formatIndex = [...]uint8{0, 7, 11, 16, 19, 22, 26, 30, 33, 42, 53}
-
 func (i Format) String() string {
-	if i < 0 || i >= Format(len(formatIndex)-1) {
+	if i < 0 || int(i) >= len(formatNames) {
 		return fmt.Sprintf("Format(%d)", i)
 	}
-	return formatName[formatIndex[i]:formatIndex[i+1]]
+	return formatNames[i]
 }
 
 // Ext returns the extension for the format. Formats may have more than one
@@ -39,8 +36,18 @@ func (f Format) Ext() string {
 		return ".tar"
 	case Zip, ZipEmpty, ZipSpanned:
 		return ".zip"
-		//case LZW:
-		//	return ".Z"
+	case SnappyFramed:
+		return ".sz"
+	case Snappy:
+		return ".snappy"
+	case Zstd:
+		return ".zst"
+	case XZ:
+		return ".xz"
+	case LZW:
+		return ".Z"
+	case Brotli:
+		return ".br"
 	}
 	return "unknown"
 }
@@ -57,6 +64,11 @@ func ParseFormat(s string) Format {
 	if s[0] == '.' {
 		s = s[1:]
 	}
+	// .z is pack, not LZW
+	switch s {
+	case "Z", "tar.Z", "taZ":
+		return LZW
+	}
 	s = strings.ToLower(s)
 	switch s {
 	case "gzip", "tar.gz", "tgz":
@@ -69,6 +81,16 @@ func ParseFormat(s string) Format {
 		return LZ4
 	case "zip":
 		return Zip
+	case "sz":
+		return SnappyFramed
+	case "snappy":
+		return Snappy
+	case "zst", "zstd", "tar.zst", "tzst":
+		return Zstd
+	case "xz", "tar.xz", "txz":
+		return XZ
+	case "br", "tar.br":
+		return Brotli
 	}
 	return Unknown
 }
@@ -121,12 +143,25 @@ func GetFormat(r io.ReaderAt) (Format, error) {
 	if ok {
 		return BZip2, nil
 	}
-	//ok, err = IsLZW(r)
-	//if err != nil {
-	//	return Unknown, err
-	//}
-	//if ok {
-	//	return LZW, nil
-	//}
+	for _, check := range []struct {
+		format Format
+		is     func(io.ReaderAt) (bool, error)
+	}{
+		{SnappyFramed, IsSnappyFramed},
+		{Zstd, IsZstd},
+		{XZ, IsXZ},
+		{LZW, IsLZW},
+		// the formats without magic number come last
+		{Snappy, IsSnappy},
+		{Brotli, IsBrotli},
+	} {
+		ok, err = check.is(r)
+		if err != nil {
+			return Unknown, err
+		}
+		if ok {
+			return check.format, nil
+		}
+	}
 	return Unknown, ErrUnknown
 }
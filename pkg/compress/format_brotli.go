@@ -1,18 +1,106 @@
 package compress
 
 import (
-	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
 
 	// external
+	"gopkg.in/kothar/brotli-go.v0/dec"
 	"gopkg.in/kothar/brotli-go.v0/enc"
 )
 
-func compressWithBrotli(input []byte) *bytes.Buffer {
+const (
+	// BrotliMaxQuality is the quality compressing the most, 0 being the
+	// fastest. It is the quality of the "brotli" codec.
+	BrotliMaxQuality = 11
+	// BrotliMinWindow and BrotliMaxWindow bound the window of the encoder,
+	// as the base 2 logarithm of its size. The decoder needs as much memory.
+	BrotliMinWindow = 10
+	BrotliMaxWindow = 24
+	// BrotliDefaultWindow is the window of the "brotli" codec, 4MiB.
+	BrotliDefaultWindow = 22
+)
+
+// brotliCodec compresses the values with brotli at the default quality and
+// window.
+type brotliCodec struct{}
+
+func (brotliCodec) ID() CodecID  { return CodecBrotli }
+func (brotliCodec) Name() string { return "brotli" }
+
+func (brotliCodec) Encode(data []byte) ([]byte, error) {
+	return enc.CompressBuffer(enc.NewBrotliParams(), data, make([]byte, 0))
+}
+
+func (brotliCodec) Decode(data []byte) ([]byte, error) {
+	return dec.DecompressBuffer(data, make([]byte, 0))
+}
+
+// The brotli encoders and decoders can't be reset, they are not pooled.
+
+func (brotliCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return enc.NewBrotliWriter(enc.NewBrotliParams(), nopWriteCloser{w}), nil
+}
+
+func (brotliCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return dec.NewBrotliReader(ioutil.NopCloser(r)), nil
+}
+
+func (brotliCodec) Levels() []int { return []int{1, 6, BrotliMaxQuality} }
+
+func (brotliCodec) WithLevel(level int) (Codec, error) {
+	return NewBrotliCodec(level, BrotliDefaultWindow)
+}
+
+func (brotliCodec) WithWindow(window int) (Codec, error) {
+	return NewBrotliCodec(BrotliMaxQuality, window)
+}
+
+// NewBrotliCodec returns a brotli codec compressing at a quality, from 0 to
+// BrotliMaxQuality, with a window between BrotliMinWindow and
+// BrotliMaxWindow, BrotliDefaultWindow if zero. Its values are decoded
+// whatever their quality and window.
+func NewBrotliCodec(quality, window int) (Codec, error) {
+	if window == 0 {
+		window = BrotliDefaultWindow
+	}
+	if quality < 0 || quality > BrotliMaxQuality {
+		return nil, errLevel(brotliCodec{}, quality)
+	}
+	if window < BrotliMinWindow || window > BrotliMaxWindow {
+		return nil, fmt.Errorf("compress: invalid brotli window %d", window)
+	}
+	return brotliParamsCodec{quality: quality, window: window}, nil
+}
+
+// brotliParamsCodec compresses the values with brotli at a quality and a
+// window.
+type brotliParamsCodec struct {
+	brotliCodec
+	quality int
+	window  int
+}
+
+func (c brotliParamsCodec) WithLevel(level int) (Codec, error) {
+	return NewBrotliCodec(level, c.window)
+}
+
+func (c brotliParamsCodec) WithWindow(window int) (Codec, error) {
+	return NewBrotliCodec(c.quality, window)
+}
+
+func (c brotliParamsCodec) params() *enc.BrotliParams {
 	params := enc.NewBrotliParams()
-	// brotli supports quality values from 0 to 11 included
-	// 0 is the fastest, 11 is the most compressed but slowest
-	params.SetQuality(11)
-	compressed, _ := enc.CompressBuffer(params, input, make([]byte, 0))
-	buf := bytes.NewBuffer(compressed)
-	return buf
+	params.SetQuality(c.quality)
+	params.SetLgwin(c.window)
+	return params
+}
+
+func (c brotliParamsCodec) Encode(data []byte) ([]byte, error) {
+	return enc.CompressBuffer(c.params(), data, make([]byte, 0))
+}
+
+func (c brotliParamsCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return enc.NewBrotliWriter(c.params(), nopWriteCloser{w}), nil
 }
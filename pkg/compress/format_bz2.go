@@ -1 +1,61 @@
 package compress
+
+import (
+	"io"
+
+	// external
+	"github.com/dsnet/compress/bzip2"
+)
+
+// compress/bzip2 only decompresses, and its reader can't be reused.
+var (
+	bzip2Writers = &encoderPool{
+		new: func(w io.Writer) (io.WriteCloser, error) {
+			return bzip2.NewWriter(w, nil)
+		},
+		reset: func(enc io.WriteCloser, w io.Writer) error {
+			return enc.(*bzip2.Writer).Reset(w)
+		},
+	}
+	bzip2Readers = &decoderPool{
+		new: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r, nil)
+		},
+		reset: func(dec io.Reader, r io.Reader) error {
+			return dec.(*bzip2.Reader).Reset(r)
+		},
+	}
+)
+
+// bzip2Codec compresses the values with bzip2.
+type bzip2Codec struct{}
+
+func (bzip2Codec) ID() CodecID                          { return CodecBZip2 }
+func (bzip2Codec) Name() string                         { return "bzip2" }
+func (c bzip2Codec) Encode(data []byte) ([]byte, error) { return encodeStream(c, data) }
+func (c bzip2Codec) Decode(data []byte) ([]byte, error) { return decodeStream(c, data) }
+
+func (bzip2Codec) NewWriter(w io.Writer) (io.WriteCloser, error) { return bzip2Writers.get(w) }
+func (bzip2Codec) NewReader(r io.Reader) (io.ReadCloser, error)  { return bzip2Readers.get(r) }
+
+func (bzip2Codec) Levels() []int { return []int{bzip2.BestSpeed, 6, bzip2.BestCompression} }
+
+func (c bzip2Codec) WithLevel(level int) (Codec, error) {
+	if level < bzip2.BestSpeed || level > bzip2.BestCompression {
+		return nil, errLevel(c, level)
+	}
+	return bzip2LevelCodec{level: level}, nil
+}
+
+// bzip2LevelCodec compresses the values with bzip2 at a level, its encoders
+// are not pooled.
+type bzip2LevelCodec struct {
+	bzip2Codec
+	level int
+}
+
+func (c bzip2LevelCodec) Encode(data []byte) ([]byte, error) { return encodeStream(c, data) }
+
+func (c bzip2LevelCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: c.level})
+}
@@ -3,9 +3,67 @@ package compress
 import (
 	"bytes"
 	"compress/gzip"
+	"io"
 	"io/ioutil"
 )
 
+var (
+	gzipWriters = &encoderPool{
+		new: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		reset: func(enc io.WriteCloser, w io.Writer) error {
+			enc.(*gzip.Writer).Reset(w)
+			return nil
+		},
+	}
+	gzipReaders = &decoderPool{
+		new: func(r io.Reader) (io.Reader, error) {
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return zr, nil
+		},
+		reset: func(dec io.Reader, r io.Reader) error {
+			return dec.(*gzip.Reader).Reset(r)
+		},
+	}
+)
+
+// gzipCodec compresses the values with gzip at the default level.
+type gzipCodec struct{}
+
+func (gzipCodec) ID() CodecID                          { return CodecGZip }
+func (gzipCodec) Name() string                         { return "gzip" }
+func (c gzipCodec) Encode(data []byte) ([]byte, error) { return encodeStream(c, data) }
+func (c gzipCodec) Decode(data []byte) ([]byte, error) { return decodeStream(c, data) }
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return gzipWriters.get(w) }
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return gzipReaders.get(r) }
+
+func (gzipCodec) Levels() []int { return []int{gzip.BestSpeed, 6, gzip.BestCompression} }
+
+func (c gzipCodec) WithLevel(level int) (Codec, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, errLevel(c, level)
+	}
+	return gzipLevelCodec{level: level}, nil
+}
+
+// gzipLevelCodec compresses the values with gzip at a level, its encoders
+// are not pooled.
+type gzipLevelCodec struct {
+	gzipCodec
+	level int
+}
+
+func (c gzipLevelCodec) Encode(data []byte) ([]byte, error) { return encodeStream(c, data) }
+
+func (c gzipLevelCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, c.level)
+}
+
 func ungzipData(data []byte) ([]byte, error) {
 	raw := bytes.NewBuffer(data)
 	r, err := gzip.NewReader(raw)
@@ -1 +1,40 @@
 package compress
+
+import (
+	"io"
+
+	// external
+	"github.com/pierrec/lz4"
+)
+
+var (
+	lz4Writers = &encoderPool{
+		new: func(w io.Writer) (io.WriteCloser, error) {
+			return lz4.NewWriter(w), nil
+		},
+		reset: func(enc io.WriteCloser, w io.Writer) error {
+			enc.(*lz4.Writer).Reset(w)
+			return nil
+		},
+	}
+	lz4Readers = &decoderPool{
+		new: func(r io.Reader) (io.Reader, error) {
+			return lz4.NewReader(r), nil
+		},
+		reset: func(dec io.Reader, r io.Reader) error {
+			dec.(*lz4.Reader).Reset(r)
+			return nil
+		},
+	}
+)
+
+// lz4Codec compresses the values with the LZ4 frame format.
+type lz4Codec struct{}
+
+func (lz4Codec) ID() CodecID                          { return CodecLZ4 }
+func (lz4Codec) Name() string                         { return "lz4" }
+func (c lz4Codec) Encode(data []byte) ([]byte, error) { return encodeStream(c, data) }
+func (c lz4Codec) Decode(data []byte) ([]byte, error) { return decodeStream(c, data) }
+
+func (lz4Codec) NewWriter(w io.Writer) (io.WriteCloser, error) { return lz4Writers.get(w) }
+func (lz4Codec) NewReader(r io.Reader) (io.ReadCloser, error)  { return lz4Readers.get(r) }
@@ -1,10 +1,54 @@
 package compress
 
 import (
+	"io"
+
 	// external
 	"github.com/golang/snappy"
 )
 
+var (
+	snappyWriters = &encoderPool{
+		new: func(w io.Writer) (io.WriteCloser, error) {
+			return snappy.NewBufferedWriter(w), nil
+		},
+		reset: func(enc io.WriteCloser, w io.Writer) error {
+			enc.(*snappy.Writer).Reset(w)
+			return nil
+		},
+	}
+	snappyReaders = &decoderPool{
+		new: func(r io.Reader) (io.Reader, error) {
+			return snappy.NewReader(r), nil
+		},
+		reset: func(dec io.Reader, r io.Reader) error {
+			dec.(*snappy.Reader).Reset(r)
+			return nil
+		},
+	}
+)
+
+// snappyCodec compresses the values with the snappy block format, as badger
+// stores did before the value headers.
+type snappyCodec struct{}
+
+func (snappyCodec) ID() CodecID                        { return CodecSnappy }
+func (snappyCodec) Name() string                       { return "snappy" }
+func (snappyCodec) Encode(data []byte) ([]byte, error) { return Compress(data) }
+func (snappyCodec) Decode(data []byte) ([]byte, error) { return Decompress(data) }
+
+// snappyFramedCodec compresses the values with the snappy framing format,
+// the streaming form of snappy.
+type snappyFramedCodec struct{}
+
+func (snappyFramedCodec) ID() CodecID                          { return CodecSnappyFramed }
+func (snappyFramedCodec) Name() string                         { return "snappy-framed" }
+func (c snappyFramedCodec) Encode(data []byte) ([]byte, error) { return encodeStream(c, data) }
+func (c snappyFramedCodec) Decode(data []byte) ([]byte, error) { return decodeStream(c, data) }
+
+func (snappyFramedCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return snappyWriters.get(w) }
+func (snappyFramedCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return snappyReaders.get(r) }
+
 func Compress(data []byte) ([]byte, error) {
 	return snappy.Encode([]byte{}, data), nil
 }
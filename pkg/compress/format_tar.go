@@ -1 +1,60 @@
 package compress
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// tarWriter writes the entries of a tar archive.
+type tarWriter struct {
+	tw *tar.Writer
+}
+
+func newTarWriter(w io.Writer) *tarWriter {
+	return &tarWriter{tw: tar.NewWriter(w)}
+}
+
+func (w *tarWriter) WriteEntry(name string, data []byte, modTime time.Time) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.tw.Write(data)
+	return err
+}
+
+// Close writes the end of the archive, the underlying writer is not closed.
+func (w *tarWriter) Close() error {
+	return w.tw.Close()
+}
+
+// tarReader reads the regular files of a tar archive.
+type tarReader struct {
+	tr *tar.Reader
+}
+
+func newTarReader(r io.Reader) *tarReader {
+	return &tarReader{tr: tar.NewReader(r)}
+}
+
+func (r *tarReader) Next() (string, []byte, error) {
+	for {
+		h, err := r.tr.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeRegA {
+			continue
+		}
+		data, err := ioutil.ReadAll(r.tr)
+		return h.Name, data, err
+	}
+}
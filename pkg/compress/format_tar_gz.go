@@ -1 +1,35 @@
 package compress
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// tarGzWriter writes the entries of a gzipped tar archive.
+type tarGzWriter struct {
+	*tarWriter
+	gw *gzip.Writer
+}
+
+func newTarGzWriter(w io.Writer) *tarGzWriter {
+	gw := gzip.NewWriter(w)
+	return &tarGzWriter{tarWriter: newTarWriter(gw), gw: gw}
+}
+
+// Close writes the end of the archive and flushes the gzip stream, the
+// underlying writer is not closed.
+func (w *tarGzWriter) Close() error {
+	if err := w.tarWriter.Close(); err != nil {
+		return err
+	}
+	return w.gw.Close()
+}
+
+// newTarGzReader returns the reader of a gzipped tar archive.
+func newTarGzReader(r io.Reader) (*tarReader, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return newTarReader(gr), nil
+}
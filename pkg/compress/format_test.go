@@ -5,6 +5,8 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"compress/lzw"
+	"math/rand"
 	"os"
 	"testing"
 
@@ -184,22 +186,15 @@ func TestIsZip(t *testing.T) {
 	}
 }
 
-// TODO: commented out because LZW output doesn't have the magic number.
-// Figure out why and resolve.
-// Another example http://play.golang.org/p/zGLAj1ruoh
-/*
+// compress/lzw doesn't write the header of compress(1), it is added by hand.
 func TestIsLZW(t *testing.T) {
 	var buf bytes.Buffer
+	buf.Write([]byte{0x1f, 0x9d, 0x90})
 	lw := lzw.NewWriter(&buf, lzw.LSB, 8)
-	n, err := lw.Write(testVal)
-	if err != nil {
+	if _, err := lw.Write(testVal); err != nil {
 		t.Errorf("Unexpected error: %s", err)
 	}
-	if n != 452 {
-		t.Errorf("Expected 452 bytes to be copied; %d were", n)
-	}
 	lw.Close()
-	t.Errorf("%x", buf.Bytes())
 	rr := bytes.NewReader(buf.Bytes())
 	ok, err := IsLZW(rr)
 	if err != nil {
@@ -216,7 +211,56 @@ func TestIsLZW(t *testing.T) {
 		t.Errorf("Expected format to be LZW got %s", format)
 	}
 }
-*/
+
+// The snappy and brotli formats have no magic number, they are detected by
+// decoding the contents.
+func TestIsSnappyBrotli(t *testing.T) {
+	tests := []struct {
+		codec  string
+		format Format
+	}{
+		{"snappy", Snappy},
+		{"snappy-framed", SnappyFramed},
+		{"brotli", Brotli},
+	}
+	for _, test := range tests {
+		c, _ := Lookup(test.codec)
+		for _, val := range [][]byte{testVal, bytes.Repeat(testVal, 5000)} {
+			encoded, err := c.Encode(val)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", test.codec, err)
+			}
+			format, err := GetFormat(bytes.NewReader(encoded))
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.codec, err)
+			}
+			if format != test.format {
+				t.Errorf("%s: expected format to be %s, got %s", test.codec, test.format, format)
+			}
+		}
+	}
+
+	for _, val := range [][]byte{testVal, []byte("<html><body>hello</body></html>")} {
+		if format, err := GetFormat(bytes.NewReader(val)); err != ErrUnknown {
+			t.Errorf("expected plain text to be unknown, got %s (%v)", format, err)
+		}
+	}
+
+	// the random data, e.g. encrypted or compressed bodies, is not brotli
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		val := make([]byte, 1+rnd.Intn(4<<10))
+		rnd.Read(val)
+		if ok, _ := IsBrotli(bytes.NewReader(val)); ok {
+			t.Fatalf("expected %d random bytes not to be brotli", len(val))
+		}
+	}
+	// nor the brotli streams followed by other bytes
+	encoded, _ := brotliCodec{}.Encode(testVal)
+	if ok, _ := IsBrotli(bytes.NewReader(append(encoded, 0, 0, 0))); ok {
+		t.Error("expected the trailing bytes to be rejected")
+	}
+}
 
 func TestString(t *testing.T) {
 	tests := []struct {
@@ -235,6 +279,12 @@ func TestString(t *testing.T) {
 		{Zip, "Zip"},
 		{ZipEmpty, "Empty Zip"},
 		{ZipSpanned, "Spanned Zip"},
+		{SnappyFramed, "Snappy Framed"},
+		{Snappy, "Snappy"},
+		{Zstd, "Zstd"},
+		{XZ, "XZ"},
+		{LZW, "LZW"},
+		{Brotli, "Brotli"},
 	}
 	for _, test := range tests {
 		s := test.f.String()
@@ -261,6 +311,12 @@ func TestExt(t *testing.T) {
 		{Zip, ".zip"},
 		{ZipEmpty, ".zip"},
 		{ZipSpanned, ".zip"},
+		{SnappyFramed, ".sz"},
+		{Snappy, ".snappy"},
+		{Zstd, ".zst"},
+		{XZ, ".xz"},
+		{LZW, ".Z"},
+		{Brotli, ".br"},
 	}
 	for _, test := range tests {
 		s := test.f.Ext()
@@ -290,6 +346,15 @@ func TestParseFormat(t *testing.T) {
 		{"tar.lz4", LZ4},
 		{"tz4", LZ4},
 		{"zip", Zip},
+		{"sz", SnappyFramed},
+		{"snappy", Snappy},
+		{".zst", Zstd},
+		{"tar.zst", Zstd},
+		{"xz", XZ},
+		{"txz", XZ},
+		{".Z", LZW},
+		{"tar.Z", LZW},
+		{"br", Brotli},
 	}
 	for _, test := range tests {
 		f := ParseFormat(test.v)
@@ -318,6 +383,10 @@ func TestGetFormat(t *testing.T) {
 		{Zip, Zip, magicnumZip, 0, nil},
 		{ZipEmpty, Zip, magicnumZipEmpty, 0, nil},
 		{ZipSpanned, Zip, magicnumZipSpanned, 0, nil},
+		{SnappyFramed, SnappyFramed, magicnumSnappy, 0, nil},
+		{Zstd, Zstd, magicnumZstd, 0, nil},
+		{XZ, XZ, magicnumXZ, 0, nil},
+		{LZW, LZW, magicnumLZW, 0, nil},
 	}
 
 	for i, test := range tests {
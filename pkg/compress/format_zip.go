@@ -1 +1,73 @@
 package compress
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// zipWriter writes the entries of a zip archive, deflated.
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{zw: zip.NewWriter(w)}
+}
+
+func (w *zipWriter) WriteEntry(name string, data []byte, modTime time.Time) error {
+	fw, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// Close writes the central directory, the underlying writer is not closed.
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+// zipReader reads the files of a zip archive in their order.
+type zipReader struct {
+	files []*zip.File
+}
+
+// newZipReader reads the whole archive in memory, its directory being at
+// its end.
+func newZipReader(r io.Reader) (*zipReader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &zipReader{files: zr.File}, nil
+}
+
+func (r *zipReader) Next() (string, []byte, error) {
+	for len(r.files) > 0 {
+		f := r.files[0]
+		r.files = r.files[1:]
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		return f.Name, data, err
+	}
+	return "", nil, io.EOF
+}
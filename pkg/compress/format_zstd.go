@@ -0,0 +1,93 @@
+package compress
+
+import (
+	"io"
+
+	// external
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// zstdEncoder and zstdDecoder compress and decompress the whole values,
+	// they are safe for concurrent use.
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+
+	zstdWriters = &encoderPool{
+		new: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		},
+		reset: func(enc io.WriteCloser, w io.Writer) error {
+			enc.(*zstd.Encoder).Reset(w)
+			return nil
+		},
+	}
+	// the decoders don't implement io.Closer, closing one would stop it for
+	// good
+	zstdReaders = &decoderPool{
+		new: func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		},
+		reset: func(dec io.Reader, r io.Reader) error {
+			return dec.(*zstd.Decoder).Reset(r)
+		},
+	}
+)
+
+// zstdCodec compresses the values with Zstandard, see Dicts for the values
+// compressed with a dictionary.
+type zstdCodec struct{}
+
+func (zstdCodec) ID() CodecID  { return CodecZstd }
+func (zstdCodec) Name() string { return "zstd" }
+
+func (zstdCodec) Encode(data []byte) ([]byte, error) {
+	return zstdEncoder.EncodeAll(data, nil), nil
+}
+
+func (zstdCodec) Decode(data []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(data, nil)
+}
+
+func (zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return zstdWriters.get(w) }
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return zstdReaders.get(r) }
+
+// Levels returns the zstd levels of the four speeds of the encoder.
+func (zstdCodec) Levels() []int { return []int{1, 3, 7, 11} }
+
+func (c zstdCodec) WithLevel(level int) (Codec, error) {
+	if level < 1 || level > 22 {
+		return nil, errLevel(c, level)
+	}
+	l := zstd.EncoderLevelFromZstd(level)
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(l))
+	if err != nil {
+		return nil, err
+	}
+	return zstdLevelCodec{level: l, enc: enc}, nil
+}
+
+// zstdLevelCodec compresses the values with zstd at a level, its encoders
+// are not pooled.
+type zstdLevelCodec struct {
+	zstdCodec
+	level zstd.EncoderLevel
+	enc   *zstd.Encoder
+}
+
+func (c zstdLevelCodec) Encode(data []byte) ([]byte, error) {
+	return c.enc.EncodeAll(data, nil), nil
+}
+
+func (c zstdLevelCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(c.level), zstd.WithEncoderConcurrency(1))
+}
+
+// zstdLevel returns the encoder level of a zstd codec, the default one if
+// it has none.
+func zstdLevel(c Codec) zstd.EncoderLevel {
+	if lc, ok := c.(zstdLevelCodec); ok {
+		return lc.level
+	}
+	return zstd.SpeedDefault
+}
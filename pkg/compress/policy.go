@@ -0,0 +1,148 @@
+package compress
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMinSize is the size under which DefaultPolicy stores the values
+// as they are, the header and the codec overhead outweigh the gain.
+const DefaultMinSize = 1024
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// Policy decides how each value is compressed, from its content type and
+// its size. The codec it picks is recorded in the header of the value, the
+// reads don't depend on the policy.
+type Policy struct {
+	// MinSize is the size under which the values are stored as they are.
+	MinSize int
+	// Codec is the name of the codec of the compressible content types
+	// without a rule in Codecs.
+	Codec string
+	// Codecs maps media types to the name of their codec, "raw" to store
+	// them as they are. A "type/*" key matches all the subtypes of type.
+	// The types without a rule are compressed with Codec if the
+	// compressible library reports them compressible.
+	Codecs map[string]string
+}
+
+// DefaultPolicy returns a policy compressing the compressible content types
+// of at least DefaultMinSize bytes with gzip.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MinSize: DefaultMinSize,
+		Codec:   "gzip",
+	}
+}
+
+// The reasons of a Decision.
+const (
+	ReasonTooSmall       = "too small"
+	ReasonRule           = "rule"
+	ReasonIncompressible = "incompressible"
+	ReasonCompressible   = "compressible"
+)
+
+// Decision is the outcome of a Policy for a value.
+type Decision struct {
+	// ContentType is the media type of the value, the hint given to the
+	// policy or the sniffed one.
+	ContentType string
+	// Codec is the codec the value is written with.
+	Codec Codec
+	// Reason tells why the codec was picked, one of the Reason constants.
+	Reason string
+}
+
+// Decide returns the codec of a value. contentType is a hint, the type is
+// sniffed from head when it is empty. head is the beginning of the value,
+// at least its first 512 bytes if it is longer, and size its length or -1
+// if it is unknown but longer than head.
+func (p *Policy) Decide(contentType string, head []byte, size int) (Decision, error) {
+	d := Decision{ContentType: mediaType(contentType)}
+	if d.ContentType == "" {
+		if len(head) > sniffLen {
+			head = head[:sniffLen]
+		}
+		d.ContentType = mediaType(http.DetectContentType(head))
+	}
+
+	name := "raw"
+	switch {
+	case size >= 0 && size < p.MinSize:
+		d.Reason = ReasonTooSmall
+	case p.rule(d.ContentType) != "":
+		name, d.Reason = p.rule(d.ContentType), ReasonRule
+	case !IsCompressible(d.ContentType):
+		d.Reason = ReasonIncompressible
+	default:
+		name, d.Reason = p.Codec, ReasonCompressible
+	}
+	c, err := Lookup(name)
+	if err != nil {
+		return Decision{}, err
+	}
+	d.Codec = c
+	return d, nil
+}
+
+// Encode compresses data with the codec decided by the policy, see Decide,
+// and prefixes it with its header.
+func (p *Policy) Encode(contentType string, data []byte) ([]byte, Decision, error) {
+	d, err := p.Decide(contentType, data, len(data))
+	if err != nil {
+		return nil, d, err
+	}
+	encoded, err := Encode(d.Codec, data)
+	return encoded, d, err
+}
+
+// DecideStream is the streaming version of Decide, it peeks at the
+// beginning of r. The returned reader must be read instead of r.
+func (p *Policy) DecideStream(contentType string, r io.Reader) (Decision, io.Reader, error) {
+	n := sniffLen
+	if p.MinSize > n {
+		n = p.MinSize
+	}
+	br := bufio.NewReaderSize(r, n)
+	head, err := br.Peek(n)
+	size := -1
+	switch err {
+	case nil:
+	case io.EOF:
+		size = len(head)
+	default:
+		return Decision{}, nil, err
+	}
+	d, err := p.Decide(contentType, head, size)
+	return d, br, err
+}
+
+// rule returns the codec of the rule matching a media type, if any.
+func (p *Policy) rule(mediaType string) string {
+	if name, ok := p.Codecs[mediaType]; ok {
+		return name
+	}
+	if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+		return p.Codecs[mediaType[:i]+"/*"]
+	}
+	return ""
+}
+
+// mediaType returns the lowercased media type of a content type, without
+// its parameters.
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	}
+	return t
+}
@@ -0,0 +1,77 @@
+package compress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	p := &Policy{
+		MinSize: 64,
+		Codec:   "snappy",
+		Codecs:  map[string]string{"text/html": "gzip", "image/*": "raw", "application/json": "brotli"},
+	}
+	text := []byte(strings.Repeat("some text ", 20))
+	html := []byte("<!DOCTYPE html><html><body>" + strings.Repeat("<p>text</p>", 20) + "</body></html>")
+	jpeg := append([]byte("\xff\xd8\xff\xe0"), text...)
+
+	for _, tt := range []struct {
+		contentType string
+		data        []byte
+		codec       string
+		reason      string
+		mediaType   string
+	}{
+		{"", text, "snappy", ReasonCompressible, "text/plain"},
+		{"", html, "gzip", ReasonRule, "text/html"},
+		{"", jpeg, "raw", ReasonRule, "image/jpeg"},
+		{"", []byte("tiny"), "raw", ReasonTooSmall, "text/plain"},
+		{"application/json; charset=utf-8", text, "brotli", ReasonRule, "application/json"},
+		{"Text/CSS", text, "snappy", ReasonCompressible, "text/css"},
+		{"application/zip", text, "raw", ReasonIncompressible, "application/zip"},
+	} {
+		d, err := p.Decide(tt.contentType, tt.data, len(tt.data))
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.contentType, err)
+		}
+		if d.Codec.Name() != tt.codec || d.Reason != tt.reason || d.ContentType != tt.mediaType {
+			t.Errorf("%q: expected %s (%s, %s), got %s (%s, %s)", tt.contentType,
+				tt.codec, tt.reason, tt.mediaType, d.Codec.Name(), d.Reason, d.ContentType)
+		}
+	}
+
+	p.Codecs["text/plain"] = "unknown"
+	if _, err := p.Decide("text/plain", text, len(text)); err == nil {
+		t.Error("expected an error for an unknown codec")
+	}
+}
+
+func TestPolicyEncode(t *testing.T) {
+	p := DefaultPolicy()
+	for _, data := range [][]byte{testVal, bytes.Repeat(testVal, 100)} {
+		encoded, d, err := p.Encode("", data)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if h, _, err := ReadHeader(encoded); err != nil || h.Codec != d.Codec.ID() {
+			t.Errorf("unexpected header %+v (%v), decided %s", h, err, d.Codec.Name())
+		}
+		if decoded, err := Decode(encoded, nil); err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("unexpected value %q (%v)", decoded, err)
+		}
+	}
+
+	d, r, err := p.DecideStream("", bytes.NewReader(testVal))
+	if err != nil || d.Reason != ReasonTooSmall {
+		t.Errorf("unexpected decision %+v (%v)", d, err)
+	}
+	var b bytes.Buffer
+	if b.ReadFrom(r); !bytes.Equal(b.Bytes(), testVal) {
+		t.Errorf("expected the whole stream, got %q", b.Bytes())
+	}
+	long := bytes.Repeat(testVal, 100)
+	if d, _, _ := p.DecideStream("", bytes.NewReader(long)); d.Codec.Name() != "gzip" {
+		t.Errorf("expected gzip, got %+v", d)
+	}
+}
@@ -0,0 +1,252 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Streamer is implemented by the codecs able to compress and decompress
+// streams, without holding the whole data in memory.
+type Streamer interface {
+	Codec
+	// NewWriter returns a writer compressing to w, the data is flushed to
+	// w when it is closed. Closing it doesn't close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r. Closing it doesn't close
+	// r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// streamAliases maps the codecs which can't stream to the codec streaming
+// the same format.
+var streamAliases = map[string]string{
+	// the snappy block format holds the whole data
+	"snappy": "snappy-framed",
+}
+
+// LookupStreamer returns the Streamer of a format, the name of a codec.
+func LookupStreamer(format string) (Streamer, error) {
+	if alias, ok := streamAliases[format]; ok {
+		format = alias
+	}
+	c, err := Lookup(format)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := c.(Streamer)
+	if !ok {
+		return nil, fmt.Errorf("compress: codec %q can't stream", format)
+	}
+	return s, nil
+}
+
+// NewWriter returns a writer compressing to w in format, the name of a
+// codec. The encoders are pooled, the writer must be closed to flush the
+// data and release its encoder.
+func NewWriter(format string, w io.Writer) (io.WriteCloser, error) {
+	s, err := LookupStreamer(format)
+	if err != nil {
+		return nil, err
+	}
+	return s.NewWriter(w)
+}
+
+// NewReader returns a reader decompressing r from format, the name of a
+// codec. The decoders are pooled, the reader must be closed to release its
+// decoder.
+func NewReader(format string, r io.Reader) (io.ReadCloser, error) {
+	s, err := LookupStreamer(format)
+	if err != nil {
+		return nil, err
+	}
+	return s.NewReader(r)
+}
+
+// EncodeStream is the streaming version of Encode: it writes the header of
+// c to w and returns a writer compressing to w. The codecs which can't
+// stream are replaced by the one streaming their format.
+func EncodeStream(c Codec, w io.Writer) (io.WriteCloser, error) {
+	s, ok := c.(Streamer)
+	if !ok {
+		var err error
+		if s, err = LookupStreamer(c.Name()); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := w.Write(AppendHeader(nil, Header{Codec: s.ID()})); err != nil {
+		return nil, err
+	}
+	return s.NewWriter(w)
+}
+
+// DecodeStream is the streaming version of Decode: it reads the header of
+// r and returns a reader decompressing the value following it. The values
+// of the codecs which can't stream are decoded in memory.
+func DecodeStream(r io.Reader, legacy Codec) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	prefix, err := br.Peek(len(headerMagic) + 1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	c := legacy
+	if HasHeader(prefix) {
+		br.Discard(len(headerMagic))
+		id, _ := br.ReadByte()
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, ErrInvalidHeader
+		}
+		if _, err := br.Discard(int(n)); err != nil {
+			return nil, ErrInvalidHeader
+		}
+		if c, err = LookupID(CodecID(id)); err != nil {
+			return nil, err
+		}
+	}
+	if c == nil {
+		return ioutil.NopCloser(br), nil
+	}
+	if s, ok := c.(Streamer); ok {
+		return s.NewReader(br)
+	}
+	data, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = c.Decode(data); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// encoderPool recycles the encoders of a codec: new creates one writing to
+// w, and reset redirects a released one to w.
+type encoderPool struct {
+	pool  sync.Pool
+	new   func(w io.Writer) (io.WriteCloser, error)
+	reset func(enc io.WriteCloser, w io.Writer) error
+}
+
+func (p *encoderPool) get(w io.Writer) (io.WriteCloser, error) {
+	if enc, ok := p.pool.Get().(io.WriteCloser); ok {
+		if err := p.reset(enc, w); err == nil {
+			return &pooledWriter{enc: enc, pool: p}, nil
+		}
+	}
+	enc, err := p.new(w)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledWriter{enc: enc, pool: p}, nil
+}
+
+// pooledWriter releases its encoder when it is closed.
+type pooledWriter struct {
+	enc  io.WriteCloser
+	pool *encoderPool
+}
+
+func (w *pooledWriter) Write(p []byte) (int, error) {
+	if w.enc == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return w.enc.Write(p)
+}
+
+func (w *pooledWriter) Close() error {
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	if err == nil {
+		w.pool.pool.Put(w.enc)
+	}
+	w.enc = nil
+	return err
+}
+
+// decoderPool recycles the decoders of a codec: new creates one reading
+// from r, and reset redirects a released one to r.
+type decoderPool struct {
+	pool  sync.Pool
+	new   func(r io.Reader) (io.Reader, error)
+	reset func(dec io.Reader, r io.Reader) error
+}
+
+func (p *decoderPool) get(r io.Reader) (io.ReadCloser, error) {
+	if dec, ok := p.pool.Get().(io.Reader); ok {
+		if err := p.reset(dec, r); err != nil {
+			return nil, err
+		}
+		return &pooledReader{dec: dec, pool: p}, nil
+	}
+	dec, err := p.new(r)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledReader{dec: dec, pool: p}, nil
+}
+
+// pooledReader releases its decoder when it is closed.
+type pooledReader struct {
+	dec  io.Reader
+	pool *decoderPool
+}
+
+func (r *pooledReader) Read(p []byte) (int, error) {
+	if r.dec == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return r.dec.Read(p)
+}
+
+func (r *pooledReader) Close() error {
+	if r.dec == nil {
+		return nil
+	}
+	var err error
+	if c, ok := r.dec.(io.Closer); ok {
+		err = c.Close()
+	}
+	r.pool.pool.Put(r.dec)
+	r.dec = nil
+	return err
+}
+
+// encodeStream compresses data with the streaming encoder of s.
+func encodeStream(s Streamer, data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := s.NewWriter(&b)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// decodeStream decompresses data with the streaming decoder of s.
+func decodeStream(s Streamer, data []byte) ([]byte, error) {
+	r, err := s.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,82 @@
+package compress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	for _, format := range Codecs() {
+		// twice to reuse the pooled encoders
+		for i := 0; i < 2; i++ {
+			var buf bytes.Buffer
+			w, err := NewWriter(format, &buf)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", format, err)
+			}
+			for j := 0; j < 3; j++ {
+				if _, err := w.Write(testVal); err != nil {
+					t.Fatalf("%s: unexpected error: %s", format, err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: unexpected error: %s", format, err)
+			}
+
+			r, err := NewReader(format, &buf)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", format, err)
+			}
+			data, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", format, err)
+			}
+			if !bytes.Equal(data, bytes.Repeat(testVal, 3)) {
+				t.Errorf("%s: unexpected data %q", format, data)
+			}
+		}
+	}
+}
+
+func TestEncodeStream(t *testing.T) {
+	for _, format := range Codecs() {
+		c, _ := Lookup(format)
+		var buf bytes.Buffer
+		w, err := EncodeStream(c, &buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", format, err)
+		}
+		w.Write(testVal)
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", format, err)
+		}
+
+		// the streamed values are read by Decode and the other way round
+		decoded, err := Decode(buf.Bytes(), nil)
+		if err != nil || !bytes.Equal(decoded, testVal) {
+			t.Errorf("%s: unexpected value %q (%v)", format, decoded, err)
+		}
+		encoded, _ := Encode(c, testVal)
+		r, err := DecodeStream(bytes.NewReader(encoded), nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", format, err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil || !bytes.Equal(data, testVal) {
+			t.Errorf("%s: unexpected data %q (%v)", format, data, err)
+		}
+	}
+
+	// written before the value headers
+	snappy, _ := Lookup("snappy")
+	legacy, _ := snappy.Encode(testVal)
+	r, err := DecodeStream(bytes.NewReader(legacy), snappy)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if data, _ := ioutil.ReadAll(r); !bytes.Equal(data, testVal) {
+		t.Errorf("unexpected legacy data %q", data)
+	}
+}
@@ -1,7 +1,6 @@
 package compress
 
 import (
-	"bytes"
 	"encoding/binary"
 )
 
@@ -0,0 +1,139 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.ValueEncoder = Values{}
+
+// Values encodes and decodes the values of a store behind their header:
+// with the codec decided by Policy, or else Codec, and the dictionary of the
+// host of their key for zstd, see storage.KeyNamespace.
+type Values struct {
+	// Codec compresses the values written.
+	Codec Codec
+	// Legacy decodes the values written before the value headers.
+	Legacy Codec
+	// Policy overrides Codec when set.
+	Policy *Policy
+	// Dicts holds the zstd dictionaries of the hosts, nil if the store
+	// keeps none.
+	Dicts *Dicts
+}
+
+// NewValues returns the Values of a store compressing its values with the
+// codec named codec, or legacy if empty. The values are stored raw if
+// compressed is unset and codec empty, legacy decodes the values written
+// before the value headers otherwise.
+func NewValues(compressed bool, codec, legacy string) (Values, error) {
+	var v Values
+	var err error
+	if !compressed && codec == "" {
+		v.Codec, err = LookupID(CodecRaw)
+		return v, err
+	}
+	if v.Legacy, err = Lookup(legacy); err != nil {
+		return v, err
+	}
+	if codec == "" {
+		v.Codec = v.Legacy
+		return v, nil
+	}
+	v.Codec, err = Lookup(codec)
+	return v, err
+}
+
+// Encode returns value prefixed with the header of its codec. contentType
+// is the hint of the policy, the type is sniffed if empty.
+func (v Values) Encode(key string, value []byte, contentType string) ([]byte, error) {
+	codec := v.Codec
+	if v.Policy != nil {
+		d, err := v.Policy.Decide(contentType, value, len(value))
+		if err != nil {
+			return nil, err
+		}
+		codec = d.Codec
+	}
+	switch {
+	case codec == nil:
+		return value, nil
+	case v.Dicts != nil && codec.ID() == CodecZstd:
+		return v.Dicts.Encode(storage.KeyNamespace(key), codec, value)
+	}
+	return Encode(codec, value)
+}
+
+// EncodeStream is the streaming version of Encode, it compresses r to w.
+func (v Values) EncodeStream(key string, w io.Writer, r io.Reader) error {
+	codec := v.Codec
+	if v.Policy != nil {
+		d, br, err := v.Policy.DecideStream("", r)
+		if err != nil {
+			return err
+		}
+		codec, r = d.Codec, br
+	}
+	var enc io.WriteCloser
+	var err error
+	switch {
+	case codec == nil:
+		_, err = io.Copy(w, r)
+		return err
+	case v.Dicts != nil && codec.ID() == CodecZstd:
+		enc, err = v.Dicts.EncodeStream(storage.KeyNamespace(key), codec, w)
+	default:
+		enc, err = EncodeStream(codec, w)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(enc, r); err != nil {
+		enc.Close()
+		return err
+	}
+	return enc.Close()
+}
+
+// Decode returns the value of a payload, whatever the codec it was written
+// with.
+func (v Values) Decode(payload []byte) ([]byte, error) {
+	if v.Dicts != nil {
+		return v.Dicts.Decode(payload, v.Legacy)
+	}
+	return Decode(payload, v.Legacy)
+}
+
+// DecodeStream is the streaming version of Decode.
+func (v Values) DecodeStream(payload []byte) (io.ReadCloser, error) {
+	if v.Dicts != nil {
+		return v.Dicts.DecodeStream(bytes.NewReader(payload), v.Legacy)
+	}
+	return DecodeStream(bytes.NewReader(payload), v.Legacy)
+}
+
+// Train trains a new version of the dictionary of namespace from up to
+// samples of its values, DefaultDictSamples if zero. sample returns the
+// payloads of up to n values of namespace picked at random, copied out of
+// the store; Dicts must be set.
+func (v Values) Train(namespace string, samples int, sample func(n int) ([][]byte, error)) (Dict, error) {
+	if samples <= 0 {
+		samples = DefaultDictSamples
+	}
+	payloads, err := sample(samples)
+	if err != nil {
+		return Dict{}, err
+	}
+	values := make([][]byte, 0, len(payloads))
+	for _, payload := range payloads {
+		value, err := v.Decode(payload)
+		if err != nil {
+			return Dict{}, err
+		}
+		values = append(values, value)
+	}
+	return v.Dicts.Train(namespace, values)
+}
@@ -0,0 +1,145 @@
+package compress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+func TestNewValues(t *testing.T) {
+	for _, tt := range []struct {
+		compressed    bool
+		codec         string
+		name, legacy  string
+		expectedError bool
+	}{
+		{false, "", "raw", "", false},
+		{true, "", "snappy", "snappy", false},
+		{false, "zstd:3", "zstd", "snappy", false},
+		{true, "unknown", "", "", true},
+	} {
+		v, err := NewValues(tt.compressed, tt.codec, "snappy")
+		if tt.expectedError {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.codec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.codec, err)
+		}
+		legacy := ""
+		if v.Legacy != nil {
+			legacy = v.Legacy.Name()
+		}
+		if v.Codec.Name() != tt.name || legacy != tt.legacy {
+			t.Errorf("%q: expected %s and %q, got %s and %q", tt.codec, tt.name, tt.legacy, v.Codec.Name(), legacy)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	dicts, _ := NewDicts(&memDictStore{})
+	v, _ := NewValues(true, "zstd", "snappy")
+	v.Dicts = dicts
+	key := "http://example.com/item/1000"
+	page := testPage(1000)
+
+	dict, err := v.Train("example.com", 0, func(n int) ([][]byte, error) {
+		if n != DefaultDictSamples {
+			t.Errorf("expected %d samples, got %d", DefaultDictSamples, n)
+		}
+		var payloads [][]byte
+		for i := 0; i < 50; i++ {
+			payload, _ := v.Encode(key, testPage(i), "")
+			payloads = append(payloads, payload)
+		}
+		return payloads, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	encoded, err := v.Encode(key, page, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h, _, _ := ReadHeader(encoded); !bytes.Equal(h.Meta, dictMeta(dict.ID)) {
+		t.Errorf("expected dictionary %d, got %+v", dict.ID, h)
+	}
+	var b bytes.Buffer
+	if err := v.EncodeStream(key, &b, bytes.NewReader(page)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, encoded := range [][]byte{encoded, b.Bytes()} {
+		if decoded, err := v.Decode(encoded); err != nil || !bytes.Equal(decoded, page) {
+			t.Errorf("unexpected value %q (%v)", decoded, err)
+		}
+		r, err := v.DecodeStream(encoded)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		decoded, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil || !bytes.Equal(decoded, page) {
+			t.Errorf("unexpected streamed value %q (%v)", decoded, err)
+		}
+	}
+
+	// the values written before the value headers
+	legacy, _ := v.Legacy.Encode(page)
+	if decoded, err := v.Decode(legacy); err != nil || !bytes.Equal(decoded, page) {
+		t.Errorf("unexpected legacy value %q (%v)", decoded, err)
+	}
+
+	// the policy decides the codec
+	v.Policy = &Policy{Codec: "gzip"}
+	encoded, _ = v.Encode(key, page, "text/html")
+	if h, _, _ := ReadHeader(encoded); h.Codec != CodecGZip {
+		t.Errorf("expected gzip, got %+v", h)
+	}
+}
+
+// recordedValues records the values encoded by a store.
+type recordedValues struct {
+	Values
+	encoded map[string][]byte
+}
+
+func (v *recordedValues) Encode(key string, value []byte, contentType string) ([]byte, error) {
+	encoded, err := v.Values.Encode(key, value, contentType)
+	v.encoded[key] = encoded
+	return encoded, err
+}
+
+func TestValuesInMemory(t *testing.T) {
+	v, _ := NewValues(true, "", "snappy")
+	v.Policy = &Policy{Codec: "gzip", Codecs: map[string]string{"image/*": "raw"}}
+	recorded := &recordedValues{Values: v, encoded: make(map[string][]byte)}
+	s, _ := storage.NewInMemoryStorage(&storage.Config{Encoder: recorded})
+	page := testPage(1000)
+
+	s.SetTyped("page", page, "text/html")
+	s.SetTyped("image", page, "image/png")
+	for key, codec := range map[string]CodecID{"page": CodecGZip, "image": CodecRaw} {
+		if h, _, err := ReadHeader(recorded.encoded[key]); err != nil || h.Codec != codec {
+			t.Errorf("%s: expected codec %d, got %+v (%v)", key, codec, h, err)
+		}
+		kvs, _, _ := s.ScanValues(key, "", 1)
+		if len(kvs) != 1 || !bytes.Equal(kvs[0].Value, page) {
+			t.Fatalf("%s: unexpected values %q", key, kvs)
+		}
+		value, ok := s.Get(key)
+		if !ok || !bytes.Equal(value, page) {
+			t.Fatalf("%s: unexpected value %q", key, value)
+		}
+		// the raw values are not shared with the store
+		value[0] = 'x'
+		if value, _ := s.Get(key); value[0] != page[0] {
+			t.Errorf("%s: expected a copy of the value", key)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package storage
+
+// TypedSetter is implemented by the stores choosing how to compress each
+// value from its content type, see compress.Policy.
+type TypedSetter interface {
+	// SetTyped stores value at key, contentType is a hint of its media type,
+	// e.g. the Content-Type header of the response. The type is sniffed from
+	// the value when it is empty.
+	SetTyped(key string, value []byte, contentType string) error
+}
+
+// SetTyped stores value at key with its content type if s is a TypedSetter,
+// with Set otherwise.
+func SetTyped(s Storage, key string, value []byte, contentType string) error {
+	if setter, ok := s.(TypedSetter); ok {
+		return setter.SetTyped(key, value, contentType)
+	}
+	return s.Set(key, value)
+}
+
+// EncodedSetter is implemented by the stores keeping the bodies received
+// compressed, e.g. with a Content-Encoding: br header, as they are instead of
+// decoding and compressing them again. Get still returns them decoded.
+type EncodedSetter interface {
+	// SetEncoded stores value, encoded with contentEncoding, at key.
+	SetEncoded(key string, value []byte, contentEncoding string) error
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"net/http"
+	"strings"
+)
+
+// StringifyCookies serializes a list of http.Cookies to a string, one
+// Set-Cookie line per cookie.
+func StringifyCookies(cookies []*http.Cookie) string {
+	cs := make([]string, len(cookies))
+	for i, c := range cookies {
+		cs[i] = c.String()
+	}
+	return strings.Join(cs, "\n")
+}
+
+// UnstringifyCookies deserializes a string made by StringifyCookies to
+// http.Cookies.
+func UnstringifyCookies(s string) []*http.Cookie {
+	h := http.Header{}
+	for _, c := range strings.Split(s, "\n") {
+		if c == "" {
+			continue
+		}
+		h.Add("Set-Cookie", c)
+	}
+	r := http.Response{Header: h}
+	return r.Cookies()
+}
+
+// MergeCookies adds the cookies serialized in update to the ones serialized
+// in stored. A cookie of update replaces the stored cookie with the same name,
+// path and domain.
+func MergeCookies(stored, update string) string {
+	if stored == "" {
+		return update
+	}
+	merged := UnstringifyCookies(stored)
+	for _, c := range UnstringifyCookies(update) {
+		replaced := false
+		for i, o := range merged {
+			if o.Name == c.Name && o.Path == c.Path && o.Domain == c.Domain {
+				merged[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, c)
+		}
+	}
+	return StringifyCookies(merged)
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestMergeCookies(t *testing.T) {
+	stored := "a=1; Path=/\nb=2; Path=/"
+	merged := MergeCookies(stored, "b=3; Path=/\nc=4; Path=/")
+	cookies := UnstringifyCookies(merged)
+	if len(cookies) != 3 {
+		t.Fatalf("expected 3 cookies, got %d: %q", len(cookies), merged)
+	}
+	values := map[string]string{}
+	for _, c := range cookies {
+		values[c.Name] = c.Value
+	}
+	if values["a"] != "1" || values["b"] != "3" || values["c"] != "4" {
+		t.Errorf("unexpected merged cookies: %v", values)
+	}
+	if MergeCookies("", "a=1") != "a=1" {
+		t.Error("expected the update to be returned as is")
+	}
+}
@@ -0,0 +1,253 @@
+// Package dedup stores each body once, under its content hash, in any
+// store: the keys hold a pointer to the body and the bodies count their
+// references.
+package dedup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// The bodies, their reference counts and the savings are stored under
+// internalPrefix, which sorts before the printable keys. It doesn't start
+// with a NUL byte, the stores hide such keys from their scans. They are
+// written without expiry, a body outlives the keys pointing at it until it
+// is released or collected. The keys hold pointerMagic followed by the SHA-256 of their body: SHA-1 collisions can
+// be crafted, a crawled page could take the place of another one.
+const (
+	internalPrefix = "\x01dedup/"
+	blobPrefix     = internalPrefix + "blobs/"
+	refsPrefix     = internalPrefix + "refs/"
+	statsKey       = internalPrefix + "stats"
+)
+
+var pointerMagic = []byte("\x00DDP")
+
+type hash [sha256.Size]byte
+
+func (h hash) String() string { return hex.EncodeToString(h[:]) }
+
+func blobKey(h hash) string { return blobPrefix + h.String() }
+func refsKey(h hash) string { return refsPrefix + h.String() }
+
+// pointer returns the value of a key holding the body hashed h.
+func pointer(h hash) []byte {
+	return append(append(make([]byte, 0, len(pointerMagic)+len(h)), pointerMagic...), h[:]...)
+}
+
+// parsePointer returns the hash of a pointer, false if value is a body
+// written without dedup.
+func parsePointer(value []byte) (h hash, ok bool) {
+	if len(value) != len(pointerMagic)+len(h) || !bytes.HasPrefix(value, pointerMagic) {
+		return h, false
+	}
+	copy(h[:], value[len(pointerMagic):])
+	return h, true
+}
+
+// refs is the reference count of a body, and its size.
+type refs struct {
+	count int64
+	size  int64
+}
+
+func (r refs) marshal() []byte {
+	buf := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(r.count))
+	n += binary.PutUvarint(buf[n:], uint64(r.size))
+	return buf[:n]
+}
+
+func (r *refs) unmarshal(data []byte) error {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return ErrInvalidRecord
+	}
+	size, m := binary.Uvarint(data[n:])
+	if m <= 0 {
+		return ErrInvalidRecord
+	}
+	r.count, r.size = int64(count), int64(size)
+	return nil
+}
+
+// Store deduplicates the bodies written to a store. Get returns the bodies
+// written before, without pointer, as they are. The store must not be
+// written to by other Stores at the same time, the reference counts are
+// updated under a lock of the Store.
+type Store struct {
+	storage.Storage
+	mu      sync.Mutex
+	savings Savings
+}
+
+// New returns a Store deduplicating the bodies written to s. The writes are
+// atomic if s is a storage.Batcher, GC repairs the reference counts of the
+// others after a crash.
+func New(s storage.Storage) (*Store, error) {
+	d := &Store{Storage: s}
+	if data, ok := s.Get(statsKey); ok {
+		if err := d.savings.unmarshal(data); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Get implements storage.Storage.Get(), it follows the pointer of key.
+func (d *Store) Get(key string) ([]byte, bool) {
+	value, ok := d.Storage.Get(key)
+	if !ok {
+		return nil, false
+	}
+	h, ok := parsePointer(value)
+	if !ok {
+		return value, true
+	}
+	return d.Storage.Get(blobKey(h))
+}
+
+// Set implements storage.Storage.Set()
+func (d *Store) Set(key string, body []byte) error {
+	return d.SetTyped(key, body, "")
+}
+
+// SetTyped implements storage.TypedSetter.SetTyped(), contentType is given
+// to the store with the body when it is written for the first time.
+func (d *Store) SetTyped(key string, body []byte, contentType string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h := hash(sha256.Sum256(body))
+	old, hadPointer, err := d.pointerOf(key)
+	if err != nil {
+		return err
+	}
+	if hadPointer && old == h {
+		return nil
+	}
+	r, err := d.refs(h)
+	if err != nil {
+		return err
+	}
+	savings := d.savings
+	if r.count == 0 {
+		// written before the batch, GC deletes it if the batch fails
+		if err := storage.SetPersistent(d.Storage, blobKey(h), body, contentType); err != nil {
+			return err
+		}
+		savings.Blobs++
+		savings.StoredBytes += int64(len(body))
+	}
+	r.count++
+	r.size = int64(len(body))
+	savings.LogicalBytes += r.size
+
+	b := storage.NewBatch()
+	b.SetPersistent(refsKey(h), r.marshal())
+	b.Set(key, pointer(h))
+	if hadPointer {
+		if err := d.release(b, old, &savings); err != nil {
+			return err
+		}
+	} else {
+		savings.Keys++
+	}
+	return d.write(b, savings)
+}
+
+// Delete implements storage.Storage.Delete(), the body is deleted with its
+// last reference.
+func (d *Store) Delete(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h, ok, err := d.pointerOf(key)
+	if err != nil || !ok {
+		if err == nil {
+			err = d.Storage.Delete(key)
+		}
+		return err
+	}
+	savings := d.savings
+	savings.Keys--
+	b := storage.NewBatch()
+	b.Delete(key)
+	if err := d.release(b, h, &savings); err != nil {
+		return err
+	}
+	return d.write(b, savings)
+}
+
+// Clear implements storage.Storage.Clear(), it resets the savings.
+func (d *Store) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.Storage.Clear(); err != nil {
+		return err
+	}
+	d.savings = Savings{}
+	return nil
+}
+
+// pointerOf returns the hash the value of key points at, false if there is
+// no value or it is not a pointer.
+func (d *Store) pointerOf(key string) (hash, bool, error) {
+	value, ok := d.Storage.Get(key)
+	if !ok {
+		return hash{}, false, nil
+	}
+	h, ok := parsePointer(value)
+	return h, ok, nil
+}
+
+// refs returns the reference count of the body hashed h, zero if it is not
+// stored.
+func (d *Store) refs(h hash) (refs, error) {
+	var r refs
+	data, ok := d.Storage.Get(refsKey(h))
+	if !ok {
+		return r, nil
+	}
+	err := r.unmarshal(data)
+	return r, err
+}
+
+// release adds to b the removal of a reference to the body hashed h, and
+// its deletion if it was the last one.
+func (d *Store) release(b *storage.Batch, h hash, savings *Savings) error {
+	r, err := d.refs(h)
+	if err != nil || r.count == 0 {
+		// already released, GC fixes the savings
+		return err
+	}
+	r.count--
+	savings.LogicalBytes -= r.size
+	if r.count > 0 {
+		b.SetPersistent(refsKey(h), r.marshal())
+		return nil
+	}
+	b.Delete(refsKey(h))
+	b.Delete(blobKey(h))
+	savings.Blobs--
+	savings.StoredBytes -= r.size
+	return nil
+}
+
+// write applies b with the new savings, which replace the ones of the
+// Store once written.
+func (d *Store) write(b *storage.Batch, savings Savings) error {
+	b.SetPersistent(statsKey, savings.marshal())
+	if err := storage.WriteBatch(d.Storage, b); err != nil {
+		return err
+	}
+	d.savings = savings
+	return nil
+}
@@ -0,0 +1,128 @@
+package dedup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+func TestDedup(t *testing.T) {
+	s, _ := storage.NewInMemoryStorage(nil)
+	d, err := New(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	page := []byte(strings.Repeat("<p>page</p>", 100))
+	other := []byte("<p>other</p>")
+	for key, body := range map[string][]byte{
+		"http://example.com/":             page,
+		"http://example.com/?utm_source=": page,
+		"http://mirror.com/":              page,
+		"http://example.com/other":        other,
+	} {
+		if err := d.Set(key, body); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	expected := Savings{Keys: 4, Blobs: 2, LogicalBytes: 3*1100 + 12, StoredBytes: 1100 + 12}
+	if savings := d.Savings(); savings != expected {
+		t.Errorf("expected %+v, got %+v", expected, savings)
+	}
+	if v, ok := d.Get("http://mirror.com/"); !ok || !bytes.Equal(v, page) {
+		t.Errorf("unexpected value %q", v)
+	}
+
+	// the body is deleted with its last reference
+	d.Set("http://example.com/other", page)
+	if _, ok := s.Get(blobKey(sha256.Sum256(other))); ok {
+		t.Error("expected the body without reference to be deleted")
+	}
+	d.Delete("http://mirror.com/")
+	expected = Savings{Keys: 3, Blobs: 1, LogicalBytes: 3 * 1100, StoredBytes: 1100}
+	if savings := d.Savings(); savings != expected {
+		t.Errorf("expected %+v, got %+v", expected, savings)
+	}
+	if expected.Saved() != 2*1100 || expected.Ratio() != 3 {
+		t.Errorf("unexpected saved bytes %d, ratio %f", expected.Saved(), expected.Ratio())
+	}
+	var b bytes.Buffer
+	expected.Print(&b)
+	if !strings.Contains(b.String(), "2.1K saved") {
+		t.Errorf("unexpected report %q", b.String())
+	}
+
+	// the values written without dedup are read as they are
+	s.Set("legacy", other)
+	if v, ok := d.Get("legacy"); !ok || !bytes.Equal(v, other) {
+		t.Errorf("unexpected legacy value %q", v)
+	}
+
+	// the savings are kept with the store
+	d, err = New(s)
+	if err != nil || d.Savings() != expected {
+		t.Errorf("expected %+v, got %+v (%v)", expected, d.Savings(), err)
+	}
+}
+
+func TestDedupTTL(t *testing.T) {
+	s, err := storage.NewInMemoryStorage(&storage.Config{TTL: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	d, _ := New(s)
+	page := []byte(strings.Repeat("<p>page</p>", 100))
+
+	// the body outlives the first key pointing at it
+	d.Set("a", page)
+	time.Sleep(150 * time.Millisecond)
+	d.Set("b", page)
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := d.Get("a"); ok {
+		t.Error("expected a to expire")
+	}
+	if v, ok := d.Get("b"); !ok || !bytes.Equal(v, page) {
+		t.Errorf("unexpected value %q", v)
+	}
+	if _, ok := s.Get(statsKey); !ok {
+		t.Error("expected the savings to be kept")
+	}
+}
+
+func TestGC(t *testing.T) {
+	s, _ := storage.NewInMemoryStorage(nil)
+	d, _ := New(s)
+	page := []byte(strings.Repeat("<p>page</p>", 100))
+	other := []byte("<p>other</p>")
+	d.Set("a", page)
+	d.Set("b", page)
+	d.Set("c", other)
+
+	// a key expired by the store, and a body written by an interrupted Set
+	s.Delete("c")
+	s.Delete("b")
+	orphan := []byte("<p>orphan</p>")
+	s.Set(blobKey(sha256.Sum256(orphan)), orphan)
+
+	report, err := d.GC()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := (GCReport{Blobs: 2, Bytes: 12 + 13, Repaired: 1}); report != expected {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+	if expected := (Savings{Keys: 1, Blobs: 1, LogicalBytes: 1100, StoredBytes: 1100}); d.Savings() != expected {
+		t.Errorf("expected %+v, got %+v", expected, d.Savings())
+	}
+	if v, ok := d.Get("a"); !ok || !bytes.Equal(v, page) {
+		t.Errorf("unexpected value %q", v)
+	}
+	if r, _ := d.refs(sha256.Sum256(page)); r.count != 1 {
+		t.Errorf("expected 1 reference, got %d", r.count)
+	}
+}
@@ -0,0 +1,10 @@
+package dedup
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidRecord = errors.New("invalid dedup record")
+	ErrNotScanner    = errors.New("the store can't list its keys")
+)
@@ -0,0 +1,102 @@
+package dedup
+
+import (
+	"strings"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// GCReport is the outcome of GC.
+type GCReport struct {
+	// Blobs is the number of bodies deleted, no key pointing at them.
+	Blobs int64
+	// Bytes is the size of the deleted bodies.
+	Bytes int64
+	// Repaired is the number of reference counts fixed.
+	Repaired int64
+}
+
+// GC deletes the bodies no key points at and recomputes the reference
+// counts and the savings from the keys of the store. Such bodies are left
+// by the keys expired by the store, and by the writes interrupted by a
+// crash on the stores without atomic batches. The store must be a
+// storage.Scanner, ErrNotScanner is returned otherwise.
+func (d *Store) GC() (GCReport, error) {
+	var report GCReport
+	scanner, ok := d.Storage.(storage.Scanner)
+	if !ok {
+		return report, ErrNotScanner
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// count the pointers of the keys
+	var savings Savings
+	counts := make(map[string]int64)
+	err := storage.Walk(scanner, "", 0, func(key string) error {
+		if strings.HasPrefix(key, internalPrefix) {
+			return nil
+		}
+		value, ok := d.Storage.Get(key)
+		if !ok {
+			return nil
+		}
+		if h, ok := parsePointer(value); ok {
+			counts[h.String()]++
+			savings.Keys++
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	b := storage.NewBatch()
+	err = storage.Walk(scanner, blobPrefix, 0, func(key string) error {
+		id := key[len(blobPrefix):]
+		var r refs
+		data, hasRefs := d.Storage.Get(refsPrefix + id)
+		if hasRefs {
+			if err := r.unmarshal(data); err != nil {
+				return err
+			}
+		} else if body, ok := d.Storage.Get(key); ok {
+			r.size = int64(len(body))
+		}
+
+		n := counts[id]
+		switch {
+		case n == 0:
+			b.Delete(key)
+			b.Delete(refsPrefix + id)
+			report.Blobs++
+			report.Bytes += r.size
+			return nil
+		case n != r.count || !hasRefs:
+			r.count = n
+			b.SetPersistent(refsPrefix+id, r.marshal())
+			report.Repaired++
+		}
+		savings.Blobs++
+		savings.StoredBytes += r.size
+		savings.LogicalBytes += n * r.size
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// the reference counts of the bodies never written
+	err = storage.Walk(scanner, refsPrefix, 0, func(key string) error {
+		if _, ok := d.Storage.Get(blobPrefix + key[len(refsPrefix):]); !ok {
+			b.Delete(key)
+			report.Repaired++
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	return report, d.write(b, savings)
+}
@@ -0,0 +1,72 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	// internal
+	"github.com/sniperkit/colly-storage/pkg/helper/format"
+)
+
+// Savings reports the space saved by the deduplication.
+type Savings struct {
+	// Keys is the number of keys pointing at a body.
+	Keys int64
+	// Blobs is the number of distinct bodies stored.
+	Blobs int64
+	// LogicalBytes is the size of the bodies of all the keys, the space
+	// they would take without deduplication.
+	LogicalBytes int64
+	// StoredBytes is the size of the distinct bodies.
+	StoredBytes int64
+}
+
+// Saved returns the number of bytes not stored thanks to the deduplication.
+func (s Savings) Saved() int64 {
+	return s.LogicalBytes - s.StoredBytes
+}
+
+// Ratio returns LogicalBytes / StoredBytes, 1 when nothing is stored.
+func (s Savings) Ratio() float64 {
+	if s.StoredBytes == 0 {
+		return 1
+	}
+	return float64(s.LogicalBytes) / float64(s.StoredBytes)
+}
+
+// Print writes the savings to w.
+func (s Savings) Print(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%d keys, %d bodies: %s stored for %s, %s saved (x%.2f)\n",
+		s.Keys, s.Blobs, format.FormatBytes(int(s.StoredBytes)), format.FormatBytes(int(s.LogicalBytes)),
+		format.FormatBytes(int(s.Saved())), s.Ratio())
+	return err
+}
+
+// Savings returns the space saved by the deduplication. The counters are
+// kept up to date by the writes, GC recomputes them.
+func (d *Store) Savings() Savings {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.savings
+}
+
+func (s Savings) marshal() []byte {
+	buf := make([]byte, 4*binary.MaxVarintLen64)
+	n := 0
+	for _, v := range []int64{s.Keys, s.Blobs, s.LogicalBytes, s.StoredBytes} {
+		n += binary.PutVarint(buf[n:], v)
+	}
+	return buf[:n]
+}
+
+func (s *Savings) unmarshal(data []byte) error {
+	for _, n := range []*int64{&s.Keys, &s.Blobs, &s.LogicalBytes, &s.StoredBytes} {
+		v, l := binary.Varint(data)
+		if l <= 0 {
+			return ErrInvalidRecord
+		}
+		*n, data = v, data[l:]
+	}
+	return nil
+}
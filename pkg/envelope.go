@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// Check is the metadata of an entry, kept in the envelope wrapping its
+// value.
+type Check struct {
+	// Enabled is false for the values written before the envelopes, whose
+	// metadata is unknown.
+	Enabled bool
+	// Key is the key the entry is stored at.
+	Key string
+	// Requests counts the reads of the entry.
+	Requests int
+	// CreatedAt is the time of the first write of the entry.
+	CreatedAt time.Time
+	// UpdatedAt is the time of the last write or read of the entry.
+	UpdatedAt time.Time
+	// ExpiredAt is the expiry of the entry, zero if it never expires.
+	ExpiredAt time.Time
+	// Priority flags the entries to keep, it is preserved by the writes.
+	Priority bool
+	// Provider is the name of the crawler which wrote the entry last.
+	Provider string
+}
+
+// Hit records a read of the entry at now.
+func (c *Check) Hit(now time.Time) {
+	c.Requests++
+	c.UpdatedAt = now
+}
+
+// Stater is implemented by the stores keeping the metadata of their
+// entries.
+type Stater interface {
+	// Stat returns the metadata of the entry stored at key without decoding
+	// its value, or ErrNotFound.
+	Stat(key string) (*Check, error)
+}
+
+// CheckSetter is implemented by the stores restoring the metadata of their
+// entries, e.g. the ones imported from an archive.
+type CheckSetter interface {
+	// SetWithCheck stores value at key with the metadata of c, expiring at
+	// c.ExpiredAt unless it is zero. The value is not stored if c has
+	// expired already.
+	SetWithCheck(key string, value []byte, c *Check) error
+}
+
+// Peeker is implemented by the stores recording the reads of their entries.
+type Peeker interface {
+	// Peek returns the value stored at key as Get, without recording the
+	// read.
+	Peek(key string) ([]byte, bool)
+}
+
+// Peek returns the value stored at key, without recording the read if s is
+// a Peeker.
+func Peek(s Storage, key string) ([]byte, bool) {
+	if p, ok := s.(Peeker); ok {
+		return p.Peek(key)
+	}
+	return s.Get(key)
+}
+
+// EnvelopeVersion is the version of the envelopes written by SealEnvelope.
+const EnvelopeVersion byte = 1
+
+// envelopeMagic starts every envelope. Values starting with a NUL byte are
+// very unlikely, which lets the values written before the envelopes be read
+// as they are.
+var envelopeMagic = []byte("\x00ENV")
+
+const (
+	checkEnabled byte = 1 << iota
+	checkPriority
+)
+
+// IsEnvelope reports whether data starts with an envelope.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, envelopeMagic)
+}
+
+// EncodeEnvelope wraps payload in an envelope carrying c. The envelope is
+// laid out as the magic, the version, the length of the header as an
+// uvarint, the header and the payload.
+func EncodeEnvelope(c *Check, payload []byte) []byte {
+	header := encodeCheck(c)
+	buf := make([]byte, 0, len(envelopeMagic)+1+binary.MaxVarintLen64+len(header)+len(payload))
+	buf = append(buf, envelopeMagic...)
+	buf = append(buf, EnvelopeVersion)
+	buf = appendUvarint(buf, uint64(len(header)))
+	buf = append(buf, header...)
+	return append(buf, payload...)
+}
+
+// DecodeEnvelope returns the metadata and the payload of an envelope, the
+// payload shares the memory of data.
+func DecodeEnvelope(data []byte) (*Check, []byte, error) {
+	header, payload, err := splitEnvelope(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := decodeCheck(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, payload, nil
+}
+
+// DecodeCheck returns the metadata of an envelope, the payload is not read.
+func DecodeCheck(data []byte) (*Check, error) {
+	header, _, err := splitEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCheck(header)
+}
+
+// OpenEnvelope is like DecodeEnvelope but accepts the values written before
+// the envelopes, returned as their payload with a nil Check.
+func OpenEnvelope(data []byte) (*Check, []byte, error) {
+	if !IsEnvelope(data) {
+		return nil, data, nil
+	}
+	return DecodeEnvelope(data)
+}
+
+// StatEnvelope is like DecodeCheck but returns a disabled Check for the
+// values written before the envelopes.
+func StatEnvelope(key string, data []byte) (*Check, error) {
+	if !IsEnvelope(data) {
+		return &Check{Key: key}, nil
+	}
+	return DecodeCheck(data)
+}
+
+// SealEnvelope returns the envelope of a payload written at key at now by
+// provider. The creation time, the read counter and the priority are taken
+// over from prev, the envelope previously stored at key, if any.
+func SealEnvelope(prev []byte, key string, payload []byte, now, expiresAt time.Time, provider string) []byte {
+	c := &Check{CreatedAt: now}
+	if IsEnvelope(prev) {
+		if p, err := DecodeCheck(prev); err == nil {
+			c = p
+		}
+	}
+	c.Enabled = true
+	c.Key = key
+	c.UpdatedAt = now
+	c.ExpiredAt = expiresAt
+	c.Provider = provider
+	return EncodeEnvelope(c, payload)
+}
+
+// RestoreEnvelope returns the envelope of a payload written at key with the
+// metadata of c, see CheckSetter.
+func RestoreEnvelope(key string, payload []byte, c *Check) []byte {
+	restored := *c
+	restored.Enabled = true
+	restored.Key = key
+	return EncodeEnvelope(&restored, payload)
+}
+
+// HitEnvelope returns data with a read recorded at now, the values written
+// before the envelopes are returned as they are.
+func HitEnvelope(data []byte, now time.Time) ([]byte, error) {
+	if !IsEnvelope(data) {
+		return data, nil
+	}
+	c, payload, err := DecodeEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	c.Hit(now)
+	return EncodeEnvelope(c, payload), nil
+}
+
+func splitEnvelope(data []byte) (header, payload []byte, err error) {
+	if !IsEnvelope(data) || len(data) < len(envelopeMagic)+1 {
+		return nil, nil, ErrInvalidEnvelope
+	}
+	data = data[len(envelopeMagic):]
+	if data[0] != EnvelopeVersion {
+		return nil, nil, ErrInvalidEnvelope
+	}
+	data = data[1:]
+	n, l := binary.Uvarint(data)
+	if l <= 0 || n > uint64(len(data)-l) {
+		return nil, nil, ErrInvalidEnvelope
+	}
+	data = data[l:]
+	return data[:n], data[n:], nil
+}
+
+func encodeCheck(c *Check) []byte {
+	var flags byte
+	if c.Enabled {
+		flags |= checkEnabled
+	}
+	if c.Priority {
+		flags |= checkPriority
+	}
+	buf := []byte{flags}
+	buf = appendUvarint(buf, uint64(c.Requests))
+	for _, t := range []time.Time{c.CreatedAt, c.UpdatedAt, c.ExpiredAt} {
+		buf = appendVarint(buf, unixNano(t))
+	}
+	for _, s := range []string{c.Key, c.Provider} {
+		buf = appendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+	return buf
+}
+
+func decodeCheck(header []byte) (*Check, error) {
+	r := bytes.NewReader(header)
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	c := &Check{
+		Enabled:  flags&checkEnabled != 0,
+		Priority: flags&checkPriority != 0,
+	}
+	requests, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	c.Requests = int(requests)
+	for _, t := range []*time.Time{&c.CreatedAt, &c.UpdatedAt, &c.ExpiredAt} {
+		ns, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, ErrInvalidEnvelope
+		}
+		if ns != 0 {
+			*t = time.Unix(0, ns)
+		}
+	}
+	for _, s := range []*string{&c.Key, &c.Provider} {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, ErrInvalidEnvelope
+		}
+		b := make([]byte, n)
+		r.Read(b)
+		*s = string(b)
+	}
+	return c, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEnvelope(t *testing.T) {
+	now := time.Unix(1500000000, 42)
+	c := &Check{
+		Enabled:   true,
+		Key:       "example.com/page",
+		Requests:  3,
+		CreatedAt: now,
+		UpdatedAt: now.Add(time.Hour),
+		Priority:  true,
+		Provider:  "crawler-1",
+	}
+	data := EncodeEnvelope(c, []byte("payload"))
+	if !IsEnvelope(data) {
+		t.Fatal("expected an envelope")
+	}
+
+	got, payload, err := DecodeEnvelope(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(payload) != "payload" {
+		t.Errorf("unexpected payload: %q", payload)
+	}
+	if got.Key != c.Key || got.Requests != 3 || !got.Priority || !got.Enabled || got.Provider != c.Provider {
+		t.Errorf("unexpected check: %+v", got)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || !got.UpdatedAt.Equal(c.UpdatedAt) || !got.ExpiredAt.IsZero() {
+		t.Errorf("unexpected times: %+v", got)
+	}
+
+	// the header is enough to read the metadata
+	header := data[:len(data)-len("payload")]
+	if got, err := DecodeCheck(header); err != nil || got.Key != c.Key {
+		t.Errorf("expected the check from the header only, got %+v (%v)", got, err)
+	}
+	if _, err := DecodeCheck(data[:6]); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("expected ErrInvalidEnvelope, got %v", err)
+	}
+}
+
+func TestEnvelopeLegacy(t *testing.T) {
+	legacy := []byte("<html></html>")
+	c, payload, err := OpenEnvelope(legacy)
+	if err != nil || c != nil || !bytes.Equal(payload, legacy) {
+		t.Errorf("expected the legacy value as it is, got %v %q (%v)", c, payload, err)
+	}
+	if c, _ := StatEnvelope("k", legacy); c.Enabled || c.Key != "k" {
+		t.Errorf("expected a disabled check, got %+v", c)
+	}
+	if hit, _ := HitEnvelope(legacy, time.Now()); !bytes.Equal(hit, legacy) {
+		t.Error("expected the legacy value to be left as it is")
+	}
+}
+
+func TestSealEnvelope(t *testing.T) {
+	created := time.Unix(1500000000, 0)
+	first := SealEnvelope(nil, "k", []byte("v1"), created, time.Time{}, "a")
+	first, _ = HitEnvelope(first, created.Add(time.Minute))
+
+	updated := created.Add(time.Hour)
+	expires := updated.Add(time.Hour)
+	second := SealEnvelope(first, "k", []byte("v2"), updated, expires, "b")
+	c, payload, err := DecodeEnvelope(second)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(payload) != "v2" {
+		t.Errorf("unexpected payload: %q", payload)
+	}
+	if !c.CreatedAt.Equal(created) || !c.UpdatedAt.Equal(updated) || !c.ExpiredAt.Equal(expires) {
+		t.Errorf("unexpected times: %+v", c)
+	}
+	if c.Requests != 1 || c.Provider != "b" {
+		t.Errorf("unexpected check: %+v", c)
+	}
+}
+
+func TestInMemoryStat(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{Provider: "crawler"})
+	if _, err := s.Stat("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	s.Set("k", []byte("v"))
+	s.Get("k")
+	s.Get("k")
+	c, err := s.Stat("k")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Requests != 2 || c.Provider != "crawler" || c.Key != "k" || c.CreatedAt.IsZero() {
+		t.Errorf("unexpected check: %+v", c)
+	}
+}
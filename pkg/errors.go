@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"errors"
+)
+
+// Sentinel errors returned by StorageV2 implementations (and by the v1
+// stores where it makes sense). Match them with errors.Is, backends are
+// free to wrap them with more context.
+var (
+	// ErrNotFound is returned when no entry is stored against a key.
+	ErrNotFound = errors.New("storage: key not found")
+
+	// ErrReadOnly is returned when a write is attempted on a read-only store.
+	ErrReadOnly = errors.New("storage: store is read-only")
+
+	// ErrClosed is returned when a store is used after it has been closed.
+	ErrClosed = errors.New("storage: store is closed")
+
+	// ErrTooLarge is returned when a value exceeds the size accepted by a store.
+	ErrTooLarge = errors.New("storage: value is too large")
+
+	// ErrUnknownAction is returned when an action is not supported by a store.
+	ErrUnknownAction = errors.New("storage: unknown action")
+
+	// ErrInvalidArgs is returned when the arguments of an action don't match
+	// its spec.
+	ErrInvalidArgs = errors.New("storage: invalid action arguments")
+
+	// ErrBatchAborted is reported for the keys of a batch rolled back because
+	// another key failed.
+	ErrBatchAborted = errors.New("storage: batch aborted")
+
+	// ErrInvalidEnvelope is returned when the envelope of a value can't be
+	// decoded.
+	ErrInvalidEnvelope = errors.New("storage: invalid envelope")
+)
@@ -4,6 +4,10 @@ import (
 	"time"
 )
 
+// DateFormat is the layout of the dates formatted and parsed by FormatDate
+// and ParseDate.
+const DateFormat = "2006-01-02"
+
 func FormatDate(date time.Time) string {
 	return date.Format(DateFormat)
 }
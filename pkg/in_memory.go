@@ -1,32 +1,141 @@
 package storage
 
 import (
+	"container/list"
 	"errors"
+	"net/http/cookiejar"
+	"net/url"
+	"reflect"
 	"sync"
+	"time"
+
+	// external
+	"github.com/golang/snappy"
+	"github.com/google/btree"
 )
 
 // Store is the default storage backend of storage interface.
+// Entries are kept in memory, in a LRU list bounded by Config.MaxRow.
 type Store struct {
-	lock *sync.RWMutex
-	// db          map[uint64]bool
-	// visitedURLs map[uint64]bool
-	// jar         *cookiejar.Jar
+	lock    *sync.RWMutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// index holds the keys sorted, for Scan
+	index  *btree.BTree
+	stats  StoreStats
+	conf   Config
+	closed bool
+	// visitedURLs and jar back the CollectorStorage methods
+	visitedURLs map[uint64]bool
+	jar         *cookiejar.Jar
+	actions     *ActionRegistry
+	// expiry indexes the entries with a TTL, sweeping is closed to stop
+	// the sweeper once started
+	expiry   expiryHeap
+	sweeping chan struct{}
 }
 
+// Config is the configuration of the in-memory store.
 type Config struct {
-	MaxRow     int
-	Sanitize   bool
-	ReadOnly   bool
+	// MaxRow is the maximum number of entries kept, the least recently used
+	// ones are evicted past this limit. Zero means unlimited.
+	MaxRow int
+	// Sanitize is not used by the in-memory store, the keys are kept as
+	// they are.
+	Sanitize bool
+	// ReadOnly rejects writes with ErrReadOnly.
+	ReadOnly bool
+	// StrictMode rejects empty keys, and deletes of missing keys with ErrNotFound.
 	StrictMode bool
-	Compress   bool
-	Debug      bool
-	Stats      bool
+	// Compress stores the values compressed with snappy.
+	Compress bool
+	// Encoder encodes the values kept, overriding Compress. A
+	// compress.Values decides the codec of each value with its policy, from
+	// the content type given to SetTyped.
+	Encoder ValueEncoder
+	// Debug is not used by the in-memory store, Debug() is not implemented.
+	Debug bool
+	// Stats keeps the hit/miss/eviction counters returned by Stats().
+	Stats bool
+	// TTL is the time to live of the entries written by Set, zero means
+	// they never expire.
+	TTL time.Duration
+	// SweepInterval is the interval between two sweeps of the expired
+	// entries, DefaultSweepInterval if zero.
+	SweepInterval time.Duration
+	// Provider is the name of the crawler recorded in the metadata of the
+	// entries written, see Stat().
+	Provider string
+}
+
+// StoreStats holds the counters of an in-memory store.
+type StoreStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Rows      int
+}
+
+// ValueEncoder encodes the values of the in-memory store, see
+// Config.Encoder. compress.Values implements it.
+type ValueEncoder interface {
+	// Encode returns the value to keep for value, contentType is a hint of
+	// its media type, see TypedSetter.
+	Encode(key string, value []byte, contentType string) ([]byte, error)
+	// Decode returns the value of a payload returned by Encode.
+	Decode(payload []byte) ([]byte, error)
+}
+
+// memoryEntry is the value of an element of the LRU list.
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	// expiryIndex is the index of the entry in the expiry heap, -1 if it
+	// doesn't expire
+	expiryIndex int
+	check       Check
 }
 
+var errEmptyKey = errors.New("storage: empty key")
+
 func NewInMemoryStorage(config *Config) (*Store, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
 	s := &Store{
-		lock: &sync.RWMutex{},
+		lock:        &sync.RWMutex{},
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		index:       btree.New(indexDegree),
+		visitedURLs: make(map[uint64]bool),
+		jar:         jar,
 	}
+	if config != nil {
+		if config.MaxRow < 0 {
+			return nil, errors.New("storage.NewInMemoryStorage(): MaxRow can't be negative")
+		}
+		if config.TTL < 0 || config.SweepInterval < 0 {
+			return nil, errors.New("storage.NewInMemoryStorage(): TTL and SweepInterval can't be negative")
+		}
+		s.conf = *config
+	}
+	s.actions = NewActionRegistry()
+	s.actions.MustRegister(ActionSpec{
+		Name:        "getKeys",
+		Description: "lists the stored keys, sorted",
+		Result:      reflect.TypeOf([]string(nil)),
+	}, func(args ...interface{}) (interface{}, error) {
+		return s.keys(), nil
+	})
+	s.actions.MustRegister(ActionSpec{
+		Name:        "getStats",
+		Description: "returns the counters of the store",
+		Result:      reflect.TypeOf(StoreStats{}),
+	}, func(args ...interface{}) (interface{}, error) {
+		return s.Stats(), nil
+	})
 	return s, nil
 }
 
@@ -36,19 +145,147 @@ func NewInMemoryStorage(config *Config) (*Store, error) {
 
 // Init initializes Store
 func (s *Store) Init() error {
-	return errors.New("Init method is not implemented yet...")
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.closed {
+		return ErrClosed
+	}
+	return nil
 }
 
+// Get returns the value stored at the given key and marks it as recently used.
 func (s *Store) Get(key string) (resp []byte, ok bool) {
-	return []byte{}, false
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return nil, false
+	}
+	resp, err := s.get(key)
+	return resp, err == nil
+}
+
+// get returns the decoded value of an entry and marks it as recently used,
+// s.lock must be held. Expired entries are removed.
+func (s *Store) get(key string) ([]byte, error) {
+	el, ok := s.entries[key]
+	if ok && el.Value.(*memoryEntry).expired(time.Now()) {
+		s.remove(key)
+		ok = false
+	}
+	if !ok {
+		if s.conf.Stats {
+			s.stats.Misses++
+		}
+		return nil, ErrNotFound
+	}
+	if s.conf.Stats {
+		s.stats.Hits++
+	}
+	s.lru.MoveToFront(el)
+	entry := el.Value.(*memoryEntry)
+	entry.check.Hit(time.Now())
+
+	return s.decode(entry.value)
 }
 
+// Set stores a value at the given key, evicting the least recently used
+// entries when the store holds more than MaxRow entries. The value expires
+// after Config.TTL when set.
 func (s *Store) Set(key string, resp []byte) error {
-	return errors.New("Set() method is not implemented yet")
+	return s.SetWithTTL(key, resp, s.conf.TTL)
 }
 
+// Delete removes the entry stored at the given key.
 func (s *Store) Delete(key string) error {
-	return errors.New("Delete() method is not implemented yet")
+	if err := s.checkKey(key); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if !s.remove(key) && s.conf.StrictMode {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// encode returns the value to keep for resp, contentType is the hint of
+// Config.Encoder.
+func (s *Store) encode(key string, resp []byte, contentType string) ([]byte, error) {
+	switch {
+	case s.conf.Encoder != nil:
+		return s.conf.Encoder.Encode(key, resp, contentType)
+	case s.conf.Compress:
+		return snappy.Encode(nil, resp), nil
+	}
+	value := make([]byte, len(resp))
+	copy(value, resp)
+	return value, nil
+}
+
+// decode returns a copy of the value of resp, kept by encode.
+func (s *Store) decode(value []byte) ([]byte, error) {
+	switch {
+	case s.conf.Encoder != nil:
+		// the raw values are decoded in place
+		resp, err := s.conf.Encoder.Decode(value)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), resp...), nil
+	case s.conf.Compress:
+		return snappy.Decode(nil, value)
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// put stores an encoded value, s.lock must be held.
+func (s *Store) put(key string, value []byte, expiresAt time.Time) {
+	now := time.Now()
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = value
+		entry.check.UpdatedAt = now
+		entry.check.ExpiredAt = expiresAt
+		entry.check.Provider = s.conf.Provider
+		s.expire(entry, expiresAt)
+		s.lru.MoveToFront(el)
+		return
+	}
+	entry := &memoryEntry{
+		key:         key,
+		value:       value,
+		expiryIndex: -1,
+		check: Check{
+			Enabled:   true,
+			Key:       key,
+			CreatedAt: now,
+			UpdatedAt: now,
+			ExpiredAt: expiresAt,
+			Provider:  s.conf.Provider,
+		},
+	}
+	s.entries[key] = s.lru.PushFront(entry)
+	s.expire(entry, expiresAt)
+	s.indexAdd(key)
+}
+
+// remove deletes an entry and reports whether it existed, s.lock must be held.
+func (s *Store) remove(key string) bool {
+	el, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+	s.lru.Remove(el)
+	delete(s.entries, key)
+	s.unexpire(el.Value.(*memoryEntry))
+	s.indexRemove(key)
+	return true
 }
 
 // Debug
@@ -56,21 +293,186 @@ func (s *Store) Debug(action string) error {
 	return errors.New("Debug() method is not implemented yet")
 }
 
-func (c *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
-	return nil, errors.New("Action() method is not implemented yet")
+// Action runs one of the actions listed by Actions().
+func (s *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	return s.actions.Action(name, args...)
+}
+
+// Actions implements Actioner.Actions()
+func (s *Store) Actions() []ActionSpec {
+	return s.actions.Actions()
+}
+
+// Do implements Actioner.Do()
+func (s *Store) Do(name string, args ...interface{}) (interface{}, error) {
+	return s.actions.Do(name, args...)
 }
 
 // Ping check if the storage is available...
 func (s *Store) Ping() error {
-	return errors.New("Ping() method is not implemented yet")
+	return s.Init()
 }
 
 // Clear truncate all key/values stored...
 func (s *Store) Clear() error {
-	return errors.New("Debug is not implemented yet")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.entries = make(map[string]*list.Element)
+	s.index = btree.New(indexDegree)
+	s.expiry = nil
+	s.lru.Init()
+	return nil
+}
+
+// Visited implements CollectorStorage.Visited()
+func (s *Store) Visited(requestID uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.visitedURLs[requestID] = true
+	return nil
+}
+
+// IsVisited implements CollectorStorage.IsVisited()
+func (s *Store) IsVisited(requestID uint64) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return false, ErrClosed
+	}
+	return s.visitedURLs[requestID], nil
+}
+
+// Cookies implements CollectorStorage.Cookies()
+func (s *Store) Cookies(u *url.URL) string {
+	return StringifyCookies(s.jar.Cookies(u))
+}
+
+// SetCookies implements CollectorStorage.SetCookies()
+func (s *Store) SetCookies(u *url.URL, cookies string) {
+	if s.conf.ReadOnly {
+		return
+	}
+	s.jar.SetCookies(u, UnstringifyCookies(cookies))
+}
+
+// Stat implements Stater.Stat(), it doesn't count as a read.
+func (s *Store) Stat(key string) (*Check, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if !s.live(key, time.Now()) {
+		return nil, ErrNotFound
+	}
+	check := s.entries[key].Value.(*memoryEntry).check
+	return &check, nil
+}
+
+// Stats returns the counters of the store. Hits, misses and evictions are
+// only counted when Config.Stats is enabled.
+func (s *Store) Stats() StoreStats {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	stats := s.stats
+	stats.Rows = s.lru.Len()
+	return stats
+}
+
+// Len returns the number of entries held by the store.
+func (s *Store) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.lru.Len()
 }
 
 // Close deletes the storage
 func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	if s.sweeping != nil {
+		close(s.sweeping)
+	}
+	s.entries = nil
+	s.index = btree.New(indexDegree)
+	s.expiry = nil
+	s.visitedURLs = nil
+	s.lru.Init()
+	return nil
+}
+
+// writable returns the error to report for a write, s.lock must be held.
+func (s *Store) writable() error {
+	if s.closed {
+		return ErrClosed
+	}
+	if s.conf.ReadOnly {
+		return ErrReadOnly
+	}
 	return nil
 }
+
+func (s *Store) checkKey(key string) error {
+	if s.conf.StrictMode && key == "" {
+		return errEmptyKey
+	}
+	return nil
+}
+
+func (s *Store) keys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys := make([]string, 0, s.index.Len())
+	s.index.Ascend(func(item btree.Item) bool {
+		keys = append(keys, string(item.(indexKey)))
+		return true
+	})
+	return keys
+}
+
+// evict drops the least recently used entries past MaxRow, s.lock must be held.
+func (s *Store) evict() {
+	if s.conf.MaxRow <= 0 {
+		return
+	}
+	for s.lru.Len() > s.conf.MaxRow {
+		s.remove(s.lru.Back().Value.(*memoryEntry).key)
+		if s.conf.Stats {
+			s.stats.Evictions++
+		}
+	}
+}
+
+// indexDegree is the degree of the B-tree of the keys.
+const indexDegree = 32
+
+// indexKey is a key in the index.
+type indexKey string
+
+func (k indexKey) Less(than btree.Item) bool { return k < than.(indexKey) }
+
+// indexAdd inserts a new key in the index, s.lock must be held.
+func (s *Store) indexAdd(key string) {
+	s.index.ReplaceOrInsert(indexKey(key))
+}
+
+// indexRemove removes a key from the index, s.lock must be held.
+func (s *Store) indexRemove(key string) {
+	s.index.Delete(indexKey(key))
+}
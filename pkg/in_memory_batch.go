@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"time"
+)
+
+var _ Batcher = (*Store)(nil)
+
+// WriteBatch implements Batcher.WriteBatch(), the batch is applied under a
+// single lock.
+func (s *Store) WriteBatch(b *Batch) error {
+	values := make([][]byte, len(b.Ops))
+	for i, op := range b.Ops {
+		if op.Delete {
+			continue
+		}
+		value, err := s.encode(op.Key, op.Value, "")
+		if err != nil {
+			return AbortBatch(b, map[string]error{op.Key: err})
+		}
+		values[i] = value
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	// check the whole batch before applying it, existing tracks the keys
+	// written or deleted by the previous operations
+	now := time.Now()
+	errs := make(map[string]error)
+	existing := make(map[string]bool)
+	for _, op := range b.Ops {
+		if err := s.checkKey(op.Key); err != nil {
+			errs[op.Key] = err
+			continue
+		}
+		exists, ok := existing[op.Key]
+		if !ok {
+			exists = s.live(op.Key, now)
+		}
+		if op.Delete && !exists && s.conf.StrictMode {
+			errs[op.Key] = ErrNotFound
+		}
+		existing[op.Key] = !op.Delete
+	}
+	if len(errs) > 0 {
+		return AbortBatch(b, errs)
+	}
+
+	for i, op := range b.Ops {
+		if op.Delete {
+			s.remove(op.Key)
+		} else {
+			s.put(op.Key, values[i], Deadline(now, op.TTL(s.conf.TTL)))
+		}
+	}
+	s.evict()
+	return nil
+}
+
+// SetMany implements Batcher.SetMany()
+func (s *Store) SetMany(kvs []KV) error {
+	return s.WriteBatch(SetBatch(kvs))
+}
+
+// DeleteMany implements Batcher.DeleteMany()
+func (s *Store) DeleteMany(keys []string) error {
+	return s.WriteBatch(DeleteBatch(keys))
+}
+
+// GetMany implements Batcher.GetMany(), the keys found are marked as
+// recently used.
+func (s *Store) GetMany(keys []string) (map[string][]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	values := make(map[string][]byte, len(keys))
+	errs := make(BatchError)
+	for _, key := range keys {
+		value, err := s.get(key)
+		switch err {
+		case nil:
+			values[key] = value
+		case ErrNotFound:
+		default:
+			errs[key] = err
+		}
+	}
+	if len(errs) > 0 {
+		return values, errs
+	}
+	return values, nil
+}
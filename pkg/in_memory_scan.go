@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"strings"
+	"time"
+
+	// external
+	"github.com/google/btree"
+)
+
+var _ Scanner = (*Store)(nil)
+
+// Scan implements Scanner.Scan(), scanned keys are not marked as used.
+func (s *Store) Scan(prefix, startAfter string, limit int) (keys []string, next string, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return nil, "", ErrClosed
+	}
+	s.scan(prefix, startAfter, limit, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}, &next)
+	return keys, next, nil
+}
+
+// ScanValues implements Scanner.ScanValues(), scanned keys are not marked
+// as used.
+func (s *Store) ScanValues(prefix, startAfter string, limit int) (kvs []KV, next string, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return nil, "", ErrClosed
+	}
+	err = s.scan(prefix, startAfter, limit, func(key string) error {
+		value, err := s.decode(s.entries[key].Value.(*memoryEntry).value)
+		if err != nil {
+			return err
+		}
+		kvs = append(kvs, KV{Key: key, Value: value})
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return kvs, next, nil
+}
+
+// scan calls fn for the keys of a page and sets next to the cursor of the
+// following one, s.lock must be held.
+func (s *Store) scan(prefix, startAfter string, limit int, fn func(key string) error, next *string) error {
+	*next = ""
+	now := time.Now()
+	last := ""
+	n := 0
+	var err error
+	s.index.AscendGreaterOrEqual(indexKey(ScanStart(prefix, startAfter)), func(item btree.Item) bool {
+		key := string(item.(indexKey))
+		switch {
+		case !strings.HasPrefix(key, prefix):
+			return false
+		case s.entries[key].Value.(*memoryEntry).expired(now):
+			return true
+		case limit > 0 && n == limit:
+			*next = last
+			return false
+		}
+		if err = fn(key); err != nil {
+			return false
+		}
+		last = key
+		n++
+		return true
+	})
+	return err
+}
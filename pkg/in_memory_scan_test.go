@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestInMemoryScan(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{Compress: true})
+	for _, k := range []string{"b/1", "a/3", "a/1", "a/2"} {
+		s.Set(k, []byte("v"+k))
+	}
+	s.Delete("a/2")
+
+	keys, next, err := s.Scan("a/", "", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"a/1"}) || next != "a/1" {
+		t.Errorf("unexpected first page: %q, next=%q", keys, next)
+	}
+	kvs, next, err := s.ScanValues("a/", next, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "a/3" || string(kvs[0].Value) != "va/3" || next != "" {
+		t.Errorf("unexpected last page: %+v, next=%q", kvs, next)
+	}
+	if keys, next, _ := s.Scan("", "", 0); len(keys) != 3 || next != "" {
+		t.Errorf("unexpected full scan: %q, next=%q", keys, next)
+	}
+}
+
+func TestInMemoryScanIndex(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{MaxRow: 500})
+	kept := make(map[string]bool)
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("k/%d", rand.Intn(1000))
+		if rand.Intn(3) == 0 {
+			s.Delete(key)
+			delete(kept, key)
+			continue
+		}
+		s.Set(key, []byte("v"))
+		kept[key] = true
+	}
+	for key := range kept {
+		if _, ok := s.entries[key]; !ok {
+			// evicted
+			delete(kept, key)
+		}
+	}
+	expected := make([]string, 0, len(kept))
+	for key := range kept {
+		expected = append(expected, key)
+	}
+	sort.Strings(expected)
+
+	var keys []string
+	for next := ""; ; {
+		page, cursor, err := s.Scan("k/", next, 100)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		keys = append(keys, page...)
+		if next = cursor; next == "" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %d sorted keys, got %d", len(expected), len(keys))
+	}
+}
+
+func TestWalk(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{MaxRow: 10})
+	for i := 0; i < 15; i++ {
+		s.Set(string(rune('a'+i)), []byte{byte(i)})
+	}
+
+	var keys []string
+	err := Walk(s, "", 3, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the 5 first keys have been evicted
+	if len(keys) != 10 || keys[0] != "f" || keys[9] != "o" {
+		t.Errorf("unexpected keys: %q", keys)
+	}
+
+	stop := errors.New("stop")
+	if err := Walk(s, "", 3, func(key string) error { return stop }); err != stop {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+}
@@ -1 +1,197 @@
 package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestInMemorySetGetDelete(t *testing.T) {
+	s, err := NewInMemoryStorage(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := s.Get("hello"); ok {
+		t.Error("expected hello to be missing")
+	}
+	if err := s.Set("hello", []byte("world")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v, ok := s.Get("hello")
+	if !ok || string(v) != "world" {
+		t.Errorf("expected world, got %q (ok=%t)", v, ok)
+	}
+	if err := s.Delete("hello"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := s.Get("hello"); ok {
+		t.Error("expected hello to be deleted")
+	}
+}
+
+func TestInMemoryMaxRow(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{MaxRow: 2, Stats: true})
+	s.Set("a", []byte("1"))
+	s.Set("b", []byte("2"))
+	// touch a, so b becomes the least recently used entry
+	s.Get("a")
+	s.Set("c", []byte("3"))
+
+	if _, ok := s.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := s.Get(k); !ok {
+			t.Errorf("expected %s to be kept", k)
+		}
+	}
+	stats := s.Stats()
+	if stats.Evictions != 1 || stats.Rows != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("unexpected hit/miss counters: %+v", stats)
+	}
+}
+
+func TestInMemoryReadOnly(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{ReadOnly: true})
+	if err := s.Set("k", []byte("v")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if err := s.Delete("k"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if err := s.Clear(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestInMemoryStrictMode(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{StrictMode: true})
+	if err := s.Set("", []byte("v")); err == nil {
+		t.Error("expected an error for an empty key")
+	}
+	if err := s.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryCompress(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{Compress: true})
+	value := bytes.Repeat([]byte("colly "), 1000)
+	if err := s.Set("page", value); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	stored := s.entries["page"].Value.(*memoryEntry).value
+	if len(stored) >= len(value) {
+		t.Errorf("expected the stored value to be compressed, %d >= %d", len(stored), len(value))
+	}
+	v, ok := s.Get("page")
+	if !ok || !bytes.Equal(v, value) {
+		t.Error("expected the original value back")
+	}
+}
+
+// typeEncoder prefixes the values with their content type, it rejects the
+// values without.
+type typeEncoder struct{}
+
+func (typeEncoder) Encode(key string, value []byte, contentType string) ([]byte, error) {
+	if contentType == "" {
+		return nil, errors.New("no content type")
+	}
+	return append([]byte(contentType+";"), value...), nil
+}
+
+func (typeEncoder) Decode(payload []byte) ([]byte, error) {
+	return payload[bytes.IndexByte(payload, ';')+1:], nil
+}
+
+func TestInMemoryEncoder(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{Compress: true, Encoder: typeEncoder{}})
+	if err := s.SetTyped("page", []byte("<p>"), "text/html"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stored := s.entries["page"].Value.(*memoryEntry).value; string(stored) != "text/html;<p>" {
+		t.Errorf("unexpected stored value %q", stored)
+	}
+	if v, ok := s.Get("page"); !ok || string(v) != "<p>" {
+		t.Errorf("unexpected value %q", v)
+	}
+
+	if err := s.Set("untyped", []byte("v")); err == nil {
+		t.Error("expected the error of the encoder")
+	}
+	var batchErr BatchError
+	if err := s.WriteBatch(SetBatch([]KV{{Key: "untyped", Value: []byte("v")}})); !errors.As(err, &batchErr) {
+		t.Errorf("expected a BatchError, got %v", err)
+	}
+	if _, ok := s.Get("untyped"); ok {
+		t.Error("expected the values failing to encode not to be stored")
+	}
+}
+
+func TestInMemoryClose(t *testing.T) {
+	s, _ := NewInMemoryStorage(nil)
+	s.Set("k", []byte("v"))
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.Set("k", []byte("v")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if _, err := Upgrade(s, nil).Get(context.Background(), "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound from a closed store, got %v", err)
+	}
+}
+
+func TestInMemoryConcurrent(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{MaxRow: 50, Stats: true})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("%d-%d", i, j)
+				s.Set(key, []byte(key))
+				s.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := s.Len(); n != 50 {
+		t.Errorf("expected 50 rows, got %d", n)
+	}
+}
+
+func TestInMemoryCollectorStorage(t *testing.T) {
+	var s CollectorStorage
+	s, _ = NewInMemoryStorage(nil)
+	if err := s.Init(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ok, err := s.IsVisited(42); err != nil || ok {
+		t.Errorf("expected 42 not to be visited, got %t (%v)", ok, err)
+	}
+	if err := s.Visited(42); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ok, err := s.IsVisited(42); err != nil || !ok {
+		t.Errorf("expected 42 to be visited, got %t (%v)", ok, err)
+	}
+
+	u, _ := url.Parse("http://example.com/")
+	s.SetCookies(u, "session=abc; Path=/")
+	if c := s.Cookies(u); c != "session=abc" {
+		t.Errorf("unexpected cookies: %q", c)
+	}
+}
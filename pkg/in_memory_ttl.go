@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"container/heap"
+	"time"
+)
+
+var _ Expirer = (*Store)(nil)
+var _ TypedSetter = (*Store)(nil)
+var _ TypedExpirer = (*Store)(nil)
+
+// SetWithTTL implements Expirer.SetWithTTL()
+func (s *Store) SetWithTTL(key string, resp []byte, ttl time.Duration) error {
+	return s.set(key, resp, "", ttl)
+}
+
+// SetTyped implements TypedSetter.SetTyped(), contentType is the hint of
+// Config.Encoder.
+func (s *Store) SetTyped(key string, resp []byte, contentType string) error {
+	return s.set(key, resp, contentType, s.conf.TTL)
+}
+
+// SetTypedWithTTL implements TypedExpirer.SetTypedWithTTL()
+func (s *Store) SetTypedWithTTL(key string, resp []byte, contentType string, ttl time.Duration) error {
+	return s.set(key, resp, contentType, ttl)
+}
+
+// set encodes and stores a value expiring after ttl.
+func (s *Store) set(key string, resp []byte, contentType string, ttl time.Duration) error {
+	if err := s.checkKey(key); err != nil {
+		return err
+	}
+
+	value, err := s.encode(key, resp, contentType)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.put(key, value, Deadline(time.Now(), ttl))
+	s.evict()
+	return nil
+}
+
+// Sweep removes the expired entries and returns how many were removed.
+func (s *Store) Sweep() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.sweep(time.Now())
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// live reports whether a key holds an entry which has not expired, s.lock
+// must be held.
+func (s *Store) live(key string, now time.Time) bool {
+	el, ok := s.entries[key]
+	return ok && !el.Value.(*memoryEntry).expired(now)
+}
+
+// expire sets the expiry of an entry, the zero time removes it, and starts
+// the sweeper if needed. s.lock must be held.
+func (s *Store) expire(e *memoryEntry, at time.Time) {
+	e.expiresAt = at
+	switch {
+	case at.IsZero():
+		s.unexpire(e)
+		return
+	case e.expiryIndex >= 0:
+		heap.Fix(&s.expiry, e.expiryIndex)
+		return
+	}
+	heap.Push(&s.expiry, e)
+	if s.sweeping != nil {
+		return
+	}
+	interval := s.conf.SweepInterval
+	if interval == 0 {
+		interval = DefaultSweepInterval
+	}
+	s.sweeping = make(chan struct{})
+	go s.sweeper(interval, s.sweeping)
+}
+
+// unexpire removes an entry from the expiry index, s.lock must be held.
+func (s *Store) unexpire(e *memoryEntry) {
+	if e.expiryIndex >= 0 {
+		heap.Remove(&s.expiry, e.expiryIndex)
+	}
+}
+
+func (s *Store) sweeper(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.Sweep()
+		}
+	}
+}
+
+// sweep removes the entries expired at now, s.lock must be held.
+func (s *Store) sweep(now time.Time) int {
+	n := 0
+	for len(s.expiry) > 0 && s.expiry[0].expired(now) {
+		s.remove(s.expiry[0].key)
+		n++
+	}
+	return n
+}
+
+// expiryHeap implements heap.Interface over the entries with a TTL, the
+// earliest expiry first. The entries keep their index in the heap, which is
+// fixed when they are rewritten and removed with them.
+type expiryHeap []*memoryEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].expiryIndex = i
+	h[j].expiryIndex = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	e := x.(*memoryEntry)
+	e.expiryIndex = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.expiryIndex = -1
+	*h = old[:len(old)-1]
+	return e
+}
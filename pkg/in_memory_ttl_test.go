@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemoryTTL(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{SweepInterval: time.Hour})
+	defer s.Close()
+
+	if err := s.SetWithTTL("short", []byte("v"), 20*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s.Set("forever", []byte("v"))
+	if _, ok := s.Get("short"); !ok {
+		t.Fatal("expected short to be readable before its expiry")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	// the sweeper has not run yet
+	if _, ok := s.Get("short"); ok {
+		t.Error("expected short to read as not found once expired")
+	}
+	if keys, _, _ := s.Scan("", "", 0); len(keys) != 1 || keys[0] != "forever" {
+		t.Errorf("expected the expired entries to be skipped by Scan, got %q", keys)
+	}
+}
+
+func TestInMemoryDefaultTTL(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{TTL: 20 * time.Millisecond, SweepInterval: 5 * time.Millisecond})
+	defer s.Close()
+
+	s.Set("a", []byte("1"))
+	s.SetMany([]KV{{Key: "b", Value: []byte("2")}})
+	// a rewrite without TTL keeps the entry past its first expiry
+	s.SetWithTTL("c", []byte("3"), 20*time.Millisecond)
+	s.SetWithTTL("c", []byte("3"), 0)
+
+	time.Sleep(60 * time.Millisecond)
+	if n := s.Len(); n != 1 {
+		t.Errorf("expected the sweeper to remove the expired entries, %d left", n)
+	}
+	if _, ok := s.Get("c"); !ok {
+		t.Error("expected c to be kept")
+	}
+}
+
+func TestInMemoryExpiryIndex(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{MaxRow: 2, SweepInterval: time.Hour})
+	defer s.Close()
+
+	// a rewrite moves the entry in the index instead of adding one
+	for i := 0; i < 100; i++ {
+		s.SetWithTTL("a", []byte("1"), time.Duration(100-i)*time.Minute)
+	}
+	s.SetWithTTL("b", []byte("2"), time.Hour)
+	if n := len(s.expiry); n != 2 {
+		t.Errorf("expected 2 entries in the expiry index, got %d", n)
+	}
+	if s.expiry[0].key != "a" {
+		t.Errorf("expected a to expire first, got %s", s.expiry[0].key)
+	}
+
+	// the entries leave the index with their TTL, deleted or evicted
+	s.SetWithTTL("a", []byte("1"), 0)
+	s.Set("c", []byte("3"))
+	s.Set("d", []byte("4"))
+	if n := len(s.expiry); n != 0 {
+		t.Errorf("expected an empty expiry index, got %d entries", n)
+	}
+	s.SetWithTTL("e", []byte("5"), time.Hour)
+	s.Delete("e")
+	if n := len(s.expiry); n != 0 {
+		t.Errorf("expected an empty expiry index, got %d entries", n)
+	}
+}
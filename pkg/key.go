@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Request holds the parts of a request used to derive its storage key.
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// NewRequest returns the Request of an *http.Request. The body is read
+// with GetBody when available, or read and restored otherwise.
+func NewRequest(r *http.Request) (*Request, error) {
+	req := &Request{
+		Method: r.Method,
+		URL:    r.URL,
+		Header: r.Header,
+	}
+	switch {
+	case r.GetBody != nil:
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		if req.Body, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+	case r.Body != nil && r.Body != http.NoBody:
+		b, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		req.Body = b
+	}
+	return req, nil
+}
+
+// KeyStrategy derives the storage key of a request. Crawlers sharing a
+// store must use the same strategy for their entries to line up.
+type KeyStrategy interface {
+	Key(req *Request) (string, error)
+}
+
+// KeyStrategyFunc is an adapter to use a function as a KeyStrategy.
+type KeyStrategyFunc func(req *Request) (string, error)
+
+// Key implements KeyStrategy.
+func (f KeyStrategyFunc) Key(req *Request) (string, error) {
+	return f(req)
+}
+
+// DefaultTrackingParams are the query parameters removed by a Fingerprint
+// with StripTracking enabled. A trailing '*' matches any suffix.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"gclid",
+	"dclid",
+	"fbclid",
+	"msclkid",
+	"yclid",
+	"igshid",
+	"mc_cid",
+	"mc_eid",
+	"_ga",
+	"_hsenc",
+	"_hsmi",
+}
+
+// Fingerprint is the default KeyStrategy. The key is the host of the request
+// followed by the sha1 of its method, canonical URL, selected headers and
+// body, e.g. "example.com/0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33". Keeping
+// the host in clear lets the entries of a site be scanned by prefix.
+//
+// The URL is canonicalized by lowercasing its scheme and host, dropping the
+// default port and the fragment, and sorting the query parameters.
+type Fingerprint struct {
+	// Headers lists the headers taken into account, e.g. "Accept-Language".
+	Headers []string
+	// StripTracking removes the tracking parameters from the query.
+	StripTracking bool
+	// TrackingParams overrides DefaultTrackingParams.
+	TrackingParams []string
+}
+
+// DefaultKeyStrategy is the KeyStrategy used until SetKeyStrategy is called.
+var DefaultKeyStrategy KeyStrategy = &Fingerprint{}
+
+var (
+	keyStrategyMu sync.RWMutex
+	keyStrategy   = DefaultKeyStrategy
+)
+
+// SetKeyStrategy sets the strategy used by Get, Set, GetRequest, SetRequest
+// and RequestKey. A nil strategy restores DefaultKeyStrategy.
+func SetKeyStrategy(k KeyStrategy) {
+	if k == nil {
+		k = DefaultKeyStrategy
+	}
+	keyStrategyMu.Lock()
+	keyStrategy = k
+	keyStrategyMu.Unlock()
+}
+
+// RequestKey returns the storage key of a request.
+func RequestKey(req *Request) (string, error) {
+	keyStrategyMu.RLock()
+	k := keyStrategy
+	keyStrategyMu.RUnlock()
+	return k.Key(req)
+}
+
+// Key implements KeyStrategy.
+func (f *Fingerprint) Key(req *Request) (string, error) {
+	if req == nil || req.URL == nil {
+		return "", errors.New("storage: can't derive the key of a request without URL")
+	}
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n", method, f.CanonicalURL(req.URL))
+	headers := make([]string, 0, len(f.Headers))
+	for _, name := range f.Headers {
+		name = http.CanonicalHeaderKey(name)
+		values := req.Header[name]
+		if len(values) == 0 {
+			continue
+		}
+		headers = append(headers, name+":"+strings.Join(values, ","))
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		fmt.Fprintf(h, "%s\n", header)
+	}
+	if len(req.Body) > 0 {
+		fmt.Fprintf(h, "%x\n", sha1.Sum(req.Body))
+	}
+	return fmt.Sprintf("%s/%x", canonicalHost(req.URL), h.Sum(nil)), nil
+}
+
+// CanonicalURL returns the canonical form of u used to derive the keys.
+func (f *Fingerprint) CanonicalURL(u *url.URL) string {
+	c := url.URL{
+		Scheme: strings.ToLower(u.Scheme),
+		Host:   canonicalHost(u),
+		Path:   u.EscapedPath(),
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+
+	query := u.Query()
+	for name, values := range query {
+		if f.StripTracking && f.isTracking(name) {
+			delete(query, name)
+			continue
+		}
+		sort.Strings(values)
+	}
+	// url.Values.Encode sorts the parameters by name
+	c.RawQuery = query.Encode()
+
+	// Path is already escaped, build the URL by hand
+	s := c.Scheme + "://" + c.Host + c.Path
+	if c.RawQuery != "" {
+		s += "?" + c.RawQuery
+	}
+	return s
+}
+
+func (f *Fingerprint) isTracking(name string) bool {
+	params := f.TrackingParams
+	if params == nil {
+		params = DefaultTrackingParams
+	}
+	name = strings.ToLower(name)
+	for _, p := range params {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyNamespace returns the host a key belongs to: the host of the URL keys
+// or the part before the first '/' of the keys derived by Fingerprint. It
+// is empty for the other keys.
+func KeyNamespace(key string) string {
+	if u, err := url.Parse(key); err == nil && u.Host != "" {
+		return canonicalHost(u)
+	}
+	if i := strings.IndexByte(key, '/'); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// NamespacePrefixes returns the prefixes of the keys of a namespace, see
+// KeyNamespace: the keys derived by Fingerprint and the http and https URL
+// keys. The keys found under them must still be checked with KeyNamespace,
+// the URL keys of a host written in upper case are not found. The empty
+// namespace has the empty prefix, all the keys.
+func NamespacePrefixes(namespace string) []string {
+	if namespace == "" {
+		return []string{""}
+	}
+	return []string{namespace + "/", "http://" + namespace, "https://" + namespace}
+}
+
+// canonicalHost returns the lowercased host of u without its default port.
+func canonicalHost(u *url.URL) string {
+	host := strings.ToLower(u.Host)
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		if strings.Contains(h, ":") {
+			return "[" + h + "]"
+		}
+		return h
+	}
+	return host
+}
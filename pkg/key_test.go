@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func mustKey(t *testing.T, f *Fingerprint, req *Request) string {
+	t.Helper()
+	key, err := f.Key(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return key
+}
+
+func getRequest(t *testing.T, rawurl string) *Request {
+	t.Helper()
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return &Request{Method: http.MethodGet, URL: u}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	f := &Fingerprint{StripTracking: true}
+	tests := []struct {
+		in, out string
+	}{
+		{"HTTP://Example.COM", "http://example.com/"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"http://example.com/a#section", "http://example.com/a"},
+		{"http://example.com/?b=2&a=1&a=0", "http://example.com/?a=0&a=1&b=2"},
+		{"http://example.com/?utm_source=x&id=1&gclid=y", "http://example.com/?id=1"},
+		{"http://example.com/a%20b", "http://example.com/a%20b"},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.in)
+		if out := f.CanonicalURL(u); out != test.out {
+			t.Errorf("CanonicalURL(%q) = %q, expected %q", test.in, out, test.out)
+		}
+	}
+}
+
+func TestFingerprintKey(t *testing.T) {
+	f := &Fingerprint{}
+	key := mustKey(t, f, getRequest(t, "http://Example.com:80/page?b=2&a=1#top"))
+	if !strings.HasPrefix(key, "example.com/") || len(key) != len("example.com/")+40 {
+		t.Errorf("unexpected key format: %q", key)
+	}
+	if other := mustKey(t, f, getRequest(t, "http://example.com/page?a=1&b=2")); other != key {
+		t.Errorf("expected equivalent URLs to share a key, %q != %q", other, key)
+	}
+
+	tracked := getRequest(t, "http://example.com/page?a=1&b=2&utm_campaign=spring")
+	if mustKey(t, f, tracked) == key {
+		t.Error("expected tracking parameters to be kept by default")
+	}
+	if mustKey(t, &Fingerprint{StripTracking: true}, tracked) != key {
+		t.Error("expected tracking parameters to be stripped")
+	}
+
+	post := getRequest(t, "http://example.com/page?a=1&b=2")
+	post.Method = http.MethodPost
+	if mustKey(t, f, post) == key {
+		t.Error("expected the method to change the key")
+	}
+	post.Body = []byte("q=1")
+	withBody := mustKey(t, f, post)
+	post.Body = []byte("q=2")
+	if mustKey(t, f, post) == withBody {
+		t.Error("expected the body to change the key")
+	}
+}
+
+func TestFingerprintHeaders(t *testing.T) {
+	en := getRequest(t, "http://example.com/")
+	en.Header = http.Header{"Accept-Language": {"en"}, "User-Agent": {"a"}}
+	fr := getRequest(t, "http://example.com/")
+	fr.Header = http.Header{"Accept-Language": {"fr"}, "User-Agent": {"b"}}
+
+	if mustKey(t, &Fingerprint{}, en) != mustKey(t, &Fingerprint{}, fr) {
+		t.Error("expected headers to be ignored by default")
+	}
+	f := &Fingerprint{Headers: []string{"accept-language"}}
+	if mustKey(t, f, en) == mustKey(t, f, fr) {
+		t.Error("expected the selected header to change the key")
+	}
+	fr.Header.Set("Accept-Language", "en")
+	if mustKey(t, f, en) != mustKey(t, f, fr) {
+		t.Error("expected the other headers to be ignored")
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewBufferString("body"))
+	req, err := NewRequest(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(req.Body) != "body" {
+		t.Errorf("unexpected body: %q", req.Body)
+	}
+	if b, _ := r.GetBody(); b == nil {
+		t.Error("expected the body of the request to be kept")
+	}
+}
+
+func TestSetKeyStrategy(t *testing.T) {
+	defer SetKeyStrategy(nil)
+	SetKeyStrategy(KeyStrategyFunc(func(req *Request) (string, error) {
+		return req.URL.Path, nil
+	}))
+
+	s, _ := NewInMemoryStorage(nil)
+	if err := Set(s, "http://example.com/custom", []byte("v")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := s.Get("/custom"); !ok {
+		t.Error("expected the custom strategy to derive the key")
+	}
+
+	SetKeyStrategy(nil)
+	if key, _ := RequestKey(getRequest(t, "http://example.com/custom")); key == "/custom" {
+		t.Error("expected the default strategy to be restored")
+	}
+}
+
+func TestGetSetQuery(t *testing.T) {
+	s, _ := NewInMemoryStorage(nil)
+	if err := Set(s, "http://example.com/?b=2&a=1", []byte("page")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v, err := Get(s, "HTTP://EXAMPLE.COM:80/?a=1&b=2#anchor")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(v) != "page" {
+		t.Errorf("expected page, got %q", v)
+	}
+	v, err = GetRequest(s, getRequest(t, "http://example.com/?a=1&b=2"))
+	if err != nil || string(v) != "page" {
+		t.Errorf("expected page from GetRequest, got %q (%v)", v, err)
+	}
+	if v, err := Get(s, "http://example.com/missing"); err != nil || len(v) != 0 {
+		t.Errorf("expected an empty miss, got %q (%v)", v, err)
+	}
+	if _, err := Get(s, "http://[::1"); err == nil {
+		t.Error("expected an error for an invalid URL")
+	}
+}
+
+func TestKeyNamespace(t *testing.T) {
+	key, _ := RequestKey(getRequest(t, "http://Example.com:80/page"))
+	for k, want := range map[string]string{
+		key:                            "example.com",
+		"https://example.com:443/page": "example.com",
+		"http://example.com:8080/":     "example.com:8080",
+		"page":                         "",
+		"/page":                        "",
+	} {
+		if got := KeyNamespace(k); got != want {
+			t.Errorf("%q: expected %q, got %q", k, want, got)
+		}
+	}
+}
+
+func TestNamespacePrefixes(t *testing.T) {
+	keys := []string{"example.com/abc", "http://example.com/page", "https://example.com:8443/", "example.org/abc", "http://example.comics.org/"}
+	var found []string
+	for _, key := range keys {
+		for _, prefix := range NamespacePrefixes("example.com") {
+			if strings.HasPrefix(key, prefix) && KeyNamespace(key) == "example.com" {
+				found = append(found, key)
+			}
+		}
+	}
+	if len(found) != 2 || found[0] != keys[0] || found[1] != keys[1] {
+		t.Errorf("unexpected keys %v", found)
+	}
+	if p := NamespacePrefixes(""); len(p) != 1 || p[0] != "" {
+		t.Errorf("unexpected prefixes %v", p)
+	}
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DSN is a parsed data source name, e.g.
+//
+//	badger:///var/lib/colly?compress=snappy&sync=false&ttl=120d
+//	bbolt://./shared/colly.bbolt?bucket=pages&compress=gzip
+//	memory://?ttl=1h
+//
+// The query parameters are:
+//
+//	bucket     the bucket (or value directory) of the entries
+//	compress   the compression of the values, "none", "true" or a codec name
+//	sync       whether the writes are synced to disk
+//	ttl        the default time to live of the entries, e.g. "90m" or "120d"
+//	read-only  whether the store rejects the writes
+type DSN struct {
+	Scheme string
+	// Path is the location of the store, host and path of the URL joined.
+	Path   string
+	Bucket string
+	// Compress is the name of the codec, empty for none.
+	Compress string
+	// Sync is nil when the DSN leaves it to the backend.
+	Sync     *bool
+	TTL      time.Duration
+	ReadOnly bool
+}
+
+// Opener opens a store from its DSN.
+type Opener func(dsn *DSN) (Storage, error)
+
+var (
+	openersMu sync.RWMutex
+	openers   = make(map[string]Opener)
+)
+
+// Register makes a backend available to Open under a scheme. It panics if
+// the scheme is registered twice or if opener is nil, it is meant to be
+// called from the init function of the backends.
+func Register(scheme string, opener Opener) {
+	openersMu.Lock()
+	defer openersMu.Unlock()
+	if opener == nil {
+		panic("storage: Register opener is nil")
+	}
+	if _, dup := openers[scheme]; dup {
+		panic("storage: Register called twice for scheme " + scheme)
+	}
+	openers[scheme] = opener
+}
+
+// Schemes returns the sorted list of the registered schemes.
+func Schemes() []string {
+	openersMu.RLock()
+	defer openersMu.RUnlock()
+	schemes := make([]string, 0, len(openers))
+	for scheme := range openers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open opens the store described by dsn, the stores holding resources
+// implement io.Closer. The backend must have been registered, usually by
+// importing its package:
+//
+//	import _ "github.com/sniperkit/colly-storage/plugin/backend/badger"
+//
+//	store, err := storage.Open(os.Getenv("COLLY_STORAGE"))
+func Open(dsn string) (Storage, error) {
+	d, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	openersMu.RLock()
+	opener, ok := openers[d.Scheme]
+	openersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown scheme %q (forgotten import?)", d.Scheme)
+	}
+	return opener(d)
+}
+
+// ParseDSN parses a data source name, see DSN.
+func ParseDSN(dsn string) (*DSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid dsn: %s", err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("storage: invalid dsn %q: missing scheme", dsn)
+	}
+
+	d := &DSN{
+		Scheme: strings.ToLower(u.Scheme),
+		Path:   u.Host + u.Path,
+	}
+	for name, values := range u.Query() {
+		value := values[len(values)-1]
+		switch name {
+		case "bucket":
+			d.Bucket = value
+		case "compress":
+			d.Compress, err = parseCompress(value)
+		case "sync":
+			var sync bool
+			sync, err = strconv.ParseBool(value)
+			d.Sync = &sync
+		case "ttl":
+			d.TTL, err = ParseTTL(value)
+		case "read-only", "readonly":
+			d.ReadOnly, err = strconv.ParseBool(value)
+		default:
+			err = fmt.Errorf("unknown parameter")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("storage: invalid dsn parameter %q: %s", name, err)
+		}
+	}
+	return d, nil
+}
+
+// CompressWith checks the codec of the DSN is one of the supported ones,
+// and reports whether the values are compressed. The names are matched
+// exactly, see CompressCodec for the codecs taking a level.
+func (d *DSN) CompressWith(supported ...string) (bool, error) {
+	if d.Compress == "" {
+		return false, nil
+	}
+	for _, codec := range supported {
+		if d.Compress == codec {
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("storage: %s stores don't support %q compression (supported: %s)", d.Scheme, d.Compress, strings.Join(supported, ", "))
+}
+
+// CompressCodec checks the codec of the DSN with valid, e.g. the lookup of
+// the codec names with their level and window of the compress package, and
+// reports whether the values are compressed. The "default" codec of the
+// backend is always valid.
+func (d *DSN) CompressCodec(valid func(codec string) error) (bool, error) {
+	switch d.Compress {
+	case "":
+		return false, nil
+	case "default":
+		return true, nil
+	}
+	if err := valid(d.Compress); err != nil {
+		return false, fmt.Errorf("storage: %s stores don't support %q compression: %s", d.Scheme, d.Compress, err)
+	}
+	return true, nil
+}
+
+// parseCompress returns the codec name of a compress parameter, "true"
+// being the default codec of the backend.
+func parseCompress(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "", "none", "false", "0":
+		return "", nil
+	case "true", "1":
+		return "default", nil
+	}
+	return strings.ToLower(value), nil
+}
+
+// ParseTTL parses a duration as time.ParseDuration, with the "d" unit for
+// days in addition.
+func ParseTTL(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func init() {
+	Register("memory", func(d *DSN) (Storage, error) {
+		compress, err := d.CompressWith("default", "snappy")
+		if err != nil {
+			return nil, err
+		}
+		return NewInMemoryStorage(&Config{
+			Compress: compress,
+			TTL:      d.TTL,
+			ReadOnly: d.ReadOnly,
+		})
+	})
+}
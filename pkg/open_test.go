@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDSN(t *testing.T) {
+	d, err := ParseDSN("badger:///var/lib/colly?bucket=values&compress=true&sync=false&ttl=2d&read-only=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Scheme != "badger" || d.Path != "/var/lib/colly" || d.Bucket != "values" {
+		t.Errorf("unexpected location: %+v", d)
+	}
+	if d.Compress != "default" || d.Sync == nil || *d.Sync || d.TTL != 48*time.Hour || !d.ReadOnly {
+		t.Errorf("unexpected options: %+v", d)
+	}
+
+	d, err = ParseDSN("bbolt://./shared/colly.bbolt?compress=none")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Path != "./shared/colly.bbolt" || d.Compress != "" || d.Sync != nil {
+		t.Errorf("unexpected dsn: %+v", d)
+	}
+
+	// the codecs keep their level and window
+	for _, codec := range []string{"gzip:9", "brotli:9:18", "zstd:3"} {
+		d, err = ParseDSN("badger:///x?compress=" + codec)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", codec, err)
+		}
+		valid := func(name string) error {
+			if name != codec {
+				t.Errorf("expected %q, got %q", codec, name)
+			}
+			return nil
+		}
+		if ok, err := d.CompressCodec(valid); !ok || err != nil {
+			t.Errorf("%s: unexpected result %v (%v)", codec, ok, err)
+		}
+		if _, err := d.CompressCodec(func(string) error { return ErrNotFound }); err == nil {
+			t.Errorf("%s: expected an error", codec)
+		}
+		if _, err := d.CompressWith("default", "snappy"); err == nil {
+			t.Errorf("%s: expected an error for the in-memory codecs", codec)
+		}
+	}
+
+	for _, dsn := range []string{
+		"/var/lib/colly",
+		"badger:///tmp?unknown=1",
+		"badger:///tmp?ttl=forever",
+		"badger:///tmp?sync=maybe",
+	} {
+		if _, err := ParseDSN(dsn); err == nil {
+			t.Errorf("%q: expected an error", dsn)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	s, err := Open("memory://?compress=snappy&ttl=1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store := s.(*Store)
+	if !store.conf.Compress || store.conf.TTL != time.Hour {
+		t.Errorf("unexpected config: %+v", store.conf)
+	}
+
+	if _, err := Open("memory://?compress=gzip"); err == nil {
+		t.Error("expected an error for an unsupported codec")
+	}
+	if _, err := Open("unknown:///tmp"); err == nil {
+		t.Error("expected an error for an unknown scheme")
+	}
+}
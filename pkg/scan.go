@@ -0,0 +1,56 @@
+package storage
+
+// KV is a key/value pair returned by Scanner.ScanValues().
+type KV struct {
+	Key   string
+	Value []byte
+}
+
+// Scanner is implemented by the stores able to enumerate their keys in
+// order, one page at a time.
+//
+// Scan returns, sorted, at most limit keys starting with prefix and sorting
+// after startAfter; a limit lower or equal to zero returns all of them. The
+// returned cursor is the startAfter of the next page, and is empty once the
+// keys are exhausted.
+type Scanner interface {
+	Scan(prefix, startAfter string, limit int) (keys []string, next string, err error)
+	ScanValues(prefix, startAfter string, limit int) (kvs []KV, next string, err error)
+}
+
+// DefaultScanPageSize is the page size used by Walk when none is given.
+const DefaultScanPageSize = 1000
+
+// Walk calls fn for every key of s starting with prefix, fetching them by
+// pages of pageSize keys. It stops at the first error returned by fn.
+func Walk(s Scanner, prefix string, pageSize int, fn func(key string) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultScanPageSize
+	}
+	cursor := ""
+	for {
+		keys, next, err := s.Scan(prefix, cursor, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// ScanStart returns the key where a scan starts, the lowest key starting
+// with prefix and sorting after startAfter.
+func ScanStart(prefix, startAfter string) string {
+	if startAfter != "" && startAfter >= prefix {
+		// the smallest key sorting after startAfter
+		return startAfter + "\x00"
+	}
+	return prefix
+}
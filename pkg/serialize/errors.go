@@ -0,0 +1,10 @@
+package serialize
+
+import (
+	"errors"
+)
+
+var (
+	ErrUnknownSerializer = errors.New("unknown serializer")
+	ErrNoHeader          = errors.New("no value header")
+)
@@ -0,0 +1,21 @@
+package serialize
+
+import (
+	// external
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborSerializer serializes the values with CBOR.
+type cborSerializer struct{}
+
+func (cborSerializer) ID() SerializerID    { return SerializerCBOR }
+func (cborSerializer) Name() string        { return "cbor" }
+func (cborSerializer) ContentType() string { return "application/cbor" }
+
+func (cborSerializer) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborSerializer) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
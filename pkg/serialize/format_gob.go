@@ -0,0 +1,24 @@
+package serialize
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// gobSerializer serializes the values with encoding/gob. Each value holds
+// the description of its type, the interface values need gob.Register.
+type gobSerializer struct{}
+
+func (gobSerializer) ID() SerializerID    { return SerializerGob }
+func (gobSerializer) Name() string        { return "gob" }
+func (gobSerializer) ContentType() string { return "application/x-gob" }
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,20 @@
+package serialize
+
+import (
+	"encoding/json"
+)
+
+// jsonSerializer serializes the values with encoding/json.
+type jsonSerializer struct{}
+
+func (jsonSerializer) ID() SerializerID    { return SerializerJSON }
+func (jsonSerializer) Name() string        { return "json" }
+func (jsonSerializer) ContentType() string { return "application/json" }
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,29 @@
+package serialize
+
+import (
+	"bytes"
+
+	// external
+	"github.com/hashicorp/go-msgpack/codec"
+)
+
+// msgpackHandle is shared by the encoders and decoders, it is not modified
+// after its creation. The raw bytes decode to strings in interface{}s.
+var msgpackHandle = &codec.MsgpackHandle{RawToString: true}
+
+// msgpackSerializer serializes the values with MessagePack.
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) ID() SerializerID    { return SerializerMsgpack }
+func (msgpackSerializer) Name() string        { return "msgpack" }
+func (msgpackSerializer) ContentType() string { return "application/msgpack" }
+
+func (msgpackSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf, msgpackHandle).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (msgpackSerializer) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(v)
+}
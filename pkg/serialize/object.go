@@ -0,0 +1,59 @@
+package serialize
+
+import (
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// DefaultSerializer is the serializer of the object stores created without
+// one.
+const DefaultSerializer = "msgpack"
+
+// ObjectStore stores structured values in any store. The values are
+// serialized, prefixed with the header of their serializer and given to the
+// store, which compresses them as the other values: the content type of the
+// serializer is the hint of its compression policy.
+type ObjectStore struct {
+	storage.Storage
+	serializer Serializer
+}
+
+// NewObjectStore returns an object store writing to s with the serializer
+// registered under name, DefaultSerializer if empty. The values are read
+// whatever their serializer.
+func NewObjectStore(s storage.Storage, name string) (*ObjectStore, error) {
+	if name == "" {
+		name = DefaultSerializer
+	}
+	serializer, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectStore{Storage: s, serializer: serializer}, nil
+}
+
+// Serializer returns the serializer the values are written with.
+func (o *ObjectStore) Serializer() Serializer {
+	return o.serializer
+}
+
+// PutObject serializes v and stores it at key.
+func (o *ObjectStore) PutObject(key string, v interface{}) error {
+	data, err := Marshal(o.serializer, v)
+	if err != nil {
+		return err
+	}
+	return storage.SetTyped(o.Storage, key, data, o.serializer.ContentType())
+}
+
+// GetObject deserializes the value stored at key into the value pointed to
+// by v. It returns storage.ErrNotFound if there is no value at key. The
+// values without a header, stored by Set, are deserialized with the
+// serializer of the store.
+func (o *ObjectStore) GetObject(key string, v interface{}) error {
+	data, ok := o.Storage.Get(key)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	return Unmarshal(data, v, o.serializer)
+}
@@ -0,0 +1,146 @@
+package serialize
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SerializerID identifies a serializer in the value headers. The IDs are
+// stored with the values and must never be reused for another serializer.
+type SerializerID byte
+
+const (
+	SerializerMsgpack SerializerID = iota + 1 // MessagePack
+	SerializerJSON                            // JSON
+	SerializerCBOR                            // CBOR, RFC 7049
+	SerializerGob                             // encoding/gob, Go only
+)
+
+// Serializer marshals the objects stored with PutObject.
+type Serializer interface {
+	// ID identifies the serializer in the value headers.
+	ID() SerializerID
+	// Name is the name the serializer is looked up with, e.g. "json".
+	Name() string
+	// ContentType is the media type of the serialized values, the hint
+	// given to the compression policy of the stores.
+	ContentType() string
+	// Marshal returns the serialized form of v.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal stores the value serialized in data in the value pointed
+	// to by v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	serializersMu sync.RWMutex
+	serializers   = make(map[string]Serializer)
+	serializerIDs = make(map[SerializerID]Serializer)
+)
+
+// Register makes a serializer available to Lookup and Unmarshal. It panics
+// if the name or the ID of the serializer is already registered.
+func Register(s Serializer) {
+	serializersMu.Lock()
+	defer serializersMu.Unlock()
+	if _, dup := serializers[s.Name()]; dup {
+		panic("serialize: Register called twice for serializer " + s.Name())
+	}
+	if _, dup := serializerIDs[s.ID()]; dup {
+		panic(fmt.Sprintf("serialize: Register called twice for serializer ID %d", s.ID()))
+	}
+	serializers[s.Name()] = s
+	serializerIDs[s.ID()] = s
+}
+
+// Lookup returns the serializer registered under name.
+func Lookup(name string) (Serializer, error) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	if s, ok := serializers[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownSerializer, name)
+}
+
+// LookupID returns the serializer registered with id.
+func LookupID(id SerializerID) (Serializer, error) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	if s, ok := serializerIDs[id]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w %d", ErrUnknownSerializer, id)
+}
+
+// Serializers returns the sorted names of the registered serializers.
+func Serializers() []string {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	names := make([]string, 0, len(serializers))
+	for name := range serializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// headerMagic starts the value headers, it is followed by the serializer
+// ID. The stores compress the values after it, with their own header.
+var headerMagic = []byte("\x00SZ")
+
+// HasHeader reports whether data starts with a value header.
+func HasHeader(data []byte) bool {
+	return bytes.HasPrefix(data, headerMagic) && len(data) > len(headerMagic)
+}
+
+// ReadHeader returns the serializer ID of data and the serialized value
+// following it, which shares the memory of data.
+func ReadHeader(data []byte) (SerializerID, []byte, error) {
+	if !HasHeader(data) {
+		return 0, nil, ErrNoHeader
+	}
+	return SerializerID(data[len(headerMagic)]), data[len(headerMagic)+1:], nil
+}
+
+// Marshal serializes v with s and prefixes it with its header.
+func Marshal(s Serializer, v interface{}) ([]byte, error) {
+	data, err := s.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(headerMagic)+1+len(data))
+	buf = append(buf, headerMagic...)
+	buf = append(buf, byte(s.ID()))
+	return append(buf, data...), nil
+}
+
+// Unmarshal deserializes a value written by Marshal into v, whatever its
+// serializer. The values without a header are deserialized with legacy, an
+// error is returned if it is nil.
+func Unmarshal(data []byte, v interface{}, legacy Serializer) error {
+	if !HasHeader(data) {
+		if legacy == nil {
+			return ErrNoHeader
+		}
+		return legacy.Unmarshal(data, v)
+	}
+	id, data, err := ReadHeader(data)
+	if err != nil {
+		return err
+	}
+	s, err := LookupID(id)
+	if err != nil {
+		return err
+	}
+	return s.Unmarshal(data, v)
+}
+
+func init() {
+	Register(msgpackSerializer{})
+	Register(jsonSerializer{})
+	Register(cborSerializer{})
+	Register(gobSerializer{})
+}
@@ -0,0 +1,94 @@
+package serialize
+
+import (
+	"reflect"
+	"testing"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+type page struct {
+	URL    string
+	Status int
+	Tags   []string
+	Meta   map[string]string
+}
+
+var testPage = page{
+	URL:    "http://example.com/",
+	Status: 200,
+	Tags:   []string{"home", "index"},
+	Meta:   map[string]string{"title": "Example"},
+}
+
+func TestSerializers(t *testing.T) {
+	names := Serializers()
+	if len(names) != 4 {
+		t.Errorf("expected 4 serializers, got %v", names)
+	}
+	for _, name := range names {
+		s, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		data, err := Marshal(s, testPage)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if id, _, err := ReadHeader(data); err != nil || id != s.ID() {
+			t.Errorf("%s: unexpected header %d (%v)", name, id, err)
+		}
+		var p page
+		if err := Unmarshal(data, &p, nil); err != nil || !reflect.DeepEqual(p, testPage) {
+			t.Errorf("%s: expected %+v, got %+v (%v)", name, testPage, p, err)
+		}
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Error("expected an error for an unknown serializer")
+	}
+	var p page
+	if err := Unmarshal([]byte("\x00SZ\xc8{}"), &p, nil); err == nil {
+		t.Error("expected an error for an unknown serializer ID")
+	}
+	if err := Unmarshal([]byte(`{"URL": "legacy"}`), &p, nil); err != ErrNoHeader {
+		t.Errorf("expected %s, got %v", ErrNoHeader, err)
+	}
+}
+
+func TestObjectStore(t *testing.T) {
+	s, _ := storage.NewInMemoryStorage(nil)
+	cbor, err := NewObjectStore(s, "cbor")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := cbor.PutObject("page", testPage); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// read whatever the serializer of the store
+	objects, err := NewObjectStore(s, "")
+	if err != nil || objects.Serializer().Name() != DefaultSerializer {
+		t.Fatalf("unexpected serializer %v (%v)", objects.Serializer(), err)
+	}
+	var p page
+	if err := objects.GetObject("page", &p); err != nil || !reflect.DeepEqual(p, testPage) {
+		t.Errorf("expected %+v, got %+v (%v)", testPage, p, err)
+	}
+	if err := objects.GetObject("missing", &p); err != storage.ErrNotFound {
+		t.Errorf("expected %s, got %v", storage.ErrNotFound, err)
+	}
+
+	// the values stored by Set are read with the serializer of the store
+	json, _ := NewObjectStore(s, "json")
+	json.Set("legacy", []byte(`{"URL": "legacy"}`))
+	p = page{}
+	if err := json.GetObject("legacy", &p); err != nil || p.URL != "legacy" {
+		t.Errorf("unexpected legacy value %+v (%v)", p, err)
+	}
+
+	if _, err := NewObjectStore(s, "unknown"); err == nil {
+		t.Error("expected an error for an unknown serializer")
+	}
+}
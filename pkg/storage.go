@@ -14,6 +14,11 @@
 
 package storage
 
+import (
+	"net/http"
+	"net/url"
+)
+
 // Storage is an interface which handles...
 type Storage interface {
 	// Init initializes the storage instance
@@ -49,19 +54,32 @@ type Store interface {
 }
 */
 
-// Visited receives and stores a request ID that is visited by the Collector
-// Visited(requestID uint64) error
-// IsVisited returns true if the request was visited before IsVisited
-// is called
-// IsVisited(requestID uint64) (bool, error)
-// Cookies retrieves stored cookies for a given host
-// Cookies(u *url.URL) string
-// SetCookies stores cookies for a given host
-// SetCookies(u *url.URL, cookies string)
+// CollectorStorage is the interface used by a colly Collector to persist its
+// visited requests and cookies, so a crawl can resume between runs.
+// It matches the colly storage.Storage interface, any implementation can be
+// given to Collector.SetStorage.
+type CollectorStorage interface {
+	// Init initializes the storage
+	Init() error
+	// Visited receives and stores a request ID that is visited by the Collector
+	Visited(requestID uint64) error
+	// IsVisited returns true if the request was visited before IsVisited
+	// is called
+	IsVisited(requestID uint64) (bool, error)
+	// Cookies retrieves stored cookies for a given host
+	Cookies(u *url.URL) string
+	// SetCookies stores cookies for a given host
+	SetCookies(u *url.URL, cookies string)
+}
 
-// Get returns the stored entry if present, and nil otherwise.
+// Get returns the stored entry of the GET request to the query URL if
+// present, and an empty slice otherwise.
 func Get(s Storage, query string) (output []byte, err error) {
-	outputStr, ok := s.Get(storageQuery(query))
+	key, err := storageQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	outputStr, ok := s.Get(key)
 	if !ok {
 		return []byte{}, nil
 	}
@@ -70,7 +88,43 @@ func Get(s Storage, query string) (output []byte, err error) {
 	// return http.ReadResponse(bufio.NewReader(b), req)
 }
 
-// storageQuery returns the entry for the query.
-func storageQuery(query string) string {
-	return ""
+// Set stores an entry for the GET request to the query URL.
+func Set(s Storage, query string, value []byte) error {
+	key, err := storageQuery(query)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, value)
+}
+
+// GetRequest returns the stored entry of a request if present, and an empty
+// slice otherwise.
+func GetRequest(s Storage, req *Request) ([]byte, error) {
+	key, err := RequestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	output, ok := s.Get(key)
+	if !ok {
+		return []byte{}, nil
+	}
+	return output, nil
+}
+
+// SetRequest stores an entry for a request.
+func SetRequest(s Storage, req *Request, value []byte) error {
+	key, err := RequestKey(req)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, value)
+}
+
+// storageQuery returns the key of the GET request to the query URL.
+func storageQuery(query string) (string, error) {
+	u, err := url.Parse(query)
+	if err != nil {
+		return "", err
+	}
+	return RequestKey(&Request{Method: http.MethodGet, URL: u})
 }
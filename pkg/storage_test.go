@@ -1 +1,159 @@
 package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapStorage is a minimal v1 Storage used to exercise the adapters.
+type mapStorage struct {
+	mu     sync.Mutex
+	data   map[string][]byte
+	delay  time.Duration
+	closed bool
+}
+
+func newMapStorage() *mapStorage {
+	return &mapStorage{data: make(map[string][]byte)}
+}
+
+func (m *mapStorage) Init() error { return nil }
+
+func (m *mapStorage) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[key]
+	return v, ok
+}
+
+func (m *mapStorage) Set(key string, resp []byte) error {
+	time.Sleep(m.delay)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = resp
+	return nil
+}
+
+func (m *mapStorage) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mapStorage) Debug(action string) error { return nil }
+
+func (m *mapStorage) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = make(map[string][]byte)
+	return nil
+}
+
+func (m *mapStorage) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	return nil, nil
+}
+
+func (m *mapStorage) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestUpgradeGetSet(t *testing.T) {
+	ctx := context.Background()
+	s := Upgrade(newMapStorage(), nil)
+
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Set(ctx, "hello", []byte("world")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v, err := s.Get(ctx, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(v) != "world" {
+		t.Errorf("expected world, got %q", v)
+	}
+	if err := s.Delete(ctx, "hello"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := s.Get(ctx, "hello"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestUpgradeLimits(t *testing.T) {
+	ctx := context.Background()
+	ro := Upgrade(newMapStorage(), &V2Config{ReadOnly: true})
+	if err := ro.Set(ctx, "k", []byte("v")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if err := ro.Delete(ctx, "k"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+
+	small := Upgrade(newMapStorage(), &V2Config{MaxValueSize: 4})
+	if err := small.Set(ctx, "k", []byte("12345")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+	if err := small.Set(ctx, "k", []byte("1234")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestUpgradeClose(t *testing.T) {
+	ctx := context.Background()
+	m := newMapStorage()
+	s := Upgrade(m, nil)
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !m.closed {
+		t.Error("expected the wrapped store to be closed")
+	}
+	if _, err := s.Get(ctx, "k"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := s.Close(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed on second close, got %v", err)
+	}
+}
+
+func TestUpgradeContext(t *testing.T) {
+	m := newMapStorage()
+	m.delay = 200 * time.Millisecond
+	s := Upgrade(m, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Set(ctx, "slow", []byte("write")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Get(canceled, "slow"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	m := newMapStorage()
+	v2 := Upgrade(m, nil)
+	if Downgrade(v2) != Storage(m) {
+		t.Error("expected Downgrade to unwrap an upgraded store")
+	}
+
+	v1 := Downgrade(Upgrade(m, &V2Config{MaxValueSize: 1}))
+	if err := v1.Set("k", []byte("too large")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge through the v1 adapter, got %v", err)
+	}
+	if _, ok := v1.Get("k"); ok {
+		t.Error("expected the entry to be missing")
+	}
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// StorageV2 is the context-aware version of Storage. Every call takes a
+// context so a crawl can cancel or time out a slow backend, and failures are
+// reported with the sentinel errors of this package (ErrNotFound, ErrReadOnly,
+// ErrClosed, ErrTooLarge) instead of a boolean.
+type StorageV2 interface {
+	// Init initializes the storage instance
+	Init(ctx context.Context) error
+	// Get returns the []byte representation of an entry, or ErrNotFound if the key is missing
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the []byte representation of an entry against a key
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete removes the entry value associated with the key
+	Delete(ctx context.Context, key string) error
+	// Clear truncates all the entries of the store
+	Clear(ctx context.Context) error
+	// Close releases the store, any later call returns ErrClosed
+	Close(ctx context.Context) error
+}
+
+// V2Config holds the limits enforced by the adapter returned by Upgrade.
+type V2Config struct {
+	// ReadOnly rejects Set, Delete and Clear with ErrReadOnly.
+	ReadOnly bool
+	// MaxValueSize rejects values bigger than this many bytes with ErrTooLarge.
+	// Zero means unlimited.
+	MaxValueSize int
+}
+
+// Upgrade wraps a v1 Storage into a StorageV2, so the existing backends
+// (badger, boltdb, bbolt, pivot...) can be used by code migrated to the
+// context-aware API.
+//
+// The v1 methods can't be interrupted: when the context is done before the
+// backend returns, the adapter gives the control back to the caller with
+// ctx.Err() and lets the pending call finish in the background.
+func Upgrade(s Storage, config *V2Config) StorageV2 {
+	if d, ok := s.(*v1Adapter); ok && config == nil {
+		return d.s
+	}
+	a := &v2Adapter{s: s}
+	if config != nil {
+		a.conf = *config
+	}
+	return a
+}
+
+// Downgrade wraps a StorageV2 into a v1 Storage, for the code which still
+// relies on the boolean Get. Calls are made with context.Background().
+func Downgrade(s StorageV2) Storage {
+	if u, ok := s.(*v2Adapter); ok && u.conf == (V2Config{}) {
+		return u.s
+	}
+	return &v1Adapter{s: s}
+}
+
+// v2Adapter implements StorageV2 on top of a v1 Storage.
+type v2Adapter struct {
+	s      Storage
+	conf   V2Config
+	closed int32
+}
+
+// run calls fn unless the adapter is closed or the context is done, and
+// returns early if the context is done while fn is running.
+func (a *v2Adapter) run(ctx context.Context, fn func() error) error {
+	if atomic.LoadInt32(&a.closed) == 1 {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *v2Adapter) Init(ctx context.Context) error {
+	return a.run(ctx, a.s.Init)
+}
+
+func (a *v2Adapter) Get(ctx context.Context, key string) ([]byte, error) {
+	var resp []byte
+	err := a.run(ctx, func() error {
+		var ok bool
+		resp, ok = a.s.Get(key)
+		if !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (a *v2Adapter) Set(ctx context.Context, key string, value []byte) error {
+	if a.conf.ReadOnly {
+		return ErrReadOnly
+	}
+	if a.conf.MaxValueSize > 0 && len(value) > a.conf.MaxValueSize {
+		return ErrTooLarge
+	}
+	return a.run(ctx, func() error {
+		return a.s.Set(key, value)
+	})
+}
+
+func (a *v2Adapter) Delete(ctx context.Context, key string) error {
+	if a.conf.ReadOnly {
+		return ErrReadOnly
+	}
+	return a.run(ctx, func() error {
+		return a.s.Delete(key)
+	})
+}
+
+func (a *v2Adapter) Clear(ctx context.Context) error {
+	if a.conf.ReadOnly {
+		return ErrReadOnly
+	}
+	return a.run(ctx, a.s.Clear)
+}
+
+// Close marks the adapter as closed and closes the wrapped store if it
+// implements io.Closer.
+func (a *v2Adapter) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&a.closed, 0, 1) {
+		return ErrClosed
+	}
+	c, ok := a.s.(io.Closer)
+	if !ok {
+		return nil
+	}
+	return c.Close()
+}
+
+// v1Adapter implements Storage on top of a StorageV2.
+type v1Adapter struct {
+	s StorageV2
+}
+
+func (a *v1Adapter) Init() error {
+	return a.s.Init(context.Background())
+}
+
+func (a *v1Adapter) Get(key string) (resp []byte, ok bool) {
+	resp, err := a.s.Get(context.Background(), key)
+	return resp, err == nil
+}
+
+func (a *v1Adapter) Set(key string, resp []byte) error {
+	return a.s.Set(context.Background(), key, resp)
+}
+
+func (a *v1Adapter) Delete(key string) error {
+	return a.s.Delete(context.Background(), key)
+}
+
+func (a *v1Adapter) Debug(action string) error {
+	return errors.New("Debug() method is not supported by StorageV2 stores")
+}
+
+func (a *v1Adapter) Clear() error {
+	return a.s.Clear(context.Background())
+}
+
+func (a *v1Adapter) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	return nil, errors.New("Action() method is not supported by StorageV2 stores")
+}
+
+// Close closes the wrapped StorageV2.
+func (a *v1Adapter) Close() error {
+	return a.s.Close(context.Background())
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// Streamer is implemented by the stores compressing the values while they
+// are streamed, instead of copying them whole in memory.
+type Streamer interface {
+	// SetReader stores the content of r at key, compressed while it is
+	// read.
+	SetReader(key string, r io.Reader) error
+	// GetReader returns a reader decompressing the value stored at key, or
+	// ErrNotFound. The reader must be closed.
+	GetReader(key string) (io.ReadCloser, error)
+}
+
+// SetReader stores the content of r at key, streamed if s is a Streamer or
+// read whole otherwise.
+func SetReader(s Storage, key string, r io.Reader) error {
+	if streamer, ok := s.(Streamer); ok {
+		return streamer.SetReader(key, r)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, data)
+}
+
+// GetReader returns a reader of the value stored at key, streamed if s is a
+// Streamer, or ErrNotFound.
+func GetReader(s Storage, key string) (io.ReadCloser, error) {
+	if streamer, ok := s.(Streamer); ok {
+		return streamer.GetReader(key)
+	}
+	data, ok := s.Get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
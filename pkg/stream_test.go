@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestStreamFallback(t *testing.T) {
+	s, _ := NewInMemoryStorage(&Config{Compress: true})
+	if err := SetReader(s, "body", strings.NewReader("large body")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r, err := GetReader(s, "body")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer r.Close()
+	if data, _ := ioutil.ReadAll(r); string(data) != "large body" {
+		t.Errorf("expected large body, got %q", data)
+	}
+	if _, err := GetReader(s, "missing"); err != ErrNotFound {
+		t.Errorf("expected %s, got %v", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"time"
+)
+
+// DefaultSweepInterval is the interval between two sweeps of the expired
+// entries, for the stores not relying on their engine to expire them.
+const DefaultSweepInterval = time.Minute
+
+// Expirer is implemented by the stores supporting entries with a time to
+// live. An expired entry reads as not found, even if it has not been swept
+// yet.
+type Expirer interface {
+	// SetWithTTL stores a value expiring after ttl, a ttl lower or equal to
+	// zero stores it without expiry.
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+}
+
+// Deadline returns the expiry time of an entry written now with ttl, the
+// zero time when it never expires.
+func Deadline(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// TypedExpirer is implemented by the stores taking both the content type and
+// the time to live of a value, see TypedSetter and Expirer.
+type TypedExpirer interface {
+	// SetTypedWithTTL stores a value expiring after ttl, a ttl lower or
+	// equal to zero stores it without expiry. contentType is a hint of its
+	// media type, as for SetTyped.
+	SetTypedWithTTL(key string, value []byte, contentType string, ttl time.Duration) error
+}
+
+// SetPersistent stores value at key without expiry, whatever the default
+// TTL of s: with SetTypedWithTTL if s is a TypedExpirer, SetWithTTL if it is
+// an Expirer, SetTyped otherwise.
+func SetPersistent(s Storage, key string, value []byte, contentType string) error {
+	switch e := s.(type) {
+	case TypedExpirer:
+		return e.SetTypedWithTTL(key, value, contentType, 0)
+	case Expirer:
+		return e.SetWithTTL(key, value, 0)
+	}
+	return SetTyped(s, key, value, contentType)
+}
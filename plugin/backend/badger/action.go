@@ -0,0 +1,56 @@
+package badgerstorage
+
+import (
+	"reflect"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Actioner = (*Store)(nil)
+
+// Action runs one of the actions listed by Actions(). getKeys keeps its
+// legacy result, see storage.ActionKeys.
+func (s *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	if name == "getKeys" {
+		keys, err := s.Do(name, args...)
+		if err != nil {
+			return nil, err
+		}
+		return storage.ActionKeys(keys.([]string)), nil
+	}
+	return s.registry().Action(name, args...)
+}
+
+// Actions implements storage.Actioner.Actions()
+func (s *Store) Actions() []storage.ActionSpec {
+	return s.registry().Actions()
+}
+
+// Do implements storage.Actioner.Do()
+func (s *Store) Do(name string, args ...interface{}) (interface{}, error) {
+	return s.registry().Do(name, args...)
+}
+
+// registry returns the actions of the store, registered on first use as
+// stores can be built by Mount.
+func (s *Store) registry() *storage.ActionRegistry {
+	s.actionsOnce.Do(func() {
+		s.actions = storage.NewActionRegistry()
+		s.actions.MustRegister(storage.ActionSpec{
+			Name:        "getKeys",
+			Description: "lists the stored keys, in badger order",
+			Result:      reflect.TypeOf([]string(nil)),
+		}, func(args ...interface{}) (interface{}, error) {
+			return s.keys(), nil
+		})
+		s.actions.MustRegister(storage.ActionSpec{
+			Name:        "runGC",
+			Description: "purges the old versions and rewrites the stale value log files",
+			Result:      reflect.TypeOf(GCReport{}),
+		}, func(args ...interface{}) (interface{}, error) {
+			return s.RunGC()
+		})
+	})
+	return s.actions
+}
@@ -0,0 +1,177 @@
+package badgerstorage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	// external
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/protos"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+)
+
+// userMetaTombstone flags the deleted keys in the backups. badger's stream
+// has no room for the deletes, they are written as empty values with this
+// user meta and deleted again by Restore.
+const userMetaTombstone byte = 1 << 7
+
+// Backup writes the versions of the entries committed since a version to
+// w, all of them if since is zero, and returns the version to give as since
+// to the next incremental backup. The stream is the one of badger's
+// DB.Backup, with the deletes: the values are written as they are stored,
+// with their envelope and codec header, and the expired entries are
+// written as deletes.
+func (s *Store) Backup(w io.Writer, since uint64) (uint64, error) {
+	// the buffered reads are part of the backup
+	s.mu.Lock()
+	err := s.flushHits()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriterSize(w, 64<<10)
+	var version uint64
+	err = s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.AllVersions = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		var last []byte
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			// the last version of a key comes first
+			latest := !bytes.Equal(item.Key(), last)
+			if latest {
+				last = item.KeyCopy(nil)
+			}
+			// the versions of the entries are their commit timestamps
+			if v := item.Version(); v < since {
+				continue
+			} else if v >= version {
+				version = v + 1
+			}
+			dead := item.IsDeletedOrExpired()
+			if dead && !latest {
+				// only the last version tells whether the key is live
+				continue
+			}
+			kv := &protos.KVPair{
+				Key:       item.KeyCopy(nil),
+				UserMeta:  []byte{item.UserMeta()},
+				Version:   item.Version(),
+				ExpiresAt: item.ExpiresAt(),
+			}
+			if dead {
+				// a delete or an expiry, the older versions of the key are
+				// deleted again by Restore
+				kv.UserMeta[0] |= userMetaTombstone
+				kv.ExpiresAt = 0
+			} else {
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				kv.Value = value
+			}
+			if err := writeKVPair(bw, kv); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if version < since {
+		// nothing committed since
+		version = since
+	}
+	return version, bw.Flush()
+}
+
+// writeKVPair writes kv as badger's DB.Backup does, prefixed with its size.
+func writeKVPair(w io.Writer, kv *protos.KVPair) error {
+	if err := binary.Write(w, binary.LittleEndian, uint64(kv.Size())); err != nil {
+		return err
+	}
+	buf, err := kv.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// Restore loads a backup written by Backup, or by badger's DB.Backup. The
+// full backup is restored first, then the incremental ones in order. The
+// store must not be used during the restore.
+func (s *Store) Restore(r io.Reader) error {
+	if s.readOnly {
+		return storage.ErrReadOnly
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.db.Load(r); err != nil {
+		return err
+	}
+	if err := s.deleteTombstones(); err != nil {
+		return err
+	}
+	if s.values.Dicts != nil {
+		// the dictionaries of the backup
+		dicts, err := compress.NewDicts(s)
+		if err != nil {
+			return err
+		}
+		prev := s.values.Dicts
+		s.values.Dicts = dicts
+		return prev.Close()
+	}
+	return nil
+}
+
+// deleteTombstones deletes the keys whose last version is a delete loaded
+// from a backup.
+func (s *Store) deleteTombstones() error {
+	var keys [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if item := it.Item(); item.UserMeta()&userMetaTombstone != 0 {
+				keys = append(keys, item.KeyCopy(nil))
+			}
+		}
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	txn := s.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	for _, key := range keys {
+		err := txn.Delete(key)
+		if err == badger.ErrTxnTooBig {
+			if err = txn.Commit(nil); err != nil {
+				return err
+			}
+			txn = s.db.NewTransaction(true)
+			err = txn.Delete(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit(nil)
+}
@@ -0,0 +1,152 @@
+package badgerstorage
+
+import (
+	"time"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Batcher = (*Store)(nil)
+
+// WriteBatch implements storage.Batcher.WriteBatch(). The batch is applied
+// in a single transaction, split into several ones when it gets too big for
+// badger: the operations fitting in the first one are committed, without
+// the partial writes of the one which didn't fit. The transactions committed before an error are kept, their keys
+// are not reported in the returned storage.BatchError.
+func (s *Store) WriteBatch(b *storage.Batch) error {
+	values := make([][]byte, len(b.Ops))
+	for i, op := range b.Ops {
+		if op.Delete {
+			continue
+		}
+		value, err := s.values.Encode(op.Key, op.Value, "")
+		if err != nil {
+			return storage.AbortBatch(b, map[string]error{op.Key: err})
+		}
+		values[i] = value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txn := s.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+
+	// the operations from start on belong to the pending transaction
+	start := 0
+	errs := make(map[string]error)
+	for i, op := range b.Ops {
+		err := s.applyOp(txn, op, values[i])
+		if err == badger.ErrTxnTooBig {
+			if len(errs) > 0 {
+				// the pending transaction is rolled back anyway
+				break
+			}
+			// op may be partly written, e.g. its read counter folded and
+			// deleted: the pending transaction is replayed without it
+			txn.Discard()
+			txn = s.db.NewTransaction(true)
+			if err := s.applyOps(txn, b.Ops[start:i], values[start:i]); err != nil {
+				return abortOps(b.Ops[start:], b.Ops[start:i], err)
+			}
+			if err := txn.Commit(nil); err != nil {
+				return abortOps(b.Ops[start:], b.Ops[start:i], err)
+			}
+			start = i
+			txn = s.db.NewTransaction(true)
+			err = s.applyOp(txn, op, values[i])
+		}
+		if err != nil {
+			errs[op.Key] = err
+		}
+	}
+	if len(errs) > 0 {
+		return storage.AbortBatch(&storage.Batch{Ops: b.Ops[start:]}, errs)
+	}
+	if err := txn.Commit(nil); err != nil {
+		return abortOps(b.Ops[start:], b.Ops[start:], err)
+	}
+	return nil
+}
+
+// SetMany implements storage.Batcher.SetMany()
+func (s *Store) SetMany(kvs []storage.KV) error {
+	return s.WriteBatch(storage.SetBatch(kvs))
+}
+
+// DeleteMany implements storage.Batcher.DeleteMany()
+func (s *Store) DeleteMany(keys []string) error {
+	return s.WriteBatch(storage.DeleteBatch(keys))
+}
+
+// GetMany implements storage.Batcher.GetMany(), the values are read in a
+// single transaction.
+func (s *Store) GetMany(keys []string) (map[string][]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	values := make(map[string][]byte, len(keys))
+	errs := make(storage.BatchError)
+	now := time.Now()
+	var hits [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			value, err := s.read(txn, []byte(key))
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err == nil {
+				value, err = s.values.Decode(value)
+			}
+			if err != nil {
+				errs[key] = err
+				continue
+			}
+			values[key] = value
+			hits = append(hits, []byte(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordHits(hits, now)
+	if len(errs) > 0 {
+		return values, errs
+	}
+	return values, nil
+}
+
+// applyOp applies an operation of a batch, the values expire after the
+// default TTL of the store.
+func (s *Store) applyOp(txn *badger.Txn, op storage.BatchOp, value []byte) error {
+	if op.Delete {
+		return s.deleteEntry(txn, []byte(op.Key))
+	}
+	return s.put(txn, []byte(op.Key), value, op.TTL(s.ttl))
+}
+
+// applyOps applies operations of a batch which were applied together
+// before, in a transaction rolled back since.
+func (s *Store) applyOps(txn *badger.Txn, ops []storage.BatchOp, values [][]byte) error {
+	for i, op := range ops {
+		if err := s.applyOp(txn, op, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// abortOps returns the storage.BatchError of the rolled back operations
+// when failed ones failed with err.
+func abortOps(rolledBack, failed []storage.BatchOp, err error) error {
+	errs := make(map[string]error, len(failed))
+	for _, op := range failed {
+		errs[op.Key] = err
+	}
+	return storage.AbortBatch(&storage.Batch{Ops: rolledBack}, errs)
+}
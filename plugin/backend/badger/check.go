@@ -0,0 +1,122 @@
+package badgerstorage
+
+import (
+	"time"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// Check is the metadata kept with every entry, see Stat.
+type Check = storage.Check
+
+var (
+	_ storage.Stater      = (*Store)(nil)
+	_ storage.CheckSetter = (*Store)(nil)
+	_ storage.Peeker      = (*Store)(nil)
+)
+
+// Stat implements storage.Stater.Stat(), it doesn't count as a read. The
+// requests are only counted when Config.Stats is enabled, see recordHits.
+func (s *Store) Stat(key string) (check *Check, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err == badger.ErrKeyNotFound {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		v, err := item.Value()
+		if err != nil {
+			return err
+		}
+		if check, err = storage.StatEnvelope(key, v); err != nil {
+			return err
+		}
+		return addHits(txn, []byte(key), check)
+	})
+	if err == nil {
+		s.statHits(key, check)
+	}
+	return check, err
+}
+
+// SetWithCheck implements storage.CheckSetter.SetWithCheck(), the value is
+// encoded as by Set and its reads counted so far are replaced by the ones of
+// c.
+func (s *Store) SetWithCheck(key string, resp []byte, c *Check) error {
+	var ttl time.Duration
+	if !c.ExpiredAt.IsZero() {
+		if ttl = time.Until(c.ExpiredAt); ttl <= 0 {
+			return nil
+		}
+	}
+	resp, err := s.values.Encode(key, resp, "")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		s.dropHits([]byte(key))
+		if err := clearHits(txn, []byte(key)); err != nil {
+			return err
+		}
+		return setWithTTL(txn, []byte(key), storage.RestoreEnvelope(key, resp, c), ttl)
+	})
+}
+
+// put writes a value in its envelope, expiring after ttl if positive.
+func (s *Store) put(txn *badger.Txn, key, value []byte, ttl time.Duration) error {
+	var prev []byte
+	item, err := txn.Get(key)
+	switch err {
+	case nil:
+		if prev, err = item.Value(); err != nil {
+			return err
+		}
+		if prev, err = foldHits(txn, key, prev); err != nil {
+			return err
+		}
+	case badger.ErrKeyNotFound:
+		// the reads of a deleted or expired entry
+		s.dropHits(key)
+		if err := clearHits(txn, key); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	now := time.Now()
+	sealed := storage.SealEnvelope(prev, string(key), value, now, storage.Deadline(now, ttl), s.provider)
+	return setWithTTL(txn, key, sealed, ttl)
+}
+
+// read returns a copy of the payload stored at key, still compressed. The
+// reads are recorded apart by recordHits.
+func (s *Store) read(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := item.Value()
+	if err != nil {
+		return nil, err
+	}
+	_, payload, err := storage.OpenEnvelope(v)
+	if err != nil {
+		return nil, err
+	}
+	// the value is only valid during the transaction
+	resp := make([]byte, len(payload))
+	copy(resp, payload)
+	return resp, nil
+}
@@ -0,0 +1,5 @@
+package badgerstorage
+
+// DefaultCodec is the codec of the compressed stores, snappy as before the
+// value headers.
+const DefaultCodec = "snappy"
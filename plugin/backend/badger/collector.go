@@ -0,0 +1,87 @@
+package badgerstorage
+
+import (
+	"net/url"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.CollectorStorage = (*Store)(nil)
+
+// Visited implements storage.CollectorStorage.Visited()
+func (s *Store) Visited(requestID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(visitedKey(requestID), []byte{})
+	})
+}
+
+// IsVisited implements storage.CollectorStorage.IsVisited()
+func (s *Store) IsVisited(requestID uint64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(visitedKey(requestID))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Cookies implements storage.CollectorStorage.Cookies()
+func (s *Store) Cookies(u *url.URL) (cookies string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(cookiesKeyPrefix + u.Host))
+		if err != nil {
+			return err
+		}
+		v, err := item.Value()
+		if err != nil {
+			return err
+		}
+		cookies = string(v)
+		return nil
+	})
+	return
+}
+
+// SetCookies implements storage.CollectorStorage.SetCookies(), the cookies
+// are merged with the ones already stored for the host.
+func (s *Store) SetCookies(u *url.URL, cookies string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := []byte(cookiesKeyPrefix + u.Host)
+	s.db.Update(func(txn *badger.Txn) error {
+		var stored string
+		item, err := txn.Get(key)
+		switch err {
+		case nil:
+			v, err := item.Value()
+			if err != nil {
+				return err
+			}
+			stored = string(v)
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+		return txn.Set(key, []byte(storage.MergeCookies(stored, cookies)))
+	})
+}
+
+func visitedKey(requestID uint64) []byte {
+	return append([]byte(visitedKeyPrefix), uint64ToBytes(requestID)...)
+}
@@ -1,9 +1,18 @@
 package badgerstorage
 
 import (
+	"fmt"
+	"math"
+	"path/filepath"
 	"time"
 
+	// external
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/options"
 	"github.com/imdario/mergo"
+
+	// internal
+	"github.com/sniperkit/colly-storage/pkg/compress"
 )
 
 // Config is the configuration for this serializer
@@ -23,6 +32,24 @@ type Config struct {
 	// loading significantly.
 	SyncWrites bool
 
+	// Open the database read-only, the writes fail with
+	// badger.ErrReadOnlyTxn.
+	ReadOnly bool
+
+	// Profile is the name of a set of the flags below, see Profiles(). The
+	// flags set in the config override the ones of the profile, which can't
+	// set the booleans.
+	Profile string
+
+	// How the LSM tree and the value log files are accessed, one of the
+	// LoadingMode constants. The value log can't be loaded to RAM.
+	TableLoadingMode    string
+	ValueLogLoadingMode string
+
+	// Truncate the value log to delete the corrupt data left by a crash,
+	// instead of failing to open. Ignored when read-only.
+	Truncate bool
+
 	// 3. Flags that user might want to review
 	// ----------------------------------------
 	// The following affect all levels of LSM tree.
@@ -50,15 +77,33 @@ type Config struct {
 	// Size of single value log file.
 	ValueLogFileSize int64
 
+	// Maximum number of entries of a value log file. RunGC samples 1% of
+	// them before rewriting a file, less entries make it more eager.
+	ValueLogMaxEntries uint32
+
 	// Number of compaction workers to run concurrently.
 	NumCompactors int
 
+	// Number of versions of each key kept by the compactions, 1 if zero.
+	// RunGC marks the older ones of the live keys as discardable.
+	NumVersionsToKeep int
+
+	// GCInterval is the interval between two runs of RunGC in background,
+	// zero disables them.
+	GCInterval time.Duration
+
+	// GCDiscardRatio is the ratio of stale data a value log file must hold
+	// to be rewritten by RunGC, DefaultGCDiscardRatio if zero.
+	GCDiscardRatio float64
+
 	// 4. Flags for dev purposes
 	// ------------------------------
-	//
+	// UseTTL is kept for compatibility, a positive TTL is enough to expire
+	// the entries.
 	UseTTL bool
 
-	//
+	// TTL is the time to live of the entries written by Set and the
+	// batches, zero means they never expire. SetWithTTL overrides it.
 	TTL time.Duration
 
 	// 5. Flags for testing purposes
@@ -73,9 +118,30 @@ type Config struct {
 
 	// Compress sets...
 	Compress bool
+
+	// Codec is the name of the codec compressing the values, see
+	// compress.Codecs() and compress.Lookup() for the levels and windows,
+	// e.g. "brotli:9:18". It implies Compress, DefaultCodec if empty.
+	Codec string
+
+	// Policy decides the codec of each value from its content type and its
+	// size, overriding Codec. The values are sniffed unless SetTyped gives
+	// their type.
+	Policy *compress.Policy
+
+	// Stats records the reads in the metadata of the entries, see Stat.
+	// The reads are buffered and written by batches to counters apart from
+	// the values, the last ones are lost on a crash.
+	Stats bool
+
+	// Provider is the name of the crawler recorded in the metadata of the
+	// entries written.
+	Provider string
 }
 
-// DefaultConfig returns the default configuration for this serializer
+// DefaultConfig returns the default configuration for this serializer. The
+// flags of badger are left unset: New takes them from Config.Profile then
+// from badger's defaults.
 func DefaultConfig() Config {
 	return Config{
 		Sanitize:   false,
@@ -83,6 +149,29 @@ func DefaultConfig() Config {
 	}
 }
 
+// defaultOptions returns the flags of badger.DefaultOptions, New merges
+// them in the configs after the profile. The booleans are left out: false
+// can't be told from unset.
+func defaultOptions() Config {
+	o := badger.DefaultOptions
+	return Config{
+		TableLoadingMode:        LoadingModeRAM,
+		ValueLogLoadingMode:     LoadingModeMMap,
+		MaxTableSize:            o.MaxTableSize,
+		LevelSizeMultiplier:     o.LevelSizeMultiplier,
+		MaxLevels:               o.MaxLevels,
+		ValueThreshold:          o.ValueThreshold,
+		NumMemtables:            o.NumMemtables,
+		NumLevelZeroTables:      o.NumLevelZeroTables,
+		NumLevelZeroTablesStall: o.NumLevelZeroTablesStall,
+		LevelOneSize:            o.LevelOneSize,
+		ValueLogFileSize:        o.ValueLogFileSize,
+		ValueLogMaxEntries:      o.ValueLogMaxEntries,
+		NumCompactors:           o.NumCompactors,
+		NumVersionsToKeep:       o.NumVersionsToKeep,
+	}
+}
+
 // ConfigName ...
 func (Config) ConfigName() string {
 	return "BadgerKV"
@@ -107,6 +196,88 @@ func (c Config) MergeSingle(cfg Config) (config Config) {
 	return
 }
 
+// options returns the badger options of the config, its unset flags taken
+// from its profile then from badger's defaults, and the merged config.
+func (c Config) options() (badger.Options, Config, error) {
+	profile, err := Profile(c.Profile)
+	if err != nil {
+		return badger.Options{}, c, err
+	}
+	c = defaultOptions().MergeSingle(profile.MergeSingle(c))
+	if c.StoragePath == "" {
+		c.StoragePath = defaultStorePrefixPath
+	}
+	if err := c.validate(); err != nil {
+		return badger.Options{}, c, err
+	}
+
+	opts := badger.DefaultOptions
+	opts.Dir = c.StoragePath
+	opts.ValueDir = filepath.Join(c.StoragePath, c.ValueDir)
+	opts.SyncWrites = c.SyncWrites
+	opts.ReadOnly = c.ReadOnly
+	opts.Truncate = c.Truncate
+	opts.TableLoadingMode = loadingModes[c.TableLoadingMode]
+	opts.ValueLogLoadingMode = loadingModes[c.ValueLogLoadingMode]
+	opts.MaxTableSize = c.MaxTableSize
+	opts.LevelSizeMultiplier = c.LevelSizeMultiplier
+	opts.MaxLevels = c.MaxLevels
+	opts.ValueThreshold = c.ValueThreshold
+	opts.NumMemtables = c.NumMemtables
+	opts.NumLevelZeroTables = c.NumLevelZeroTables
+	opts.NumLevelZeroTablesStall = c.NumLevelZeroTablesStall
+	opts.LevelOneSize = c.LevelOneSize
+	opts.ValueLogFileSize = c.ValueLogFileSize
+	opts.ValueLogMaxEntries = c.ValueLogMaxEntries
+	opts.NumCompactors = c.NumCompactors
+	opts.NumVersionsToKeep = c.NumVersionsToKeep
+	opts.DoNotCompact = c.DoNotCompact
+	return opts, c, nil
+}
+
+// validate checks the flags of a merged config, badger panics on some of
+// them.
+func (c Config) validate() error {
+	for _, flag := range []struct {
+		name  string
+		value int64
+	}{
+		{"MaxTableSize", c.MaxTableSize},
+		{"LevelSizeMultiplier", int64(c.LevelSizeMultiplier)},
+		{"ValueThreshold", int64(c.ValueThreshold)},
+		{"NumMemtables", int64(c.NumMemtables)},
+		{"NumLevelZeroTables", int64(c.NumLevelZeroTables)},
+		{"LevelOneSize", c.LevelOneSize},
+		{"NumCompactors", int64(c.NumCompactors)},
+		{"NumVersionsToKeep", int64(c.NumVersionsToKeep)},
+		{"GCInterval", int64(c.GCInterval)},
+		{"TTL", int64(c.TTL)},
+	} {
+		if flag.value < 0 {
+			return fmt.Errorf("%w: negative %s", ErrInvalidConfig, flag.name)
+		}
+	}
+	switch {
+	case c.MaxLevels < 2:
+		return fmt.Errorf("%w: MaxLevels must be at least 2", ErrInvalidConfig)
+	case c.ValueThreshold > math.MaxUint16-16:
+		return fmt.Errorf("%w: ValueThreshold must be at most %d", ErrInvalidConfig, math.MaxUint16-16)
+	case c.NumLevelZeroTablesStall <= c.NumLevelZeroTables:
+		return fmt.Errorf("%w: NumLevelZeroTablesStall must be greater than NumLevelZeroTables", ErrInvalidConfig)
+	case c.ValueLogFileSize < 1<<20 || c.ValueLogFileSize > 2<<30:
+		return fmt.Errorf("%w: ValueLogFileSize must be between 1MB and 2GB", ErrInvalidConfig)
+	case c.GCDiscardRatio < 0 || c.GCDiscardRatio >= 1:
+		return fmt.Errorf("%w: GCDiscardRatio must be in [0, 1)", ErrInvalidConfig)
+	}
+	if _, ok := loadingModes[c.TableLoadingMode]; !ok {
+		return fmt.Errorf("%w: unknown TableLoadingMode %q", ErrInvalidConfig, c.TableLoadingMode)
+	}
+	if mode, ok := loadingModes[c.ValueLogLoadingMode]; !ok || mode == options.LoadToRAM {
+		return fmt.Errorf("%w: invalid ValueLogLoadingMode %q", ErrInvalidConfig, c.ValueLogLoadingMode)
+	}
+	return nil
+}
+
 /*
 // storageConfig is...
 type storageConfig struct {
@@ -1,5 +1,10 @@
 package badgerstorage
 
+import (
+	// internal
+	"github.com/sniperkit/colly-storage/pkg/compress"
+)
+
 const (
 
 	// storageBucketName...
@@ -12,8 +17,42 @@ const (
 )
 
 const (
-	// GzipMinSize gzip min size
-	GzipMinSize = 1024
+	// internalKeyPrefix starts every key used by the store for its own
+	// bookkeeping, they are skipped when listing the user keys.
+	internalKeyPrefix string = "\x00"
+
+	// visitedKeyPrefix...
+	visitedKeyPrefix string = internalKeyPrefix + "visited/"
+
+	// cookiesKeyPrefix...
+	cookiesKeyPrefix string = internalKeyPrefix + "cookies/"
+
+	// dictKeyPrefix starts the keys of the compression dictionaries,
+	// followed by their ID, see dict.go.
+	dictKeyPrefix string = internalKeyPrefix + "dicts/"
+
+	// hitsKeyPrefix starts the read counters of the entries, followed by
+	// their key, see hits.go.
+	hitsKeyPrefix string = internalKeyPrefix + "hits/"
+
+	// queueKeyPrefix starts the keys of the queues, followed by their name,
+	// see queue.go.
+	queueKeyPrefix string = internalKeyPrefix + "queues/"
+
+	//-- End
+)
+
+const (
+	// DefaultGCDiscardRatio is the ratio of stale data of the value log
+	// files rewritten by RunGC, badger's recommendation.
+	DefaultGCDiscardRatio = 0.5
+
+	// maxGCRewrites bounds the value log files rewritten by a run of RunGC.
+	maxGCRewrites = 100
+
+	// GzipMinSize is the size under which the compression policies store
+	// the values as they are by default, see compress.DefaultMinSize.
+	GzipMinSize = compress.DefaultMinSize
 
 	// StoreFormatRaw raw
 	StoreFormatRaw = 0
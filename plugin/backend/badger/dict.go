@@ -0,0 +1,108 @@
+package badgerstorage
+
+import (
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+)
+
+var _ compress.DictStore = (*Store)(nil)
+
+// LoadDicts implements compress.DictStore.LoadDicts(), the dictionaries are
+// kept under dictKeyPrefix.
+func (s *Store) LoadDicts() ([]compress.Dict, error) {
+	var dicts []compress.Dict
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(dictKeyPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			v, err := it.Item().Value()
+			if err != nil {
+				return err
+			}
+			var dict compress.Dict
+			if err := dict.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			dicts = append(dicts, dict)
+		}
+		return nil
+	})
+	return dicts, err
+}
+
+// SaveDict implements compress.DictStore.SaveDict()
+func (s *Store) SaveDict(dict compress.Dict) error {
+	data, err := dict.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(append([]byte(dictKeyPrefix), uint64ToBytes(uint64(dict.ID))...), data)
+	})
+}
+
+// TrainDictionary trains a new version of the zstd dictionary of the
+// values of a host, see storage.KeyNamespace, from up to samples of them
+// (compress.DefaultDictSamples if zero) picked at random. Only the keys under
+// storage.NamespacePrefixes are scanned. The next values of the host are
+// compressed with it when the codec of the store is zstd, the values written
+// with the previous versions stay readable.
+func (s *Store) TrainDictionary(namespace string, samples int) (compress.Dict, error) {
+	if s.values.Dicts == nil {
+		return compress.Dict{}, ErrNoDicts
+	}
+	return s.values.Train(namespace, samples, func(n int) ([][]byte, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		var payloads [][]byte
+		err := s.db.View(func(txn *badger.Txn) error {
+			for _, key := range sampleKeys(txn, namespace, n) {
+				item, err := txn.Get([]byte(key))
+				if err != nil {
+					return err
+				}
+				v, err := item.Value()
+				if err != nil {
+					return err
+				}
+				_, payload, err := storage.OpenEnvelope(v)
+				if err != nil {
+					return err
+				}
+				// the payloads share the memory of badger
+				payloads = append(payloads, append([]byte(nil), payload...))
+			}
+			return nil
+		})
+		return payloads, err
+	})
+}
+
+// sampleKeys returns up to n keys of namespace picked at random.
+func sampleKeys(txn *badger.Txn, namespace string, n int) []string {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	sampler := compress.KeySampler{N: n}
+	for _, prefix := range storage.NamespacePrefixes(namespace) {
+		start := []byte(prefix)
+		if prefix == "" {
+			start = []byte(userKeysStart)
+		}
+		for it.Seek(start); it.ValidForPrefix([]byte(prefix)); it.Next() {
+			if key := string(it.Item().Key()); storage.KeyNamespace(key) == namespace {
+				sampler.Offer(key)
+			}
+		}
+	}
+	return sampler.Keys
+}
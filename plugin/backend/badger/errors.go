@@ -1 +1,19 @@
 package badgerstorage
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNoDicts is returned by TrainDictionary on the mounted stores, only
+	// the ones opened by New load their dictionaries.
+	ErrNoDicts = errors.New("badgerstorage: no dictionaries on a mounted store")
+
+	// ErrInvalidConfig is returned by New when a flag of the config is out of
+	// the range badger accepts.
+	ErrInvalidConfig = errors.New("badgerstorage: invalid config")
+
+	// ErrInvalidQueueName is returned by Queue when the name is empty or
+	// holds a slash.
+	ErrInvalidQueueName = errors.New("badgerstorage: invalid queue name")
+)
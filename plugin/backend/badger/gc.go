@@ -0,0 +1,210 @@
+package badgerstorage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// GCReport is the outcome of a run of RunGC.
+type GCReport struct {
+	// Start is the time the run started at.
+	Start time.Time
+	// Duration is the duration of the run.
+	Duration time.Duration
+	// VersionsPurged is the number of old versions marked as discardable,
+	// dropped by the next compactions.
+	VersionsPurged int
+	// Rewrites is the number of value log files rewritten.
+	Rewrites int
+	// ReclaimedBytes is the decrease of the size of the value log. It is
+	// mostly zero on the mounted stores: badger refreshes the size it
+	// reports once a minute, their value directory is unknown.
+	ReclaimedBytes int64
+	// Err is the error the run failed with, for the background runs.
+	Err error
+}
+
+// RunGC purges the old versions of the keys and rewrites the value log
+// files holding more than Config.GCDiscardRatio of stale data, until none
+// is left. The background runs started by Config.GCInterval call it too,
+// see LastGC. The mounted stores use DefaultGCDiscardRatio.
+func (s *Store) RunGC() (GCReport, error) {
+	if s.readOnly {
+		return GCReport{}, storage.ErrReadOnly
+	}
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	ratio := s.gcDiscardRatio
+	if ratio == 0 {
+		ratio = DefaultGCDiscardRatio
+	}
+	report := GCReport{Start: time.Now()}
+	before := s.valueLogSize()
+	report.VersionsPurged, report.Err = s.purgeVersions()
+	for report.Err == nil && report.Rewrites < maxGCRewrites {
+		if report.Err = s.db.RunValueLogGC(ratio); report.Err == nil {
+			report.Rewrites++
+		}
+	}
+	if report.Err == badger.ErrNoRewrite {
+		report.Err = nil
+	}
+	report.ReclaimedBytes = before - s.valueLogSize()
+	report.Duration = time.Since(report.Start)
+	s.lastGC = report
+	return report, report.Err
+}
+
+// LastGC returns the report of the last run of RunGC, the zero report if
+// it never ran.
+func (s *Store) LastGC() GCReport {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+	return s.lastGC
+}
+
+// purgeVersions marks the older versions of the live keys as discardable
+// by rewriting their last version, and returns their number. The
+// compactions drop them on their own when a single version is kept. The
+// entries with a TTL are skipped, badger can't mark them: they are dropped
+// once expired.
+func (s *Store) purgeVersions() (int, error) {
+	if s.numVersions <= 1 {
+		return 0, nil
+	}
+
+	var keys [][]byte
+	purged := 0
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.AllVersions = true
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		var last []byte
+		skip := false
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if !bytes.Equal(item.Key(), last) {
+				// the last version of the key comes first
+				last = item.KeyCopy(nil)
+				skip = item.IsDeletedOrExpired() || item.ExpiresAt() > 0 || item.DiscardEarlierVersions()
+				continue
+			}
+			if skip {
+				continue
+			}
+			if len(keys) == 0 || !bytes.Equal(keys[len(keys)-1], last) {
+				keys = append(keys, last)
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txn := s.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	for _, key := range keys {
+		err := discardEarlierVersions(txn, key)
+		if err == badger.ErrTxnTooBig {
+			if err = txn.Commit(nil); err != nil {
+				return 0, err
+			}
+			txn = s.db.NewTransaction(true)
+			err = discardEarlierVersions(txn, key)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return purged, txn.Commit(nil)
+}
+
+// discardEarlierVersions rewrites the last version of key with the marker
+// discarding the older ones.
+func discardEarlierVersions(txn *badger.Txn, key []byte) error {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		// deleted since
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	return txn.SetWithDiscard(key, value, item.UserMeta())
+}
+
+// valueLogSize returns the size of the value log files, as last refreshed
+// by badger on the mounted stores.
+func (s *Store) valueLogSize() int64 {
+	if s.valueDir == "" {
+		// maintained by badger, updated every minute
+		_, vlog := s.db.Size()
+		return vlog
+	}
+	files, _ := filepath.Glob(filepath.Join(s.valueDir, "*.vlog"))
+	var size int64
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil {
+			size += fi.Size()
+		}
+	}
+	return size
+}
+
+// gcLoop runs RunGC periodically until stopped.
+type gcLoop struct {
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// startGC starts running RunGC every interval.
+func (s *Store) startGC(interval time.Duration) {
+	gc := &gcLoop{done: make(chan struct{})}
+	gc.wg.Add(1)
+	go func() {
+		defer gc.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-gc.done:
+				return
+			case <-ticker.C:
+				s.RunGC()
+			}
+		}
+	}()
+	s.gc = gc
+}
+
+// stopGC stops the background runs and waits for the current one.
+func (s *Store) stopGC() {
+	if s.gc == nil {
+		return
+	}
+	s.gc.once.Do(func() { close(s.gc.done) })
+	s.gc.wg.Wait()
+}
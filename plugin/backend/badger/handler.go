@@ -1,7 +1,6 @@
 package badgerstorage
 
 import (
-	"errors"
 	"path/filepath"
 	"sync"
 	"time"
@@ -9,148 +8,259 @@ import (
 	// external
 	"github.com/dgraph-io/badger"
 	"github.com/rohanthewiz/roencoding"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+	helper "github.com/sniperkit/colly-storage/pkg/helper"
 )
 
 var defaultStorePrefixPath string = filepath.Join(storagePrefixPath, storageBucketName)
 
+var _ storage.TypedSetter = (*Store)(nil)
+var _ storage.EncodedSetter = (*Store)(nil)
+var _ storage.TypedExpirer = (*Store)(nil)
+
 // Store stores and retrieves data using Badger KV.
 type Store struct {
 	mu          sync.RWMutex
 	db          *badger.DB
 	storagePath string
 	bucketName  string
-	compress    bool
-	debug       bool
-}
-
-type Check struct {
-	Enabled   bool
-	Key       string
-	Requests  int
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	ExpiredAt time.Time
-	Priority  bool
-	Provider  string
+	// values encodes and decodes the values, its dictionaries are trained
+	// by TrainDictionary
+	values   compress.Values
+	debug    bool
+	stats    bool
+	provider string
+	// ttl is the default time to live of the entries
+	ttl      time.Duration
+	readOnly bool
+	// valueDir holds the value log files, empty for Mount
+	valueDir string
+	// gcMu serializes the runs of RunGC, gc runs them in background
+	gcMu           sync.Mutex
+	gcDiscardRatio float64
+	numVersions    int
+	lastGC         GCReport
+	gc             *gcLoop
+	// hits buffers the reads counted when stats is set, see recordHits
+	hitsMu   sync.Mutex
+	hits     map[string]*pendingHits
+	buffered int
+	// queues holds the queues returned by Queue, closed by Close
+	queuesMu sync.Mutex
+	queues   map[string]*Queue
+
+	actionsOnce sync.Once
+	actions     *storage.ActionRegistry
 }
 
+// Mount returns a Store over an open badger database, closed by Close. Its
+// RunGC reports no ReclaimedBytes, see GCReport.
 func Mount(client *badger.DB) *Store {
 	return &Store{db: client}
 }
 
+// New opens the badger database described by config, the default one if
+// config is nil. The unset flags are taken from Config.Profile then from
+// badger's defaults.
 func New(config *Config) (*Store, error) {
-	badgerConfig := badger.DefaultOptions
 	if config == nil {
-		badgerConfig.Dir = storagePrefixPath
-		badgerConfig.ValueDir = storageBucketName
-		badgerConfig.SyncWrites = true
-	} else {
-		badgerConfig.Dir = config.StoragePath
-		badgerConfig.ValueDir = filepath.Join(config.StoragePath, config.ValueDir)
-		badgerConfig.SyncWrites = config.SyncWrites
+		defaults := DefaultConfig()
+		config = &defaults
 	}
+	badgerConfig, merged, err := config.options()
+	if err != nil {
+		return nil, err
+	}
+	config = &merged
 
+	values, err := compress.NewValues(config.Compress, config.Codec, DefaultCodec)
+	if err != nil {
+		return nil, err
+	}
+	values.Policy = config.Policy
+
+	if !config.ReadOnly {
+		// badger creates the directories, not their parents
+		if err := helper.EnsurePathExists(badgerConfig.Dir); err != nil {
+			return nil, err
+		}
+	}
 	client, err := badger.Open(badgerConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Store{
+	store := &Store{
 		db:       client,
 		debug:    config.Debug,
-		compress: config.Compress,
-	}, nil
+		values:   values,
+		ttl:      config.TTL,
+		stats:    config.Stats && !config.ReadOnly,
+		provider: config.Provider,
+		readOnly: config.ReadOnly,
+		valueDir: badgerConfig.ValueDir,
+
+		gcDiscardRatio: config.GCDiscardRatio,
+		numVersions:    badgerConfig.NumVersionsToKeep,
+	}
+	if store.gcDiscardRatio == 0 {
+		store.gcDiscardRatio = DefaultGCDiscardRatio
+	}
+	if store.values.Dicts, err = compress.NewDicts(store); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if config.GCInterval > 0 && !config.ReadOnly {
+		store.startGC(config.GCInterval)
+	}
+	return store, nil
+}
+
+// Init implements storage.CollectorStorage.Init(), the database is opened by New.
+func (s *Store) Init() error {
+	if s.db == nil {
+		return storage.ErrClosed
+	}
+	return nil
 }
 
 func (s *Store) Get(key string) (resp []byte, ok bool) {
+	return s.get(key, true)
+}
+
+// Peek implements storage.Peeker.Peek(), the read isn't counted by
+// Config.Stats.
+func (s *Store) Peek(key string) (resp []byte, ok bool) {
+	return s.get(key, false)
+}
+
+// get returns the decoded response stored at key, the read is counted if
+// hit is set.
+func (s *Store) get(key string, hit bool) (resp []byte, ok bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(key))
+		var err error
+		resp, err = s.read(txn, []byte(key))
 		if err != nil {
 			return err
 		}
-		resp, err = item.Value()
-		if err != nil {
-			return err
-		}
-		if s.compress {
-			var err error
-			resp, err = Decompress(resp)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
+		resp, err = s.values.Decode(resp)
+		return err
 	})
-	return resp, err == nil
+	if err != nil {
+		return nil, false
+	}
+	if hit {
+		// a read is served even when it can't be counted
+		s.recordHits([][]byte{[]byte(key)}, time.Now())
+	}
+	return resp, true
 }
 
-// Set stores a response to the store at the given key.
+// Set stores a response to the store at the given key, expiring after
+// Config.TTL when set.
 func (s *Store) Set(key string, resp []byte) error {
+	return s.SetWithTTL(key, resp, s.ttl)
+}
+
+// SetWithTTL implements storage.Expirer.SetWithTTL(), the expiry is handled
+// by badger.
+func (s *Store) SetWithTTL(key string, resp []byte, ttl time.Duration) error {
+	return s.set(key, resp, "", ttl)
+}
+
+// SetTyped implements storage.TypedSetter.SetTyped(), contentType is the
+// hint of Config.Policy.
+func (s *Store) SetTyped(key string, resp []byte, contentType string) error {
+	return s.set(key, resp, contentType, s.ttl)
+}
+
+// SetTypedWithTTL implements storage.TypedExpirer.SetTypedWithTTL()
+func (s *Store) SetTypedWithTTL(key string, resp []byte, contentType string, ttl time.Duration) error {
+	return s.set(key, resp, contentType, ttl)
+}
+
+// SetEncoded implements storage.EncodedSetter.SetEncoded(), the response
+// is stored as it was received behind the header of its codec, bypassing
+// Config.Policy and the dictionaries.
+func (s *Store) SetEncoded(key string, resp []byte, contentEncoding string) error {
+	codec, err := compress.ContentEncodingCodec(contentEncoding)
+	if err != nil {
+		return err
+	}
+	return s.setEncoded(key, compress.WrapEncoded(codec, resp), s.ttl)
+}
+
+// set encodes and stores a response, contentType is the hint of the policy.
+func (s *Store) set(key string, resp []byte, contentType string, ttl time.Duration) error {
+	resp, err := s.values.Encode(key, resp, contentType)
+	if err != nil {
+		return err
+	}
+	return s.setEncoded(key, resp, ttl)
+}
+
+// setEncoded stores an encoded response at key.
+func (s *Store) setEncoded(key string, resp []byte, ttl time.Duration) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	return s.db.Update(func(txn *badger.Txn) error {
-		if s.compress {
-			var err error
-			resp, err = Compress(resp)
-			if err != nil {
-				return errors.New("error while compressing content...")
-			}
-		}
-		return txn.Set([]byte(key), resp)
+		return s.put(txn, []byte(key), resp, ttl)
 	})
 }
 
+func setWithTTL(txn *badger.Txn, key, value []byte, ttl time.Duration) error {
+	if ttl > 0 {
+		return txn.SetWithTTL(key, value, ttl)
+	}
+	return txn.Set(key, value)
+}
+
 func (s *Store) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	return s.db.Update(func(txn *badger.Txn) error {
-		err := txn.Delete([]byte(key))
-		return err
+		return s.deleteEntry(txn, []byte(key))
 	})
 }
 
-func (s *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
-	if name == "getKeys" {
-		keys := s.keys()
-		resp := make(map[string]*interface{})
-		for _, v := range keys {
-			resp[v] = nil
+// Close stops the background GC, writes the buffered reads, closes the
+// dictionaries, the queues and the underlying badger database.
+func (s *Store) Close() error {
+	s.stopGC()
+	s.mu.Lock()
+	err := s.flushHits()
+	if s.values.Dicts != nil {
+		if e := s.values.Dicts.Close(); err == nil {
+			err = e
 		}
-		return resp, nil
 	}
-
-	return nil, errors.New("Action not implemented yet")
-}
-
-// Close closes the underlying boltdb database.
-func (s *Store) Close() error {
+	s.mu.Unlock()
+	if err == nil {
+		err = s.closeQueues()
+	}
+	if err != nil {
+		s.db.Close()
+		return err
+	}
 	return s.db.Close()
 }
 
-func (s *Store) keys() (keys []string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	err := s.db.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchValues = false
-		it := txn.NewIterator(opts)
-		for it.Rewind(); it.Valid(); it.Next() {
-			item := it.Item()
-			k := item.Key()
-			keys = append(keys, string(k))
-		}
-		return nil
-	})
+// keys returns all the keys of the store, Scan should be preferred on large
+// stores.
+func (s *Store) keys() []string {
+	keys, _, err := s.Scan("", "", 0)
 	if err != nil {
 		return []string{}
 	}
-	return
+	return keys
 }
 
 // Add a hashed key to the store if it doesn't already exist
@@ -171,102 +281,3 @@ func (s *Store) existsHashed(key string) (bool, error) {
 	})
 	return err == nil, err
 }
-
-/*
-
-func (s *Store) seekPrefix(value string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.db.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
-		prefix := []byte(value)
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-			k := item.Key()
-			v, err := item.Value()
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-}
-
-func (s *Store) firstIndex() (uint64, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	tx := s.db.NewTransaction(false)
-	defer tx.Discard()
-	iter := tx.NewIterator(iterAscOpt)
-	iter.Rewind()
-	item := iter.Item()
-	if item == nil {
-		return 0, nil
-	}
-
-	return bytesToUint64(item.Key()), nil
-}
-
-func (s *Store) lastIndex() (uint64, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	tx := s.db.NewTransaction(false)
-	defer tx.Discard()
-	iter := tx.NewIterator(iterDescOpt)
-	iter.Rewind()
-	item := iter.Item()
-	if item == nil {
-		return 0, nil
-	}
-	return bytesToUint64(item.Key()), nil
-}
-
-func (s *Store) deleteRange(min, max uint64) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	tx := s.db.NewTransaction(true)
-	defer tx.Discard()
-	minKey := uint64ToBytes(min)
-	iter := tx.NewIterator(iterAscOpt)
-	for iter.Seek(minKey); iter.Valid(); iter.Next() {
-		item := iter.Item()
-		if item == nil {
-			break
-		}
-		curKey := safeKey(item)
-		if bytesToUint64(curKey) > max {
-			break
-		}
-		if err := tx.Delete(curKey); err != nil {
-			return err
-		}
-	}
-	if err := tx.Commit(nil); err != nil {
-		return err
-	}
-	return nil
-}
-*/
-
-/*
-func (s *Store) updates(updates map[string]interface{}) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	txn := db.NewTransaction(true)
-	for k,v := range updates {
-	  if err := txn.Set(byte[](k),byte[](v)); err == ErrTxnTooBig {
-	    _ = txn.Commit()
-	    txn = db.NewTransaction(..)
-	    _ = txn.Set(k,v)
-	  }
-	}
-	_ = txn.Commit()
-
-}
-*/
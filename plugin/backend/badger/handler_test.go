@@ -1 +1,1089 @@
 package badgerstorage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// external
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/options"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+	"github.com/sniperkit/colly-storage/pkg/serialize"
+)
+
+func newTestStore(t *testing.T, config *Config) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	config.StoragePath = dir
+	store, err := New(config)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSetGetDelete(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		store, done := newTestStore(t, &Config{Compress: compress})
+		if err := store.Set("hello", []byte("world")); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		v, ok := store.Get("hello")
+		if !ok || string(v) != "world" {
+			t.Errorf("expected world, got %q (ok=%t, compress=%t)", v, ok, compress)
+		}
+		if err := store.Delete("hello"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, ok := store.Get("hello"); ok {
+			t.Error("expected hello to be deleted")
+		}
+		done()
+	}
+}
+
+func TestCollectorStorage(t *testing.T) {
+	store, done := newTestStore(t, nil)
+	defer done()
+
+	if err := store.Init(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ok, err := store.IsVisited(42); err != nil || ok {
+		t.Errorf("expected 42 not to be visited, got %t (%v)", ok, err)
+	}
+	if err := store.Visited(42); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ok, err := store.IsVisited(42); err != nil || !ok {
+		t.Errorf("expected 42 to be visited, got %t (%v)", ok, err)
+	}
+
+	u, _ := url.Parse("http://example.com/path")
+	store.SetCookies(u, "a=1")
+	store.SetCookies(u, "b=2")
+	if c := store.Cookies(u); c != "a=1\nb=2" {
+		t.Errorf("unexpected cookies: %q", c)
+	}
+
+	if keys := store.keys(); len(keys) != 0 {
+		t.Errorf("expected the collector keys to be hidden, got %q", keys)
+	}
+}
+
+func TestActions(t *testing.T) {
+	store, done := newTestStore(t, nil)
+	defer done()
+
+	store.Set("a", []byte("1"))
+	store.Visited(1)
+
+	specs := store.Actions()
+	if len(specs) != 2 || specs[0].Name != "getKeys" || specs[1].Name != "runGC" {
+		t.Errorf("unexpected actions: %v", specs)
+	}
+	keys, err := store.Do("getKeys")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if k := keys.([]string); len(k) != 1 || k[0] != "a" {
+		t.Errorf("unexpected keys: %q", k)
+	}
+	if _, err := store.Do("getKeys", "extra"); !errors.Is(err, storage.ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+	// the legacy result of getKeys
+	legacy, err := store.Action("getKeys")
+	if v, ok := legacy["a"]; err != nil || len(legacy) != 1 || !ok || v != nil {
+		t.Errorf("expected the keys mapped to nil, got %v (%v)", legacy, err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		store, done := newTestStore(t, &Config{Compress: compress})
+		for _, k := range []string{"a/1", "a/2", "a/3", "b/1"} {
+			if err := store.Set(k, []byte("v"+k)); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+		store.Visited(1)
+
+		keys, next, err := store.Scan("a/", "", 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(keys) != 2 || keys[0] != "a/1" || keys[1] != "a/2" || next != "a/2" {
+			t.Errorf("unexpected first page: %q, next=%q", keys, next)
+		}
+		kvs, next, err := store.ScanValues("a/", next, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(kvs) != 1 || kvs[0].Key != "a/3" || string(kvs[0].Value) != "va/3" || next != "" {
+			t.Errorf("unexpected last page: %+v, next=%q (compress=%t)", kvs, next, compress)
+		}
+
+		var all []string
+		if err := storage.Walk(store, "", 1, func(key string) error {
+			all = append(all, key)
+			return nil
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(all) != 4 {
+			t.Errorf("expected the internal keys to be skipped, got %q", all)
+		}
+		done()
+	}
+}
+
+func TestBatch(t *testing.T) {
+	store, done := newTestStore(t, &Config{Compress: true})
+	defer done()
+
+	store.Set("old", []byte("value"))
+	b := storage.NewBatch()
+	b.Set("a", []byte("1"))
+	b.Delete("old")
+	if err := store.WriteBatch(b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	values, err := store.GetMany([]string{"a", "old"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(values) != 1 || string(values["a"]) != "1" {
+		t.Errorf("unexpected values: %q", values)
+	}
+
+	// an invalid key rolls back the whole batch
+	b = storage.NewBatch()
+	b.Set("b", []byte("2"))
+	b.Set("", []byte("empty"))
+	err = store.WriteBatch(b)
+	var batchErr storage.BatchError
+	if !errors.As(err, &batchErr) || batchErr[""] == nil || batchErr["b"] != storage.ErrBatchAborted {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, ok := store.Get("b"); ok {
+		t.Error("expected the batch to be rolled back")
+	}
+}
+
+func TestBatchTooBig(t *testing.T) {
+	store, done := newTestStore(t, nil)
+	defer done()
+
+	// larger than the number of entries of a badger transaction
+	const n = 250000
+	kvs := make([]storage.KV, n)
+	for i := range kvs {
+		kvs[i] = storage.KV{Key: fmt.Sprintf("key-%06d", i), Value: []byte("v")}
+	}
+	if err := store.SetMany(kvs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	keys, _, err := store.Scan("key-", "", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(keys) != n {
+		t.Errorf("expected %d keys, got %d", n, len(keys))
+	}
+}
+
+func TestBatchTooBigHits(t *testing.T) {
+	store, done := newTestStore(t, &Config{Stats: true})
+	defer done()
+
+	// the entries are rewritten with their read counters folded, the batch
+	// is split in the middle of an operation
+	const n = 150000
+	kvs := make([]storage.KV, n)
+	keys := make([]string, n)
+	for i := range kvs {
+		keys[i] = fmt.Sprintf("key-%06d", i)
+		kvs[i] = storage.KV{Key: keys[i], Value: []byte("v")}
+	}
+	if err := store.SetMany(kvs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := store.GetMany(keys); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.flushHits(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.SetMany(kvs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, key := range keys {
+		if c, err := store.Stat(key); err != nil || c.Requests != 1 {
+			t.Fatalf("%s: expected 1 request, got %+v (%v)", key, c, err)
+		}
+	}
+}
+
+func TestTTL(t *testing.T) {
+	store, done := newTestStore(t, &Config{TTL: time.Second})
+	defer done()
+
+	if err := store.SetWithTTL("short", []byte("v"), time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.SetWithTTL("forever", []byte("v"), 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.SetMany([]storage.KV{{Key: "batch", Value: []byte("v")}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := store.Get("short"); !ok {
+		t.Fatal("expected short to be readable before its expiry")
+	}
+
+	// badger expiries have a one second resolution
+	time.Sleep(2 * time.Second)
+	for _, k := range []string{"short", "batch"} {
+		if _, ok := store.Get(k); ok {
+			t.Errorf("expected %s to read as not found once expired", k)
+		}
+	}
+	if keys := store.keys(); len(keys) != 1 || keys[0] != "forever" {
+		t.Errorf("expected the expired entries to be skipped, got %q", keys)
+	}
+}
+
+func TestStat(t *testing.T) {
+	store, done := newTestStore(t, &Config{Stats: true, Provider: "crawler", Compress: true})
+	defer done()
+
+	if _, err := store.Stat("missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	store.SetWithTTL("page", []byte("v1"), time.Hour)
+	store.Get("page")
+	store.Get("page")
+	first, err := store.Stat("page")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if first.Requests != 2 || first.Provider != "crawler" || first.ExpiredAt.IsZero() {
+		t.Errorf("unexpected check: %+v", first)
+	}
+
+	store.Set("page", []byte("v2"))
+	second, _ := store.Stat("page")
+	if !second.CreatedAt.Equal(first.CreatedAt) || second.Requests != 2 || !second.ExpiredAt.IsZero() {
+		t.Errorf("expected the metadata to be carried over, got %+v", second)
+	}
+	if v, ok := store.Get("page"); !ok || string(v) != "v2" {
+		t.Errorf("expected v2, got %q", v)
+	}
+
+	// the concurrent reads of a key are all served and counted
+	var (
+		wg     sync.WaitGroup
+		misses int32
+	)
+	for w := 0; w < 32; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, ok := store.Get("page"); !ok {
+					atomic.AddInt32(&misses, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	third, _ := store.Stat("page")
+	if misses > 0 || third.Requests != 2+1+1600 {
+		t.Errorf("expected 1603 requests and no miss, got %d requests and %d misses", third.Requests, misses)
+	}
+
+	// the counters are folded in the envelopes by the writes, and deleted
+	// with their entries
+	store.Set("page", []byte("v3"))
+	if check, _ := store.Stat("page"); check.Requests != third.Requests {
+		t.Errorf("expected the requests to be kept, got %d", check.Requests)
+	}
+	store.Get("page")
+	store.Delete("page")
+	store.Set("page", []byte("v4"))
+	if check, _ := store.Stat("page"); check.Requests != 0 {
+		t.Errorf("expected the requests of the deleted entry to be dropped, got %d", check.Requests)
+	}
+}
+
+func TestLegacyValues(t *testing.T) {
+	store, done := newTestStore(t, nil)
+	defer done()
+
+	// written before the envelopes
+	store.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("legacy"), []byte("raw"))
+	})
+	if v, ok := store.Get("legacy"); !ok || string(v) != "raw" {
+		t.Errorf("expected the raw value, got %q", v)
+	}
+	if c, err := store.Stat("legacy"); err != nil || c.Enabled {
+		t.Errorf("expected a disabled check, got %+v (%v)", c, err)
+	}
+}
+
+func TestCodecChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	values := map[string]string{}
+	for i, config := range []Config{
+		{Compress: true},
+		{Codec: "lz4"},
+		{Compress: true, Codec: "gzip"},
+	} {
+		config.StoragePath = dir
+		store, err := New(&config)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if i == 0 {
+			// written with snappy before the value headers
+			store.db.Update(func(txn *badger.Txn) error {
+				legacy, _ := Compress([]byte("legacy"))
+				return txn.Set([]byte("legacy"), legacy)
+			})
+			values["legacy"] = "legacy"
+		}
+		key := fmt.Sprintf("value-%d", i)
+		if err := store.Set(key, []byte(key)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		values[key] = key
+		for key, value := range values {
+			if v, ok := store.Get(key); !ok || string(v) != value {
+				t.Errorf("%s: expected %q, got %q (codec %s)", key, value, v, store.values.Codec.Name())
+			}
+		}
+		store.Close()
+	}
+}
+
+func TestStream(t *testing.T) {
+	body := strings.Repeat("<p>large body</p>", 10000)
+	for _, codec := range []string{"", "snappy", "gzip", "brotli", "brotli:5:18"} {
+		store, done := newTestStore(t, &Config{Codec: codec})
+		if err := store.SetReader("body", strings.NewReader(body)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if v, ok := store.Get("body"); !ok || string(v) != body {
+			t.Errorf("%s: unexpected value of %d bytes", codec, len(v))
+		}
+		store.Set("page", []byte(body))
+		for _, key := range []string{"body", "page"} {
+			r, err := store.GetReader(key)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			data, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil || string(data) != body {
+				t.Errorf("%s: unexpected %s of %d bytes (%v)", codec, key, len(data), err)
+			}
+		}
+		if _, err := store.GetReader("missing"); err != storage.ErrNotFound {
+			t.Errorf("expected %s, got %v", storage.ErrNotFound, err)
+		}
+		done()
+	}
+	if _, err := New(&Config{StoragePath: "unused", Codec: "brotli:5:30"}); err == nil {
+		t.Error("expected an error for an invalid brotli window")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := storage.Open("badger://" + dir + "?bucket=values&compress=snappy&sync=false&ttl=1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store := s.(*Store)
+	if store.values.Codec.Name() != "snappy" || store.ttl != time.Hour {
+		t.Errorf("unexpected store: %+v", store)
+	}
+	if err := store.Set("page", []byte("value")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store.Close()
+
+	s, err = storage.Open("badger://" + dir + "?bucket=values&compress=snappy&read-only=true")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer s.(*Store).Close()
+	if v, ok := s.Get("page"); !ok || string(v) != "value" {
+		t.Errorf("expected value, got %q", v)
+	}
+	if err := s.Set("page", []byte("other")); err != badger.ErrReadOnlyTxn {
+		t.Errorf("expected %s, got %v", badger.ErrReadOnlyTxn, err)
+	}
+
+	if _, err := storage.Open("badger://" + dir + "?compress=gzip"); err == nil {
+		t.Error("expected an error for an unsupported codec")
+	}
+
+	// the codecs take their level and window
+	s, err = storage.Open("badger://" + filepath.Join(dir, "brotli") + "?compress=brotli:9:18")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s.(*Store).values.Codec.ID() != compress.CodecBrotli {
+		t.Errorf("unexpected codec %s", s.(*Store).values.Codec.Name())
+	}
+	if err := s.Set("page", []byte("value")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	s.(*Store).Close()
+	if _, err := storage.Open("badger://" + filepath.Join(dir, "gzip") + "?compress=gzip:10"); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	opts, _, err := Config{StoragePath: "db", ValueDir: "values", Profile: ProfileLowMemory, NumMemtables: 2}.options()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if opts.Dir != "db" || opts.ValueDir != "db/values" || opts.SyncWrites {
+		t.Errorf("unexpected directories %q, %q or synced writes", opts.Dir, opts.ValueDir)
+	}
+	// set, from the profile, from badger's defaults
+	if opts.NumMemtables != 2 || opts.ValueLogFileSize != 64<<20 || opts.ValueLogLoadingMode != options.FileIO || opts.LevelSizeMultiplier != badger.DefaultOptions.LevelSizeMultiplier {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if opts, _, _ := (Config{}).options(); opts.Dir != defaultStorePrefixPath || opts.MaxTableSize != badger.DefaultOptions.MaxTableSize {
+		t.Errorf("unexpected default options %+v", opts)
+	}
+	config := DefaultConfig()
+	config.Profile = ProfileLowMemory
+	if opts, _, _ := config.options(); opts.NumMemtables != 1 || opts.MaxTableSize != 16<<20 || opts.TableLoadingMode != options.FileIO || !opts.SyncWrites {
+		t.Errorf("expected the profile applied to the default config, got %+v", opts)
+	}
+
+	for _, config := range []Config{
+		{Profile: "unknown"},
+		{MaxLevels: 1},
+		{NumCompactors: -1},
+		{ValueThreshold: 1 << 16},
+		{NumLevelZeroTables: 10},
+		{ValueLogFileSize: 1 << 10},
+		{GCDiscardRatio: 1},
+		{TableLoadingMode: "disk"},
+		{ValueLogLoadingMode: LoadingModeRAM},
+	} {
+		if _, err := New(&config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%+v: expected ErrInvalidConfig, got %v", config, err)
+		}
+	}
+
+	for _, profile := range Profiles() {
+		store, done := newTestStore(t, &Config{Profile: profile})
+		if err := store.Set("key", []byte("value")); err != nil {
+			t.Errorf("%s: unexpected error: %s", profile, err)
+		}
+		done()
+	}
+
+	// the default database, in the working directory
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	store, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer store.Close()
+	if err := store.Set("key", []byte("value")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(defaultStorePrefixPath); err != nil {
+		t.Errorf("expected the database in %s: %s", defaultStorePrefixPath, err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	store, done := newTestStore(t, &Config{
+		Compress: true,
+		Policy: &compress.Policy{
+			MinSize: GzipMinSize,
+			Codec:   "gzip",
+			Codecs:  map[string]string{"application/json": "brotli"},
+		},
+	})
+	defer done()
+
+	page := strings.Repeat("<p>large body</p>", 100)
+	jpeg := "\xff\xd8\xff\xe0" + page
+	json := `{"body": "` + page + `"}`
+	if err := store.Set("page", []byte(page)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store.Set("small", []byte("<p>small body</p>"))
+	store.Set("jpeg", []byte(jpeg))
+	store.SetTyped("json", []byte(json), "application/json; charset=utf-8")
+	store.SetReader("stream", strings.NewReader(page))
+
+	for key, tt := range map[string]struct {
+		value string
+		codec compress.CodecID
+	}{
+		"page":   {page, compress.CodecGZip},
+		"small":  {"<p>small body</p>", compress.CodecRaw},
+		"jpeg":   {jpeg, compress.CodecRaw},
+		"json":   {json, compress.CodecBrotli},
+		"stream": {page, compress.CodecGZip},
+	} {
+		if v, ok := store.Get(key); !ok || string(v) != tt.value {
+			t.Errorf("%s: unexpected value %q", key, v)
+		}
+		store.db.View(func(txn *badger.Txn) error {
+			payload, err := store.read(txn, []byte(key))
+			if h, _, _ := compress.ReadHeader(payload); err != nil || h.Codec != tt.codec {
+				t.Errorf("%s: expected codec %d, got %+v (%v)", key, tt.codec, h, err)
+			}
+			return nil
+		})
+	}
+}
+
+func TestDictionaries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := New(&Config{StoragePath: dir, Codec: "zstd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	page := func(i int) []byte {
+		return []byte(fmt.Sprintf(`<html><head><title>Item %d</title></head><body><nav>
+<a href="/">Home</a><a href="/items">Items</a></nav><div class="item"><h1>Item %d</h1>
+<p class="price">%d.99</p></div><footer>example.com</footer></body></html>`, i, i, i))
+	}
+	for i := 0; i < 20; i++ {
+		store.Set(fmt.Sprintf("http://example.com/item/%d", i), page(i))
+	}
+	store.Set("http://other.com/", []byte("other"))
+	plain, _ := store.values.Encode("http://example.com/item/100", page(100), "")
+
+	if _, err := store.TrainDictionary("example.com", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	first, _ := store.values.Encode("http://example.com/item/100", page(100), "")
+	if len(first) >= len(plain) {
+		t.Errorf("expected the dictionary to help, got %d bytes instead of %d", len(first), len(plain))
+	}
+	store.Set("http://example.com/item/100", page(100))
+	if _, err := store.TrainDictionary("example.com", 10); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store.Set("http://example.com/item/101", page(101))
+	if _, err := store.TrainDictionary("unknown.com", 10); err != compress.ErrNoSamples {
+		t.Errorf("expected %s, got %v", compress.ErrNoSamples, err)
+	}
+	store.Close()
+
+	// the values of all the versions are read after a restart
+	store, err = New(&Config{StoragePath: dir, Codec: "zstd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer store.Close()
+	if d, ok := store.values.Dicts.Current("example.com"); !ok || d.ID != 2 {
+		t.Errorf("unexpected current dictionary %+v", d)
+	}
+	for _, i := range []int{0, 19, 100, 101} {
+		if v, ok := store.Get(fmt.Sprintf("http://example.com/item/%d", i)); !ok || string(v) != string(page(i)) {
+			t.Errorf("%d: unexpected value %q", i, v)
+		}
+	}
+	r, err := store.GetReader("http://example.com/item/101")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer r.Close()
+	if v, err := ioutil.ReadAll(r); err != nil || string(v) != string(page(101)) {
+		t.Errorf("unexpected streamed value %q (%v)", v, err)
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	store, done := newTestStore(t, &Config{Codec: "zstd"})
+	defer done()
+
+	page := strings.Repeat("<p>large body</p>", 100)
+	brotli, _ := compress.Lookup("brotli")
+	body, _ := brotli.Encode([]byte(page))
+	if err := store.SetEncoded("page", body, "br"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.SetEncoded("other", body, "deflate"); !errors.Is(err, compress.ErrUnsupportedEncoding) {
+		t.Errorf("expected %s, got %v", compress.ErrUnsupportedEncoding, err)
+	}
+
+	// the body is kept as it was received
+	store.db.View(func(txn *badger.Txn) error {
+		payload, err := store.read(txn, []byte("page"))
+		h, encoded, _ := compress.ReadHeader(payload)
+		if err != nil || h.Codec != compress.CodecBrotli || string(encoded) != string(body) {
+			t.Errorf("unexpected payload %+v (%v)", h, err)
+		}
+		return nil
+	})
+	if v, ok := store.Get("page"); !ok || string(v) != page {
+		t.Errorf("unexpected value %q", v)
+	}
+	r, err := store.GetReader("page")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer r.Close()
+	if v, err := ioutil.ReadAll(r); err != nil || string(v) != page {
+		t.Errorf("unexpected streamed value %q (%v)", v, err)
+	}
+}
+
+func TestObjects(t *testing.T) {
+	store, done := newTestStore(t, &Config{
+		Policy: &compress.Policy{
+			MinSize: 64,
+			Codec:   "snappy",
+			Codecs:  map[string]string{"application/cbor": "gzip"},
+		},
+	})
+	defer done()
+
+	type page struct {
+		URL  string
+		Body string
+	}
+	in := page{URL: "http://example.com/", Body: strings.Repeat("<p>large body</p>", 100)}
+	for serializer, codec := range map[string]compress.CodecID{
+		"cbor":    compress.CodecGZip,
+		"json":    compress.CodecSnappy,
+		"msgpack": compress.CodecRaw,
+	} {
+		objects, err := serialize.NewObjectStore(store, serializer)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := objects.PutObject(serializer, in); err != nil {
+			t.Fatalf("%s: unexpected error: %s", serializer, err)
+		}
+		var out page
+		if err := objects.GetObject(serializer, &out); err != nil || out != in {
+			t.Errorf("%s: unexpected object %+v (%v)", serializer, out, err)
+		}
+		store.db.View(func(txn *badger.Txn) error {
+			payload, err := store.read(txn, []byte(serializer))
+			if h, _, _ := compress.ReadHeader(payload); err != nil || h.Codec != codec {
+				t.Errorf("%s: expected codec %d, got %+v (%v)", serializer, codec, h, err)
+			}
+			return nil
+		})
+	}
+}
+
+func TestGC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	config := &Config{
+		StoragePath:        dir,
+		ValueLogFileSize:   1 << 20,
+		ValueLogMaxEntries: 1000,
+		NumVersionsToKeep:  2,
+	}
+	store, err := New(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	value := func(i, version int) []byte {
+		return []byte(fmt.Sprintf("%d/%d/%s", i, version, strings.Repeat("x", 1<<10)))
+	}
+	for version := 0; version < 3; version++ {
+		for i := 0; i < 1000; i++ {
+			if err := store.Set(fmt.Sprintf("page/%d", i), value(i, version)); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+	for i := 0; i < 900; i++ {
+		store.Delete(fmt.Sprintf("page/%d", i))
+	}
+	store.SetWithTTL("page/ttl", value(0, 0), time.Hour)
+	store.SetWithTTL("page/ttl", value(0, 1), time.Hour)
+
+	// the value log files are collected once the memtable is flushed
+	store.Close()
+	if store, err = New(config); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	report, err := store.RunGC()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.VersionsPurged != 100 {
+		t.Errorf("expected 100 versions purged, got %d", report.VersionsPurged)
+	}
+	if report.Rewrites == 0 || report.ReclaimedBytes <= 0 {
+		t.Errorf("expected the value log to be rewritten, got %+v", report)
+	}
+	if last := store.LastGC(); last.Start != report.Start {
+		t.Errorf("expected the last report %+v, got %+v", report, last)
+	}
+	for i := 900; i < 1000; i++ {
+		if v, ok := store.Get(fmt.Sprintf("page/%d", i)); !ok || string(v) != string(value(i, 2)) {
+			t.Fatalf("%d: unexpected value %.20q", i, v)
+		}
+	}
+	if _, err := store.Do("runGC"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	store.Close()
+
+	// with the default discard ratio on the mounted stores
+	opts := badger.DefaultOptions
+	opts.Dir, opts.ValueDir = dir, dir
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mounted := Mount(db)
+	defer mounted.Close()
+	if _, err := mounted.RunGC(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestGCInterval(t *testing.T) {
+	store, done := newTestStore(t, &Config{GCInterval: 10 * time.Millisecond})
+	defer done()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for store.LastGC().Start.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the GC to run in background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// stopped by Close
+	store.stopGC()
+	last := store.LastGC()
+	time.Sleep(50 * time.Millisecond)
+	if store.LastGC().Start != last.Start {
+		t.Error("expected the background runs to stop")
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	config := &Config{Codec: "gzip", Provider: "crawler"}
+	src, done := newTestStore(t, config)
+	defer done()
+
+	src.Set("a", []byte("a1"))
+	src.Set("b", []byte("b1"))
+	src.SetWithTTL("ttl", []byte("ttl"), time.Hour)
+	src.Visited(1)
+	// the older versions of the expired entries are not restored
+	src.Set("expired", []byte("old"))
+	src.SetWithTTL("expired", []byte("new"), time.Second)
+	time.Sleep(2 * time.Second)
+	var full bytes.Buffer
+	version, err := src.Backup(&full, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the incremental backup carries on from the full one
+	src.Set("b", []byte("b2"))
+	src.Set("c", []byte("c1"))
+	src.Delete("a")
+	var incremental bytes.Buffer
+	next, err := src.Backup(&incremental, version)
+	if err != nil || next <= version {
+		t.Fatalf("unexpected version %d after %d (%v)", next, version, err)
+	}
+	if incremental.Len() >= full.Len() {
+		t.Errorf("expected an incremental backup, got %d bytes for %d", incremental.Len(), full.Len())
+	}
+	var empty bytes.Buffer
+	if v, err := src.Backup(&empty, next); err != nil || v != next || empty.Len() != 0 {
+		t.Errorf("expected an empty backup at %d, got %d bytes at %d (%v)", next, empty.Len(), v, err)
+	}
+
+	dst, done := newTestStore(t, &Config{})
+	defer done()
+	for _, backup := range []*bytes.Buffer{&full, &incremental} {
+		if err := dst.Restore(backup); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	for key, value := range map[string]string{"b": "b2", "c": "c1", "ttl": "ttl"} {
+		if v, ok := dst.Get(key); !ok || string(v) != value {
+			t.Errorf("%s: expected %q, got %q", key, value, v)
+		}
+	}
+	for _, key := range []string{"a", "expired"} {
+		if v, ok := dst.Get(key); ok {
+			t.Errorf("%s: expected the key to stay deleted, got %q", key, v)
+		}
+	}
+	if visited, _ := dst.IsVisited(1); !visited {
+		t.Error("expected the visited requests to be restored")
+	}
+
+	// with the metadata and the codec headers
+	want, _ := src.Stat("b")
+	got, err := dst.Stat("b")
+	if err != nil || got.Provider != "crawler" || !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("expected %+v, got %+v (%v)", want, got, err)
+	}
+	if check, _ := dst.Stat("ttl"); check == nil || check.ExpiredAt.IsZero() {
+		t.Errorf("expected the expiry to be restored, got %+v", check)
+	}
+	dst.db.View(func(txn *badger.Txn) error {
+		payload, err := dst.read(txn, []byte("c"))
+		if h, _, _ := compress.ReadHeader(payload); err != nil || h.Codec != compress.CodecGZip {
+			t.Errorf("expected the gzip header, got %+v (%v)", h, err)
+		}
+		return nil
+	})
+}
+
+func TestPrefix(t *testing.T) {
+	store, done := newTestStore(t, &Config{Codec: "gzip"})
+	defer done()
+
+	for i := 0; i < 2500; i++ {
+		store.Set(fmt.Sprintf("example.com/%04d", i), []byte(fmt.Sprintf("page %d", i)))
+	}
+	store.Set("example.org/", []byte("other"))
+	store.Set("\xffmax", []byte("max"))
+	store.Visited(1)
+
+	var keys []string
+	err := store.SeekPrefix("example.com/00", func(key string, value []byte) error {
+		if want := "page " + strings.TrimLeft(key[len("example.com/"):], "0"); string(value) != want && key != "example.com/0000" {
+			t.Errorf("%s: expected %q, got %q", key, want, value)
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil || len(keys) != 100 || keys[0] != "example.com/0000" || keys[99] != "example.com/0099" {
+		t.Errorf("unexpected keys %v (%v)", keys, err)
+	}
+	stop := errors.New("stop")
+	if err := store.SeekPrefix("", func(string, []byte) error { return stop }); err != stop {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		prefix      string
+		first, last string
+	}{
+		{"example.com/", "example.com/0000", "example.com/2499"},
+		{"example.org", "example.org/", "example.org/"},
+		{"", "example.com/0000", "\xffmax"},
+		{"\xff", "\xffmax", "\xffmax"},
+		{"missing", "", ""},
+		{internalKeyPrefix, "", ""},
+	} {
+		first, _, err := store.First(tt.prefix)
+		last, _, err2 := store.Last(tt.prefix)
+		if err != nil || err2 != nil || first != tt.first || last != tt.last {
+			t.Errorf("%q: expected %q..%q, got %q..%q (%v, %v)", tt.prefix, tt.first, tt.last, first, last, err, err2)
+		}
+	}
+
+	// deleted in several chunks
+	if n, err := store.DeletePrefix("example.com/"); err != nil || n != 2500 {
+		t.Errorf("expected 2500 keys deleted, got %d (%v)", n, err)
+	}
+	if _, ok, _ := store.First("example.com/"); ok {
+		t.Error("expected the keys of the prefix to be deleted")
+	}
+	if v, ok := store.Get("example.org/"); !ok || string(v) != "other" {
+		t.Errorf("expected the other keys to be kept, got %q", v)
+	}
+	if n, _ := store.DeletePrefix(""); n != 2 {
+		t.Errorf("expected 2 keys deleted, got %d", n)
+	}
+	if visited, _ := store.IsVisited(1); !visited {
+		t.Error("expected the internal keys to be kept")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerstorage")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := New(&Config{StoragePath: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := store.Queue("a/b"); err != ErrInvalidQueueName {
+		t.Errorf("expected ErrInvalidQueueName, got %v", err)
+	}
+	q, _ := store.Queue("frontier")
+	if q2, _ := store.Queue("frontier"); q2 != q {
+		t.Error("expected the same queue")
+	}
+	if _, ok, err := q.Pop(); ok || err != nil {
+		t.Errorf("expected an empty queue, got %v", err)
+	}
+	for i := 0; i < 150; i++ {
+		q.Push([]byte(fmt.Sprintf("http://example.com/%d", i)))
+	}
+	if v, ok, _ := q.Peek(); !ok || string(v) != "http://example.com/0" {
+		t.Errorf("unexpected head %q", v)
+	}
+	if v, ok, _ := q.Pop(); !ok || string(v) != "http://example.com/0" {
+		t.Errorf("unexpected head %q", v)
+	}
+	// the other keys are left alone
+	if n, _ := store.DeletePrefix(""); n != 0 {
+		t.Errorf("expected the queue to be internal, deleted %d keys", n)
+	}
+
+	// crash: the lease of the sequence is not released
+	store.queues = nil
+	store.Close()
+	store, err = New(&Config{StoragePath: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer store.Close()
+	q, _ = store.Queue("frontier")
+	q.Push([]byte("http://example.com/150"))
+	if n, err := q.Len(); n != 150 || err != nil {
+		t.Errorf("expected 150 items, got %d (%v)", n, err)
+	}
+	if last, _, _ := store.Last(""); last != "" {
+		t.Errorf("expected no user key, got %q", last)
+	}
+	if v, _, _ := q.Peek(); string(v) != "http://example.com/1" {
+		t.Errorf("unexpected head %q", v)
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		popped []string
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok, err := q.Pop()
+				if err != nil || !ok {
+					return
+				}
+				mu.Lock()
+				popped = append(popped, string(v))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	seen := make(map[string]bool)
+	for _, v := range popped {
+		seen[v] = true
+	}
+	if len(popped) != 150 || len(seen) != 150 || !seen["http://example.com/1"] || !seen["http://example.com/150"] {
+		t.Errorf("expected the 150 items popped once, got %d (%d distinct)", len(popped), len(seen))
+	}
+	if n, _ := q.Len(); n != 0 {
+		t.Errorf("expected an empty queue, got %d items", n)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	src, done := newTestStore(t, &Config{Stats: true, Provider: "crawler"})
+	defer done()
+	dst, done := newTestStore(t, &Config{Stats: true, Provider: "importer"})
+	defer done()
+
+	src.SetWithTTL("page", []byte("value"), time.Hour)
+	src.Get("page")
+	before, _ := src.Stat("page")
+
+	var b bytes.Buffer
+	if err := compress.Export(src, &b, compress.Tar); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the export doesn't count as a read
+	if c, _ := src.Stat("page"); c.Requests != 1 {
+		t.Errorf("expected 1 request, got %d", c.Requests)
+	}
+
+	if err := compress.Import(dst, &b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v, ok := dst.Get("page"); !ok || string(v) != "value" {
+		t.Errorf("unexpected value %q", v)
+	}
+	after, err := dst.Stat("page")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after.Provider != "crawler" || !after.CreatedAt.Equal(before.CreatedAt) || after.Requests != 2 || after.ExpiredAt.IsZero() {
+		t.Errorf("expected the metadata to be restored, got %+v", after)
+	}
+}
@@ -12,8 +12,6 @@ import (
 	// external
 	"github.com/dgraph-io/badger"
 	"github.com/golang/snappy"
-	"github.com/hashicorp/go-msgpack/codec"
-	"gopkg.in/kothar/brotli-go.v0/enc"
 )
 
 // GetChecksum gets the checksum.
@@ -90,33 +88,6 @@ func compressWithGzip(b []byte) *bytes.Buffer {
 	return buf
 }
 
-func compressWithBrotli(input []byte) *bytes.Buffer {
-	params := enc.NewBrotliParams()
-	// brotli supports quality values from 0 to 11 included
-	// 0 is the fastest, 11 is the most compressed but slowest
-	params.SetQuality(11)
-	compressed, _ := enc.CompressBuffer(params, input, make([]byte, 0))
-	buf := bytes.NewBuffer(compressed)
-	return buf
-}
-
-// Decode reverses the encode operation on a byte slice input
-func decodeMsgPack(buf []byte, out interface{}) error {
-	r := bytes.NewBuffer(buf)
-	hd := codec.MsgpackHandle{}
-	dec := codec.NewDecoder(r, &hd)
-	return dec.Decode(out)
-}
-
-// Encode writes an encoded object to a new bytes buffer
-func encodeMsgPack(in interface{}) (*bytes.Buffer, error) {
-	buf := bytes.NewBuffer(nil)
-	hd := codec.MsgpackHandle{}
-	enc := codec.NewEncoder(buf, &hd)
-	err := enc.Encode(in)
-	return buf, err
-}
-
 // Converts bytes to an integer
 func bytesToUint64(b []byte) uint64 {
 	return binary.BigEndian.Uint64(b)
@@ -0,0 +1,236 @@
+package badgerstorage
+
+import (
+	"encoding/binary"
+	"time"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// hitsFlushSize is the number of reads buffered before their counters are
+// written, in a single transaction.
+const hitsFlushSize = 1000
+
+// pendingHits are the reads of an entry not written yet.
+type pendingHits struct {
+	requests int
+	last     time.Time
+}
+
+// hitsKey returns the key of the read counter of key. The reads are counted
+// apart from the values, they would be rewritten by every read otherwise,
+// and folded back in the envelope by the next write.
+func hitsKey(key []byte) []byte {
+	return append([]byte(hitsKeyPrefix), key...)
+}
+
+// recordHits counts a read of keys at now when Config.Stats is enabled. The
+// reads are buffered and written by batches of hitsFlushSize, the last ones
+// are lost on a crash. The caller holds s.mu, read locked: the writes don't
+// race with the flushes.
+func (s *Store) recordHits(keys [][]byte, now time.Time) error {
+	if !s.stats || len(keys) == 0 {
+		return nil
+	}
+	s.hitsMu.Lock()
+	defer s.hitsMu.Unlock()
+
+	if s.hits == nil {
+		s.hits = make(map[string]*pendingHits)
+	}
+	for _, key := range keys {
+		h, ok := s.hits[string(key)]
+		if !ok {
+			h = &pendingHits{}
+			s.hits[string(key)] = h
+		}
+		h.requests++
+		h.last = now
+	}
+	if s.buffered += len(keys); s.buffered < hitsFlushSize {
+		return nil
+	}
+	return s.flushHitsLocked()
+}
+
+// flushHits writes the buffered reads, the caller must hold s.mu.
+func (s *Store) flushHits() error {
+	s.hitsMu.Lock()
+	defer s.hitsMu.Unlock()
+	return s.flushHitsLocked()
+}
+
+// flushHitsLocked writes the buffered reads, s.hitsMu held. The reads are
+// dropped on error.
+func (s *Store) flushHitsLocked() error {
+	hits := s.hits
+	s.hits, s.buffered = nil, 0
+	if len(hits) == 0 {
+		return nil
+	}
+
+	txn := s.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	now := time.Now()
+	for key, h := range hits {
+		err := hit(txn, []byte(key), h, now)
+		if err == badger.ErrTxnTooBig {
+			if err = txn.Commit(nil); err != nil {
+				return err
+			}
+			txn = s.db.NewTransaction(true)
+			err = hit(txn, []byte(key), h, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit(nil)
+}
+
+// bufferedHits returns the reads of key not written yet.
+func (s *Store) bufferedHits(key string) pendingHits {
+	s.hitsMu.Lock()
+	defer s.hitsMu.Unlock()
+	if h, ok := s.hits[key]; ok {
+		return *h
+	}
+	return pendingHits{}
+}
+
+// dropHits forgets the reads of key not written yet, when it is deleted.
+func (s *Store) dropHits(key []byte) {
+	if !s.stats {
+		return
+	}
+	s.hitsMu.Lock()
+	defer s.hitsMu.Unlock()
+	delete(s.hits, string(key))
+}
+
+// hit adds the reads h to the counter of key, which expires with the entry.
+func hit(txn *badger.Txn, key []byte, h *pendingHits, now time.Time) error {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+		if ttl = time.Unix(int64(expiresAt), 0).Sub(now); ttl <= 0 {
+			// about to expire, not worth a write
+			return nil
+		}
+	}
+	requests, _, err := readHits(txn, key)
+	if err != nil {
+		return err
+	}
+	return setWithTTL(txn, hitsKey(key), encodeHits(requests+h.requests, h.last), ttl)
+}
+
+// readHits returns the reads counted for key and the time of the last one.
+func readHits(txn *badger.Txn, key []byte) (int, time.Time, error) {
+	item, err := txn.Get(hitsKey(key))
+	if err == badger.ErrKeyNotFound {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	v, err := item.Value()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if len(v) != 16 {
+		return 0, time.Time{}, storage.ErrInvalidEnvelope
+	}
+	return int(binary.BigEndian.Uint64(v)), time.Unix(0, int64(binary.BigEndian.Uint64(v[8:]))), nil
+}
+
+// encodeHits encodes a read counter, the number of reads and the time of the
+// last one.
+func encodeHits(requests int, last time.Time) []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf, uint64(requests))
+	binary.BigEndian.PutUint64(buf[8:], uint64(last.UnixNano()))
+	return buf
+}
+
+// addHits adds the reads counted for key to c, see statHits for the ones
+// not written yet.
+func addHits(txn *badger.Txn, key []byte, c *Check) error {
+	if !c.Enabled {
+		// the values written before the envelopes are not counted
+		return nil
+	}
+	requests, last, err := readHits(txn, key)
+	if err != nil || requests == 0 {
+		return err
+	}
+	c.Requests += requests
+	if last.After(c.UpdatedAt) {
+		c.UpdatedAt = last
+	}
+	return nil
+}
+
+// foldHits returns prev, the envelope stored at key, with the reads
+// counted for key, and deletes the counter.
+func foldHits(txn *badger.Txn, key, prev []byte) ([]byte, error) {
+	if _, err := txn.Get(hitsKey(key)); err == badger.ErrKeyNotFound {
+		return prev, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if storage.IsEnvelope(prev) {
+		c, err := storage.DecodeCheck(prev)
+		if err != nil {
+			return nil, err
+		}
+		if err := addHits(txn, key, c); err != nil {
+			return nil, err
+		}
+		prev = storage.EncodeEnvelope(c, nil)
+	}
+	return prev, txn.Delete(hitsKey(key))
+}
+
+// statHits adds the reads of key not written yet to c.
+func (s *Store) statHits(key string, c *Check) {
+	if !c.Enabled {
+		return
+	}
+	h := s.bufferedHits(key)
+	c.Requests += h.requests
+	if h.last.After(c.UpdatedAt) {
+		c.UpdatedAt = h.last
+	}
+}
+
+// clearHits deletes the read counter of key, if any.
+func clearHits(txn *badger.Txn, key []byte) error {
+	_, err := txn.Get(hitsKey(key))
+	if err == badger.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return txn.Delete(hitsKey(key))
+}
+
+// deleteEntry deletes key and its reads.
+func (s *Store) deleteEntry(txn *badger.Txn, key []byte) error {
+	if err := txn.Delete(key); err != nil {
+		return err
+	}
+	s.dropHits(key)
+	return clearHits(txn, key)
+}
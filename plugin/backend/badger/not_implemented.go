@@ -4,16 +4,10 @@ import (
 	"errors"
 )
 
-func (s *Store) Init() error {
-	return errors.New("Init method is not implemented yet...")
-}
-
 func (s *Store) Debug(action string) error {
 	// s.listAll()
 	// s.keys()
 	// s.compressor()
-	// s.db.PurgeOlderVersions()
-	// s.purgeOlderVersions()
 	// s.updates()
 	// s.seekPrefix()
 	return errors.New("Debug is not implemented yet")
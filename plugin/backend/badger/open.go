@@ -0,0 +1,50 @@
+package badgerstorage
+
+import (
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+)
+
+// Scheme is the scheme of the badger DSNs, e.g.
+// "badger:///var/lib/colly?bucket=values&compress=snappy&sync=false".
+const Scheme = "badger"
+
+func init() {
+	storage.Register(Scheme, Open)
+}
+
+// Open implements storage.Opener. The path of the DSN is the directory of
+// the database and its bucket the value log directory, relative to it.
+func Open(dsn *storage.DSN) (storage.Storage, error) {
+	config, err := DSNConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return New(config)
+}
+
+// DSNConfig returns the Config described by dsn.
+func DSNConfig(dsn *storage.DSN) (*Config, error) {
+	compressed, err := dsn.CompressCodec(compress.CheckCodec)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultConfig().MergeSingle(Config{
+		StoragePath: dsn.Path,
+		ValueDir:    dsn.Bucket,
+		Compress:    compressed,
+		TTL:         dsn.TTL,
+		ReadOnly:    dsn.ReadOnly,
+	})
+	if config.StoragePath == "" {
+		config.StoragePath = defaultStorePrefixPath
+	}
+	if dsn.Compress != "default" {
+		config.Codec = dsn.Compress
+	}
+	if dsn.Sync != nil {
+		config.SyncWrites = *dsn.Sync
+	}
+	return &config, nil
+}
@@ -0,0 +1,191 @@
+package badgerstorage
+
+import (
+	"strings"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// deleteChunkSize is the number of keys deleted per transaction by
+// DeletePrefix, the transactions are split earlier if badger finds them too
+// big.
+const deleteChunkSize = 1000
+
+// SeekPrefix calls fn with the keys starting with prefix and their value, in
+// order. It stops at the first error returned by fn, and returns it. fn runs
+// inside a read transaction: it may use the store, but the keys it writes
+// are not seen by the iteration.
+func (s *Store) SeekPrefix(prefix string, fn func(key string, value []byte) error) error {
+	if strings.HasPrefix(prefix, internalKeyPrefix) {
+		return nil
+	}
+	return s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(userKey(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+			item := it.Item()
+			// the value is only valid during the transaction
+			v, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			_, value, err := storage.OpenEnvelope(v)
+			if err != nil {
+				return err
+			}
+			if value, err = s.values.Decode(value); err != nil {
+				return err
+			}
+			if err := fn(string(item.Key()), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeletePrefix deletes the keys starting with prefix, e.g. "example.com/"
+// to drop the pages of a host, and returns their number. The keys are
+// deleted in chunks of one transaction each: on error, the chunks committed
+// before are kept. An empty prefix deletes all the keys but the internal
+// ones.
+func (s *Store) DeletePrefix(prefix string) (int, error) {
+	if s.readOnly {
+		return 0, storage.ErrReadOnly
+	}
+	if strings.HasPrefix(prefix, internalKeyPrefix) {
+		return 0, nil
+	}
+	deleted := 0
+	start := userKey(prefix)
+	for {
+		keys, err := s.prefixKeys(prefix, start, deleteChunkSize)
+		if err != nil || len(keys) == 0 {
+			return deleted, err
+		}
+		n, err := s.deleteKeys(keys)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		// the smallest key after the chunk
+		start = append(keys[len(keys)-1], 0)
+	}
+}
+
+// prefixKeys returns at most limit keys starting with prefix, from start.
+func (s *Store) prefixKeys(prefix string, start []byte, limit int) ([][]byte, error) {
+	var keys [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(start); it.ValidForPrefix([]byte(prefix)) && len(keys) < limit; it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// deleteKeys deletes keys, in several transactions if they don't fit in
+// one, and returns the number of keys deleted.
+func (s *Store) deleteKeys(keys [][]byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txn := s.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	start := 0
+	for i, key := range keys {
+		err := s.deleteEntry(txn, key)
+		if err == badger.ErrTxnTooBig {
+			if err := txn.Commit(nil); err != nil {
+				return start, err
+			}
+			start = i
+			txn = s.db.NewTransaction(true)
+			err = s.deleteEntry(txn, key)
+		}
+		if err != nil {
+			return start, err
+		}
+	}
+	if err := txn.Commit(nil); err != nil {
+		return start, err
+	}
+	return len(keys), nil
+}
+
+// First returns the smallest key starting with prefix, false if there is
+// none.
+func (s *Store) First(prefix string) (string, bool, error) {
+	return s.bound(prefix, false)
+}
+
+// Last returns the largest key starting with prefix, false if there is
+// none.
+func (s *Store) Last(prefix string) (string, bool, error) {
+	return s.bound(prefix, true)
+}
+
+// bound returns the first key starting with prefix in the order of the
+// iteration, reverse for the largest.
+func (s *Store) bound(prefix string, reverse bool) (key string, ok bool, err error) {
+	if strings.HasPrefix(prefix, internalKeyPrefix) {
+		return "", false, nil
+	}
+	err = s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Reverse = reverse
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		if !reverse {
+			it.Seek(userKey(prefix))
+		} else if end := prefixEnd(prefix); end != nil {
+			// the largest key lower or equal to end
+			it.Seek(end)
+			if it.Valid() && string(it.Item().Key()) == string(end) {
+				it.Next()
+			}
+		} else {
+			it.Rewind()
+		}
+		if it.ValidForPrefix([]byte(prefix)) && !strings.HasPrefix(string(it.Item().Key()), internalKeyPrefix) {
+			key, ok = string(it.Item().Key()), true
+		}
+		return nil
+	})
+	return key, ok, err
+}
+
+// userKey returns the first user key starting with prefix, after the
+// internal keys.
+func userKey(prefix string) []byte {
+	if prefix < userKeysStart {
+		return []byte(userKeysStart)
+	}
+	return []byte(prefix)
+}
+
+// prefixEnd returns the smallest key greater than all the keys starting with
+// prefix, nil if there is none.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package badgerstorage
+
+import (
+	"fmt"
+	"sort"
+
+	// external
+	"github.com/dgraph-io/badger/options"
+)
+
+// The names of Config.TableLoadingMode and Config.ValueLogLoadingMode.
+const (
+	// LoadingModeFileIO reads the files with standard I/O.
+	LoadingModeFileIO = "fileio"
+
+	// LoadingModeRAM loads the files into RAM, the tables only.
+	LoadingModeRAM = "ram"
+
+	// LoadingModeMMap memory-maps the files.
+	LoadingModeMMap = "mmap"
+
+	//-- End
+)
+
+var loadingModes = map[string]options.FileLoadingMode{
+	LoadingModeFileIO: options.FileIO,
+	LoadingModeRAM:    options.LoadToRAM,
+	LoadingModeMMap:   options.MemoryMap,
+}
+
+// The names of the profiles of Config.Profile.
+const (
+	// ProfileDefault keeps badger's defaults, the profile of an empty name.
+	ProfileDefault = "default"
+
+	// ProfileLowMemory reads the files instead of mapping them and keeps a
+	// single small memtable, for the small machines.
+	ProfileLowMemory = "low-memory"
+
+	// ProfileBulkLoad keeps more memtables and level 0 tables before
+	// stalling the writes, for the first crawls. It is best combined with
+	// SyncWrites false, see profiles.
+	ProfileBulkLoad = "bulk-load"
+
+	//-- End
+)
+
+// profiles holds the flags of the profiles, they are merged in the unset
+// flags of the configs. The booleans (SyncWrites, Truncate, DoNotCompact...)
+// can't be set by a profile: false can't be told from unset.
+var profiles = map[string]Config{
+	ProfileDefault: {},
+	ProfileLowMemory: {
+		TableLoadingMode:        LoadingModeFileIO,
+		ValueLogLoadingMode:     LoadingModeFileIO,
+		MaxTableSize:            16 << 20,
+		NumMemtables:            1,
+		NumLevelZeroTables:      1,
+		NumLevelZeroTablesStall: 2,
+		LevelOneSize:            64 << 20,
+		ValueLogFileSize:        64 << 20,
+		NumCompactors:           1,
+	},
+	ProfileBulkLoad: {
+		MaxTableSize:            128 << 20,
+		NumMemtables:            8,
+		NumLevelZeroTables:      10,
+		NumLevelZeroTablesStall: 20,
+		LevelOneSize:            1 << 30,
+		NumCompactors:           4,
+	},
+}
+
+// Profile returns the flags of the named profile, the ones of
+// ProfileDefault if name is empty.
+func Profile(name string) (Config, error) {
+	if name == "" {
+		name = ProfileDefault
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return Config{}, fmt.Errorf("%w: unknown profile %q (profiles: %v)", ErrInvalidConfig, name, Profiles())
+	}
+	return profile, nil
+}
+
+// Profiles returns the sorted names of the profiles.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,225 @@
+package badgerstorage
+
+import (
+	"strings"
+	"sync"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// queueBandwidth is the number of IDs leased at once by the sequences of
+// the queues. The IDs leased and not used are lost on a crash, leaving a
+// gap in the order of the items.
+const queueBandwidth = 100
+
+// Queue is a persistent FIFO queue of a Store, e.g. the frontier of the
+// URLs to visit. The items are internal keys ordered by the IDs of a badger
+// sequence, the length and the ID of the head are updated in the same
+// transactions: a pushed item is kept until popped, across crashes when
+// Config.SyncWrites is set. The queues are safe for concurrent use, each
+// item is popped once.
+type Queue struct {
+	store *Store
+	// prefix starts the keys of the queue, followed by "items/" and the IDs
+	prefix string
+
+	// mu serializes the pushes and the pops of the queue, the items are
+	// committed in the order of their IDs; seq is created by the first push
+	mu  sync.Mutex
+	seq *badger.Sequence
+}
+
+// Queue returns the queue of the given name, created empty by the first
+// push. The same name returns the same queue, its sequence is released by
+// Close.
+func (s *Store) Queue(name string) (*Queue, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, ErrInvalidQueueName
+	}
+	s.queuesMu.Lock()
+	defer s.queuesMu.Unlock()
+
+	if q, ok := s.queues[name]; ok {
+		return q, nil
+	}
+	q := &Queue{store: s, prefix: queueKeyPrefix + name + "/"}
+	if s.queues == nil {
+		s.queues = make(map[string]*Queue)
+	}
+	s.queues[name] = q
+	return q, nil
+}
+
+// Push appends value to the queue.
+func (q *Queue) Push(value []byte) error {
+	if q.store.readOnly {
+		return storage.ErrReadOnly
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	id, err := q.next()
+	if err != nil {
+		return err
+	}
+	return q.store.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(q.itemKey(id), value); err != nil {
+			return err
+		}
+		return q.add(txn, 1)
+	})
+}
+
+// Pop removes and returns the oldest item of the queue, false if the queue
+// is empty.
+func (q *Queue) Pop() (value []byte, ok bool, err error) {
+	if q.store.readOnly {
+		return nil, false, storage.ErrReadOnly
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	err = q.store.db.Update(func(txn *badger.Txn) error {
+		id, v, err := q.first(txn)
+		if err != nil || v == nil {
+			return err
+		}
+		if err := txn.Delete(q.itemKey(id)); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte(q.prefix+"head"), uint64ToBytes(id+1)); err != nil {
+			return err
+		}
+		value, ok = v, true
+		return q.add(txn, -1)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return value, ok, nil
+}
+
+// Peek returns the oldest item of the queue without removing it, false if
+// the queue is empty.
+func (q *Queue) Peek() (value []byte, ok bool, err error) {
+	err = q.store.db.View(func(txn *badger.Txn) error {
+		_, v, err := q.first(txn)
+		value, ok = v, v != nil
+		return err
+	})
+	return value, ok, err
+}
+
+// Len returns the number of items of the queue.
+func (q *Queue) Len() (int, error) {
+	var n uint64
+	err := q.store.db.View(func(txn *badger.Txn) error {
+		var err error
+		n, err = q.len(txn)
+		return err
+	})
+	return int(n), err
+}
+
+// Close releases the IDs leased by the queue and not used, the queue can
+// still be used after. Store.Close closes the queues.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.seq == nil {
+		return nil
+	}
+	return q.seq.Release()
+}
+
+// next returns the ID of the next item pushed, q.mu held.
+func (q *Queue) next() (uint64, error) {
+	if q.seq == nil {
+		seq, err := q.store.db.GetSequence([]byte(q.prefix+"seq"), queueBandwidth)
+		if err != nil {
+			return 0, err
+		}
+		q.seq = seq
+	}
+	return q.seq.Next()
+}
+
+// first returns the ID and the value of the oldest item, a nil value if the
+// queue is empty. The items are looked up from the head: the deletes of the
+// items popped before are not scanned again.
+func (q *Queue) first(txn *badger.Txn) (id uint64, value []byte, err error) {
+	head, err := q.uint64(txn, "head")
+	if err != nil {
+		return 0, nil, err
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchSize = 1
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	// the IDs leased and not used leave gaps after the head
+	items := []byte(q.prefix + "items/")
+	it.Seek(q.itemKey(head))
+	if !it.ValidForPrefix(items) {
+		return 0, nil, nil
+	}
+	if value, err = it.Item().ValueCopy(nil); err != nil {
+		return 0, nil, err
+	}
+	return bytesToUint64(it.Item().Key()[len(items):]), value, nil
+}
+
+// itemKey returns the key of the item of the given ID, the big endian IDs
+// keep the keys in order.
+func (q *Queue) itemKey(id uint64) []byte {
+	return append([]byte(q.prefix+"items/"), uint64ToBytes(id)...)
+}
+
+// len returns the length recorded in txn.
+func (q *Queue) len(txn *badger.Txn) (uint64, error) {
+	return q.uint64(txn, "len")
+}
+
+// uint64 returns the counter of the queue of the given name, zero if unset.
+func (q *Queue) uint64(txn *badger.Txn, name string) (uint64, error) {
+	item, err := txn.Get([]byte(q.prefix + name))
+	if err == badger.ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value, err := item.Value()
+	if err != nil {
+		return 0, err
+	}
+	return bytesToUint64(value), nil
+}
+
+// add adds delta to the length recorded in txn.
+func (q *Queue) add(txn *badger.Txn, delta int) error {
+	n, err := q.len(txn)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(q.prefix+"len"), uint64ToBytes(uint64(int64(n)+int64(delta))))
+}
+
+// closeQueues closes the queues of the store.
+func (s *Store) closeQueues() error {
+	s.queuesMu.Lock()
+	defer s.queuesMu.Unlock()
+
+	var err error
+	for _, q := range s.queues {
+		if e := q.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
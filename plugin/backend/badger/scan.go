@@ -0,0 +1,98 @@
+package badgerstorage
+
+import (
+	"strings"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Scanner = (*Store)(nil)
+
+// userKeysStart is the first key after the internal keys.
+const userKeysStart = "\x01"
+
+// Scan implements storage.Scanner.Scan(), the values are not fetched.
+func (s *Store) Scan(prefix, startAfter string, limit int) (keys []string, next string, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	err = s.scan(prefix, startAfter, limit, false, func(item *badger.Item) error {
+		keys = append(keys, string(item.Key()))
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return keys, next, nil
+}
+
+// ScanValues implements storage.Scanner.ScanValues(), the values of a page
+// are prefetched.
+func (s *Store) ScanValues(prefix, startAfter string, limit int) (kvs []storage.KV, next string, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	err = s.scan(prefix, startAfter, limit, true, func(item *badger.Item) error {
+		// the value is only valid during the transaction
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		_, value, err := storage.OpenEnvelope(v)
+		if err != nil {
+			return err
+		}
+		if value, err = s.values.Decode(value); err != nil {
+			return err
+		}
+		kvs = append(kvs, storage.KV{Key: string(item.Key()), Value: value})
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return kvs, next, nil
+}
+
+// scan calls fn for the items of a page and sets next to the cursor of the
+// following one. The internal keys are skipped.
+func (s *Store) scan(prefix, startAfter string, limit int, prefetch bool, fn func(item *badger.Item) error, next *string) error {
+	*next = ""
+	if strings.HasPrefix(prefix, internalKeyPrefix) {
+		return nil
+	}
+	start := storage.ScanStart(prefix, startAfter)
+	if start < userKeysStart {
+		start = userKeysStart
+	}
+
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = prefetch
+		if limit > 0 && limit < opts.PrefetchSize {
+			opts.PrefetchSize = limit
+		}
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		var last []byte
+		n := 0
+		for it.Seek([]byte(start)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+			if limit > 0 && n == limit {
+				*next = string(last)
+				break
+			}
+			item := it.Item()
+			if err := fn(item); err != nil {
+				return err
+			}
+			last = item.KeyCopy(last)
+			n++
+		}
+		return nil
+	})
+}
@@ -0,0 +1,54 @@
+package badgerstorage
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	// external
+	"github.com/dgraph-io/badger"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Streamer = (*Store)(nil)
+
+// SetReader implements storage.Streamer.SetReader(). Badger needs the whole
+// value to write it, r is compressed in memory while it is read and only the
+// compressed value is held.
+func (s *Store) SetReader(key string, r io.Reader) error {
+	var buf bytes.Buffer
+	if err := s.values.EncodeStream(key, &buf, r); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return s.put(txn, []byte(key), buf.Bytes(), s.ttl)
+	})
+}
+
+// GetReader implements storage.Streamer.GetReader(), the compressed value
+// is copied out of badger and decompressed while it is read.
+func (s *Store) GetReader(key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var payload []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err error
+		payload, err = s.read(txn, []byte(key))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.recordHits([][]byte{[]byte(key)}, time.Now())
+	return s.values.DecodeStream(payload)
+}
@@ -0,0 +1,99 @@
+package boltdbstorage
+
+import (
+	"fmt"
+	"time"
+
+	// external
+	"github.com/boltdb/bolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Batcher = (*Store)(nil)
+
+// WriteBatch implements storage.Batcher.WriteBatch(), the batch is applied
+// in a single Update.
+func (s *Store) WriteBatch(b *storage.Batch) error {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	expiresAt := storage.Deadline(now, s.ttl)
+	errs := make(map[string]error)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(s.bucketName))
+		if bkt == nil {
+			return fmt.Errorf("boltdbstore.WriteBatch(): could not reach the bucket: %s", s.bucketName)
+		}
+		for _, op := range b.Ops {
+			var err error
+			if op.Delete {
+				err = s.delete(tx, bkt, []byte(op.Key))
+			} else {
+				err = s.put(tx, bkt, []byte(op.Key), op.Value, storage.Deadline(now, op.TTL(s.ttl)))
+			}
+			if err != nil {
+				errs[op.Key] = err
+			}
+		}
+		if len(errs) > 0 {
+			// roll back the transaction
+			return storage.AbortBatch(b, errs)
+		}
+		return nil
+	})
+	if err == nil && !expiresAt.IsZero() {
+		s.startSweeper()
+	}
+	return err
+}
+
+// SetMany implements storage.Batcher.SetMany()
+func (s *Store) SetMany(kvs []storage.KV) error {
+	return s.WriteBatch(storage.SetBatch(kvs))
+}
+
+// DeleteMany implements storage.Batcher.DeleteMany()
+func (s *Store) DeleteMany(keys []string) error {
+	return s.WriteBatch(storage.DeleteBatch(keys))
+}
+
+// GetMany implements storage.Batcher.GetMany(), the values are read in a
+// single View.
+func (s *Store) GetMany(keys []string) (map[string][]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	now := time.Now()
+	values := make(map[string][]byte, len(keys))
+	errs := make(storage.BatchError)
+	var hits [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(s.bucketName))
+		if bkt == nil {
+			return fmt.Errorf("boltdbstore.GetMany(): could not reach the bucket: %s", s.bucketName)
+		}
+		for _, key := range keys {
+			value, err := s.read(tx, bkt, []byte(key), now)
+			if err != nil {
+				errs[key] = err
+				continue
+			}
+			if value != nil {
+				values[key] = value
+				hits = append(hits, []byte(key))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordHits(hits...)
+	if len(errs) > 0 {
+		return values, errs
+	}
+	return values, nil
+}
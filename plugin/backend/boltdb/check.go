@@ -0,0 +1,80 @@
+package boltdbstorage
+
+import (
+	"time"
+
+	// external
+	"github.com/boltdb/bolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// Check is the metadata kept with every entry, see Stat.
+type Check = storage.Check
+
+var (
+	_ storage.Stater      = (*Store)(nil)
+	_ storage.CheckSetter = (*Store)(nil)
+	_ storage.Peeker      = (*Store)(nil)
+)
+
+// Stat implements storage.Stater.Stat(), it doesn't count as a read. The
+// requests are only counted when Config.Stats is enabled.
+func (s *Store) Stat(key string) (check *Check, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		check, err = s.buckets().Stat(kvTx{tx}, key, time.Now(), s.hits.Buffered(key))
+		return err
+	})
+	return check, err
+}
+
+// SetWithCheck implements storage.CheckSetter.SetWithCheck()
+func (s *Store) SetWithCheck(key string, resp []byte, check *Check) error {
+	if !check.ExpiredAt.IsZero() && !check.ExpiredAt.After(time.Now()) {
+		return nil
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return s.buckets().Restore(kvTx{tx}, key, resp, check)
+	})
+	if err == nil && !check.ExpiredAt.IsZero() {
+		s.startSweeper()
+	}
+	return err
+}
+
+// read returns a copy of the payload stored at key, nil if it is missing or
+// expired. The read is not counted, see recordHits.
+func (s *Store) read(tx *bolt.Tx, bkt *bolt.Bucket, key []byte, now time.Time) ([]byte, error) {
+	return s.buckets().Read(kvTx{tx}, kvBucket{bkt}, key, now)
+}
+
+// recordHits counts a read of keys when Config.Stats is enabled. The reads
+// are buffered and written by batches of boltkv.HitsFlushSize, the last
+// ones are lost on a crash. The caller holds the read lock of s, outside of
+// any transaction: the writes don't race with the flushes.
+func (s *Store) recordHits(keys ...[]byte) {
+	if !s.stats || len(keys) == 0 {
+		return
+	}
+	if s.hits.Record(keys, time.Now()) {
+		s.flushHits()
+	}
+}
+
+// flushHits writes the buffered reads, they are dropped on error.
+func (s *Store) flushHits() error {
+	hits := s.hits.Take()
+	if len(hits) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return s.buckets().WriteHits(kvTx{tx}, hits, time.Now())
+	})
+}
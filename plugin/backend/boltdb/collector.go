@@ -0,0 +1,46 @@
+package boltdbstorage
+
+import (
+	"net/url"
+
+	// external
+	"github.com/boltdb/bolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.CollectorStorage = (*Store)(nil)
+
+// Visited implements storage.CollectorStorage.Visited()
+func (s *Store) Visited(requestID uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return s.buckets().Visited(kvTx{tx}, requestID)
+	})
+}
+
+// IsVisited implements storage.CollectorStorage.IsVisited()
+func (s *Store) IsVisited(requestID uint64) (visited bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		visited = s.buckets().IsVisited(kvTx{tx}, requestID)
+		return nil
+	})
+	return
+}
+
+// Cookies implements storage.CollectorStorage.Cookies()
+func (s *Store) Cookies(u *url.URL) (cookies string) {
+	s.db.View(func(tx *bolt.Tx) error {
+		cookies = s.buckets().Cookies(kvTx{tx}, u.Host)
+		return nil
+	})
+	return
+}
+
+// SetCookies implements storage.CollectorStorage.SetCookies(), the cookies
+// are merged with the ones already stored for the host.
+func (s *Store) SetCookies(u *url.URL, cookies string) {
+	s.db.Update(func(tx *bolt.Tx) error {
+		return s.buckets().SetCookies(kvTx{tx}, u.Host, cookies)
+	})
+}
@@ -1,6 +1,8 @@
 package boltdbstorage
 
 import (
+	"time"
+
 	"github.com/imdario/mergo"
 )
 
@@ -10,6 +12,30 @@ type Config struct {
 	BucketName  string
 	StoragePath string
 	Debug       bool
+
+	// ReadOnly opens the database read-only, the buckets must exist and the
+	// writes fail.
+	ReadOnly bool
+
+	// NoSync skips the fsync after each commit, faster but unsafe on crash.
+	NoSync bool
+
+	// Stats records the reads in the metadata of the entries, see Stat.
+	// The reads are buffered and written by batches to counters apart from
+	// the values, the last ones are lost on a crash.
+	Stats bool
+
+	// Provider is the name of the crawler recorded in the metadata of the
+	// entries written.
+	Provider string
+
+	// TTL is the time to live of the entries written by Set, zero means
+	// they never expire.
+	TTL time.Duration
+
+	// SweepInterval is the interval between two sweeps of the expired
+	// entries, storage.DefaultSweepInterval if zero.
+	SweepInterval time.Duration
 }
 
 // DefaultConfig returns the default configuration for this serializer
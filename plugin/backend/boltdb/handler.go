@@ -5,12 +5,15 @@ import (
 	// "path/filepath"
 	"fmt"
 	"sync"
+	"time"
 
 	// external
 	"github.com/boltdb/bolt"
 
 	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
 	helper "github.com/sniperkit/colly-storage/pkg/helper"
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
 )
 
 var (
@@ -24,11 +27,24 @@ type Store struct {
 	bucketName  string
 	fp          string
 	debug       bool
+	stats       bool
+	// hits buffers the reads counted when stats is set, see recordHits
+	hits     boltkv.Hits
+	provider string
+	// ttl is the default time to live of the entries, the sweeper is
+	// started with the first entry having one
+	ttl           time.Duration
+	sweepInterval time.Duration
+	sweeper       *boltkv.Sweeper
+
+	actionsOnce sync.Once
+	actions     *storage.ActionRegistry
 }
 
 func New(config *Config) (*Store, error) {
 	if config == nil {
-		config.StoragePath = DefaultStorageFile
+		defaultConfig := DefaultConfig()
+		config = &defaultConfig
 	}
 	if config.StoragePath == "" {
 		return nil, errors.New("boltdbstore.New(): Storage path is not defined.")
@@ -39,6 +55,13 @@ func New(config *Config) (*Store, error) {
 
 	store := &Store{}
 	store.debug = config.Debug
+	store.storagePath = config.StoragePath
+	store.bucketName = config.BucketName
+	// the reads are not recorded on a read-only database
+	store.stats = config.Stats && !config.ReadOnly
+	store.provider = config.Provider
+	store.ttl = config.TTL
+	store.sweepInterval = config.SweepInterval
 
 	if err := helper.EnsurePathExists(config.StoragePath); err != nil {
 		return nil, err
@@ -47,89 +70,115 @@ func New(config *Config) (*Store, error) {
 	}
 
 	var err error
-	store.db, err = bolt.Open(config.StoragePath, 0600, nil)
+	store.db, err = bolt.Open(config.StoragePath, 0600, &bolt.Options{ReadOnly: config.ReadOnly})
 	if err != nil {
 		return nil, err
 	}
-
-	init := func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(config.BucketName))
-		return err
+	store.db.NoSync = config.NoSync
+	if config.ReadOnly {
+		return store, nil
 	}
 
-	if err := store.db.Update(init); err != nil {
+	if err := store.Init(); err != nil {
 		if err := store.db.Close(); err != nil {
 			return nil, err
 		}
 		return nil, err
 	}
+	if err := store.startSweeperIfNeeded(); err != nil {
+		store.db.Close()
+		return nil, err
+	}
 	return store, nil
 }
 
 // Mount returns a new Cache using the provided (and opened) bolt database.
+// Init must be called to create the buckets if they don't exist yet.
 func Mount(db *bolt.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, bucketName: StorageBucketName}
 }
 
-// Close closes the underlying boltdb database.
+// Init creates the buckets used by the store if they don't exist yet.
+func (s *Store) Init() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range s.buckets().Names() {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close stops the sweeper, writes the buffered reads and closes the
+// underlying boltdb database.
 func (s *Store) Close() error {
+	s.stopSweeper()
+	s.Lock()
+	err := s.flushHits()
+	s.Unlock()
+	if err != nil {
+		s.db.Close()
+		return err
+	}
 	return s.db.Close()
 }
 
 // Get retrieves the response corresponding to the given key if present.
 func (s *Store) Get(key string) (resp []byte, ok bool) {
+	return s.get(key, true)
+}
+
+// Peek implements storage.Peeker.Peek(), the read isn't recorded by
+// Config.Stats.
+func (s *Store) Peek(key string) (resp []byte, ok bool) {
+	return s.get(key, false)
+}
+
+// get returns the response stored at key, the read is recorded if hit is
+// set.
+func (s *Store) get(key string, hit bool) (resp []byte, ok bool) {
 	s.RLock()
+	defer s.RUnlock()
 
 	get := func(tx *bolt.Tx) error {
 		bkt := tx.Bucket([]byte(s.bucketName))
 		if bkt == nil {
 			return errors.New("bucket is nil")
 		}
-		resp = bkt.Get([]byte(key))
-		return nil
+		var err error
+		resp, err = s.read(tx, bkt, []byte(key), time.Now())
+		return err
+	}
+	err := s.db.View(get)
+	if hit && err == nil && resp != nil {
+		s.recordHits([]byte(key))
 	}
-	if err := s.db.View(get); err != nil {
+	if err != nil {
 		return resp, false
 	}
-	s.RUnlock()
 	return resp, resp != nil
 }
 
-// Set stores a response to the store at the given key.
+// Set stores a response to the store at the given key, expiring after
+// Config.TTL when set.
 func (s *Store) Set(key string, resp []byte) error {
-	s.Lock()
-
-	set := func(tx *bolt.Tx) error {
-		bkt := tx.Bucket([]byte(s.bucketName))
-		if bkt == nil {
-			return errors.New("bucket is nil")
-		}
-		return bkt.Put([]byte(key), resp)
-	}
-	s.db.Update(set)
-	if err := s.db.Update(set); err != nil {
-		return err
-	}
-	s.Unlock()
-	return nil
+	return s.SetWithTTL(key, resp, s.ttl)
 }
 
 // Delete removes the response with the given key from the store.
 func (s *Store) Delete(key string) error {
 	s.Lock()
+	defer s.Unlock()
 
 	del := func(tx *bolt.Tx) error {
 		bkt := tx.Bucket([]byte(s.bucketName))
 		if bkt == nil {
 			return errors.New("bucket is nil")
 		}
-		return bkt.Delete([]byte(key))
-	}
-	if err := s.db.Update(del); err != nil {
-		return err
+		return s.delete(tx, bkt, []byte(key))
 	}
-	s.Unlock()
-	return nil
+	return s.db.Update(del)
 }
 
 // Ping connects to the database. Returns nil if successful.
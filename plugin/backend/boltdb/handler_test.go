@@ -1 +1,309 @@
 package boltdbstorage
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	// external
+	"github.com/boltdb/bolt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		dir   string
+		store *Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "boltdbstorage")
+		Expect(err).NotTo(HaveOccurred())
+		store, err = New(&Config{StoragePath: filepath.Join(dir, "test.boltdb")})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		store.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("stores and deletes entries", func() {
+		Expect(store.Set("hello", []byte("world"))).To(Succeed())
+		v, ok := store.Get("hello")
+		Expect(ok).To(BeTrue())
+		Expect(string(v)).To(Equal("world"))
+
+		Expect(store.Delete("hello")).To(Succeed())
+		_, ok = store.Get("hello")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("persists visited requests and cookies", func() {
+		visited, err := store.IsVisited(42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(visited).To(BeFalse())
+
+		Expect(store.Visited(42)).To(Succeed())
+		visited, err = store.IsVisited(42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(visited).To(BeTrue())
+
+		u, _ := url.Parse("http://example.com/")
+		store.SetCookies(u, "a=1")
+		store.SetCookies(u, "a=2\nb=3")
+		Expect(store.Cookies(u)).To(Equal("a=2\nb=3"))
+	})
+
+	It("scans the keys by page", func() {
+		for _, k := range []string{"a/1", "a/2", "a/3", "b/1"} {
+			Expect(store.Set(k, []byte("v"+k))).To(Succeed())
+		}
+
+		keys, next, err := store.Scan("a/", "", 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"a/1", "a/2"}))
+		Expect(next).To(Equal("a/2"))
+
+		kvs, next, err := store.ScanValues("a/", next, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvs).To(HaveLen(1))
+		Expect(kvs[0].Key).To(Equal("a/3"))
+		Expect(string(kvs[0].Value)).To(Equal("va/3"))
+		Expect(next).To(BeEmpty())
+
+		keys, _, err = store.Scan("", "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(HaveLen(4))
+	})
+
+	It("applies batches atomically", func() {
+		Expect(store.Set("old", []byte("value"))).To(Succeed())
+
+		b := storage.NewBatch()
+		b.Set("a", []byte("1"))
+		b.Delete("old")
+		Expect(store.WriteBatch(b)).To(Succeed())
+
+		values, err := store.GetMany([]string{"a", "old"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(HaveLen(1))
+		Expect(string(values["a"])).To(Equal("1"))
+
+		b = storage.NewBatch()
+		b.Set("b", []byte("2"))
+		b.Set("", []byte("empty"))
+		err = store.WriteBatch(b)
+		var batchErr storage.BatchError
+		Expect(errors.As(err, &batchErr)).To(BeTrue())
+		Expect(batchErr[""]).To(HaveOccurred())
+		Expect(batchErr["b"]).To(Equal(storage.ErrBatchAborted))
+		_, ok := store.Get("b")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("expires the entries", func() {
+		Expect(store.SetWithTTL("short", []byte("v"), 20*time.Millisecond)).To(Succeed())
+		Expect(store.Set("forever", []byte("v"))).To(Succeed())
+		_, ok := store.Get("short")
+		Expect(ok).To(BeTrue())
+
+		time.Sleep(30 * time.Millisecond)
+		// expired entries read as not found before being swept
+		_, ok = store.Get("short")
+		Expect(ok).To(BeFalse())
+		keys, _, err := store.Scan("", "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"forever"}))
+
+		n, err := store.Sweep()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+		n, err = store.Sweep()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(0))
+	})
+
+	It("applies the default TTL and sweeps in background", func() {
+		store.Close()
+		var err error
+		store, err = New(&Config{
+			StoragePath:   filepath.Join(dir, "ttl.db"),
+			TTL:           20 * time.Millisecond,
+			SweepInterval: 5 * time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.Set("a", []byte("1"))).To(Succeed())
+		Expect(store.SetWithTTL("b", []byte("2"), 0)).To(Succeed())
+		Eventually(func() int {
+			n := 0
+			store.db.View(func(tx *bolt.Tx) error {
+				n = tx.Bucket([]byte(store.bucketName)).Stats().KeyN
+				return nil
+			})
+			return n
+		}).Should(Equal(1))
+	})
+
+	It("keeps the metadata of the entries", func() {
+		store.Close()
+		var err error
+		store, err = New(&Config{
+			StoragePath: filepath.Join(dir, "stats.db"),
+			Stats:       true,
+			Provider:    "crawler",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.Stat("missing")
+		Expect(err).To(Equal(storage.ErrNotFound))
+
+		Expect(store.SetWithTTL("page", []byte("v1"), time.Hour)).To(Succeed())
+		store.Get("page")
+		store.Get("page")
+		first, err := store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Requests).To(Equal(2))
+		Expect(first.Provider).To(Equal("crawler"))
+		Expect(first.ExpiredAt.IsZero()).To(BeFalse())
+
+		Expect(store.Set("page", []byte("v2"))).To(Succeed())
+		second, err := store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.CreatedAt).To(Equal(first.CreatedAt))
+		Expect(second.Requests).To(Equal(2))
+		Expect(second.ExpiredAt.IsZero()).To(BeTrue())
+
+		v, ok := store.Get("page")
+		Expect(ok).To(BeTrue())
+		Expect(string(v)).To(Equal("v2"))
+
+		// Peek doesn't count as a read, SetWithCheck restores the metadata
+		v, ok = store.Peek("page")
+		Expect(ok).To(BeTrue())
+		Expect(string(v)).To(Equal("v2"))
+		third, err := store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(third.Requests).To(Equal(3))
+
+		third.Provider = "importer"
+		third.ExpiredAt = time.Now().Add(time.Hour).Round(0)
+		Expect(store.SetWithCheck("copy", []byte("v3"), third)).To(Succeed())
+		restored, err := store.Stat("copy")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Key).To(Equal("copy"))
+		Expect(restored.Requests).To(Equal(3))
+		Expect(restored.Provider).To(Equal("importer"))
+		Expect(restored.CreatedAt).To(Equal(first.CreatedAt))
+		Expect(restored.ExpiredAt.Equal(third.ExpiredAt)).To(BeTrue())
+
+		third.ExpiredAt = time.Now().Add(-time.Minute)
+		Expect(store.SetWithCheck("expired", []byte("v3"), third)).To(Succeed())
+		_, ok = store.Get("expired")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("buffers the reads", func() {
+		store.Close()
+		path := filepath.Join(dir, "hits.db")
+		var err error
+		store, err = New(&Config{StoragePath: path, Stats: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Set("page", []byte("v1"))).To(Succeed())
+		counted := func() (n int) {
+			store.db.View(func(tx *bolt.Tx) error {
+				n = tx.Bucket([]byte(store.buckets().HitsBucket())).Stats().KeyN
+				return nil
+			})
+			return n
+		}
+
+		// the reads don't write the value
+		writes := store.db.Stats().TxStats.Write
+		for i := 0; i < 10; i++ {
+			store.Get("page")
+		}
+		Expect(store.db.Stats().TxStats.Write).To(Equal(writes))
+		check, err := store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Requests).To(Equal(10))
+
+		// written by batches and on Close
+		for i := 10; i < boltkv.HitsFlushSize; i++ {
+			store.Get("page")
+		}
+		Expect(counted()).To(Equal(1))
+		store.Get("page")
+		Expect(store.Close()).To(Succeed())
+		store, err = New(&Config{StoragePath: path, Stats: true})
+		Expect(err).NotTo(HaveOccurred())
+		check, err = store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Requests).To(Equal(boltkv.HitsFlushSize + 1))
+
+		// folded in the envelope by the next write, dropped with the entry
+		Expect(store.Set("page", []byte("v2"))).To(Succeed())
+		Expect(counted()).To(Equal(0))
+		check, err = store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Requests).To(Equal(boltkv.HitsFlushSize + 1))
+		store.Get("page")
+		Expect(store.Delete("page")).To(Succeed())
+		Expect(store.Set("page", []byte("v3"))).To(Succeed())
+		check, err = store.Stat("page")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Requests).To(Equal(0))
+	})
+
+	It("declares its actions", func() {
+		Expect(store.Set("a", []byte("1"))).To(Succeed())
+		Expect(store.Visited(1)).To(Succeed())
+
+		specs := store.Actions()
+		Expect(specs).To(HaveLen(2))
+		Expect(specs[0].Name).To(Equal("getKeys"))
+		Expect(specs[1].Name).To(Equal("stats"))
+		keys, err := store.Do("getKeys")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"a"}))
+		stats, err := store.Do("stats")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(BeAssignableToTypeOf(bolt.Stats{}))
+
+		// the legacy result of getKeys
+		legacy, err := store.Action("getKeys")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(legacy).To(HaveLen(1))
+		Expect(legacy).To(HaveKey("a"))
+		_, err = store.Do("getKeys", "extra")
+		Expect(errors.Is(err, storage.ErrInvalidArgs)).To(BeTrue())
+	})
+
+	It("opens from a DSN", func() {
+		path := filepath.Join(dir, "dsn.db")
+		s, err := storage.Open("boltdb://" + path + "?bucket=pages&ttl=1h")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.(*Store).bucketName).To(Equal("pages"))
+		Expect(s.(*Store).ttl).To(Equal(time.Hour))
+		Expect(s.Set("page", []byte("value"))).To(Succeed())
+		Expect(s.(*Store).Close()).To(Succeed())
+
+		s, err = storage.Open("boltdb://" + path + "?bucket=pages&read-only=true")
+		Expect(err).NotTo(HaveOccurred())
+		defer s.(*Store).Close()
+		v, ok := s.Get("page")
+		Expect(ok).To(BeTrue())
+		Expect(string(v)).To(Equal("value"))
+		Expect(s.Set("page", []byte("other"))).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,40 @@
+package boltdbstorage
+
+import (
+	// external
+	"github.com/boltdb/bolt"
+
+	// internal
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
+)
+
+// kvTx adapts a bolt transaction to boltkv, which holds the bucket logic
+// shared with the bbolt store.
+type kvTx struct{ *bolt.Tx }
+
+func (tx kvTx) Bucket(name []byte) boltkv.Bucket {
+	if bkt := tx.Tx.Bucket(name); bkt != nil {
+		return kvBucket{bkt}
+	}
+	return nil
+}
+
+func (tx kvTx) CreateBucketIfNotExists(name []byte) (boltkv.Bucket, error) {
+	bkt, err := tx.Tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return kvBucket{bkt}, nil
+}
+
+// kvBucket adapts a bolt bucket to boltkv.
+type kvBucket struct{ *bolt.Bucket }
+
+func (bkt kvBucket) Cursor() boltkv.Cursor {
+	return bkt.Bucket.Cursor()
+}
+
+// buckets returns the buckets of the store.
+func (s *Store) buckets() boltkv.Buckets {
+	return boltkv.Buckets(s.bucketName)
+}
@@ -0,0 +1,41 @@
+package boltdbstorage
+
+import (
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// Scheme is the scheme of the boltdb DSNs, e.g.
+// "boltdb://./shared/colly.boltdb?bucket=pages&ttl=120d".
+const Scheme = "boltdb"
+
+func init() {
+	storage.Register(Scheme, Open)
+}
+
+// Open implements storage.Opener. The path of the DSN is the database file,
+// the values can't be compressed.
+func Open(dsn *storage.DSN) (storage.Storage, error) {
+	config, err := DSNConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return New(config)
+}
+
+// DSNConfig returns the Config described by dsn.
+func DSNConfig(dsn *storage.DSN) (*Config, error) {
+	if _, err := dsn.CompressWith(); err != nil {
+		return nil, err
+	}
+	config := DefaultConfig().MergeSingle(Config{
+		StoragePath: dsn.Path,
+		BucketName:  dsn.Bucket,
+		TTL:         dsn.TTL,
+		ReadOnly:    dsn.ReadOnly,
+	})
+	if dsn.Sync != nil {
+		config.NoSync = !*dsn.Sync
+	}
+	return &config, nil
+}
@@ -0,0 +1,84 @@
+package boltdbstorage
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	// external
+	"github.com/boltdb/bolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Scanner = (*Store)(nil)
+
+// Scan implements storage.Scanner.Scan()
+func (s *Store) Scan(prefix, startAfter string, limit int) (keys []string, next string, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.scan(prefix, startAfter, limit, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return keys, next, nil
+}
+
+// ScanValues implements storage.Scanner.ScanValues()
+func (s *Store) ScanValues(prefix, startAfter string, limit int) (kvs []storage.KV, next string, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.scan(prefix, startAfter, limit, func(k, v []byte) error {
+		_, payload, err := storage.OpenEnvelope(v)
+		if err != nil {
+			return err
+		}
+		// the value is only valid during the transaction
+		value := make([]byte, len(payload))
+		copy(value, payload)
+		kvs = append(kvs, storage.KV{Key: string(k), Value: value})
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return kvs, next, nil
+}
+
+// scan calls fn for the pairs of a page and sets next to the cursor of the
+// following one.
+func (s *Store) scan(prefix, startAfter string, limit int, fn func(k, v []byte) error, next *string) error {
+	*next = ""
+	return s.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(s.bucketName))
+		if bkt == nil {
+			return fmt.Errorf("boltdbstore.Scan(): could not reach the bucket: %s", s.bucketName)
+		}
+		c := bkt.Cursor()
+		p := []byte(prefix)
+		now := time.Now()
+		var last []byte
+		n := 0
+		for k, v := c.Seek([]byte(storage.ScanStart(prefix, startAfter))); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if s.expired(tx, k, now) {
+				continue
+			}
+			if limit > 0 && n == limit {
+				*next = string(last)
+				break
+			}
+			if err := fn(k, v); err != nil {
+				return err
+			}
+			last = k
+			n++
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package boltdbstorage
+
+import (
+	"errors"
+	"time"
+
+	// external
+	"github.com/boltdb/bolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
+)
+
+var _ storage.Expirer = (*Store)(nil)
+
+// SetWithTTL implements storage.Expirer.SetWithTTL()
+func (s *Store) SetWithTTL(key string, resp []byte, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	expiresAt := storage.Deadline(time.Now(), ttl)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(s.bucketName))
+		if bkt == nil {
+			return errors.New("bucket is nil")
+		}
+		return s.put(tx, bkt, []byte(key), resp, expiresAt)
+	})
+	if err == nil && !expiresAt.IsZero() {
+		s.startSweeper()
+	}
+	return err
+}
+
+// Sweep deletes the expired entries and returns how many were deleted.
+func (s *Store) Sweep() (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	n := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		n, err = s.buckets().Sweep(kvTx{tx}, time.Now())
+		return err
+	})
+	return n, err
+}
+
+// put writes a value in its envelope and its expiry, tx must be writable.
+func (s *Store) put(tx *bolt.Tx, bkt *bolt.Bucket, key, value []byte, expiresAt time.Time) error {
+	return s.buckets().Put(kvTx{tx}, kvBucket{bkt}, key, value, expiresAt, s.provider)
+}
+
+// delete deletes the entry stored at key, its expiry and its reads.
+func (s *Store) delete(tx *bolt.Tx, bkt *bolt.Bucket, key []byte) error {
+	s.hits.Drop(key)
+	return s.buckets().Delete(kvTx{tx}, kvBucket{bkt}, key)
+}
+
+// setExpiry replaces the expiry of a key, the zero time removes it.
+func (s *Store) setExpiry(tx *bolt.Tx, key []byte, expiresAt time.Time) error {
+	return s.buckets().SetExpiry(kvTx{tx}, key, expiresAt)
+}
+
+// expired reports whether the entry stored at key has expired at now.
+func (s *Store) expired(tx *bolt.Tx, key []byte, now time.Time) bool {
+	return s.buckets().Expired(kvTx{tx}, key, now)
+}
+
+// startSweeperIfNeeded starts the sweeper when entries expire by default or
+// some have been written with a TTL.
+func (s *Store) startSweeperIfNeeded() error {
+	pending := s.ttl > 0
+	if !pending {
+		err := s.db.View(func(tx *bolt.Tx) error {
+			pending = s.buckets().Pending(kvTx{tx})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if pending {
+		s.Lock()
+		s.startSweeper()
+		s.Unlock()
+	}
+	return nil
+}
+
+// startSweeper starts the sweeper if it is not running, s must be locked.
+func (s *Store) startSweeper() {
+	if s.sweeper == nil {
+		s.sweeper = boltkv.StartSweeper(s.sweepInterval, func() { s.Sweep() })
+	}
+}
+
+// stopSweeper stops the sweeper and waits for it to return.
+func (s *Store) stopSweeper() {
+	s.Lock()
+	sw := s.sweeper
+	s.Unlock()
+	if sw != nil {
+		sw.Stop()
+	}
+}
@@ -0,0 +1,60 @@
+package bboltstorage
+
+import (
+	"reflect"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Actioner = (*Store)(nil)
+
+// Action runs one of the actions listed by Actions(). getKeys keeps its
+// legacy result, see storage.ActionKeys.
+func (c *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	if name == "getKeys" {
+		keys, err := c.Do(name, args...)
+		if err != nil {
+			return nil, err
+		}
+		return storage.ActionKeys(keys.([]string)), nil
+	}
+	return c.registry().Action(name, args...)
+}
+
+// Actions implements storage.Actioner.Actions()
+func (c *Store) Actions() []storage.ActionSpec {
+	return c.registry().Actions()
+}
+
+// Do implements storage.Actioner.Do()
+func (c *Store) Do(name string, args ...interface{}) (interface{}, error) {
+	return c.registry().Do(name, args...)
+}
+
+// registry returns the actions of the store, registered on first use as
+// stores can be built by Mount.
+func (c *Store) registry() *storage.ActionRegistry {
+	c.actionsOnce.Do(func() {
+		c.actions = storage.NewActionRegistry()
+		c.actions.MustRegister(storage.ActionSpec{
+			Name:        "getKeys",
+			Description: "lists the stored keys, in bbolt order",
+			Result:      reflect.TypeOf([]string(nil)),
+		}, func(args ...interface{}) (interface{}, error) {
+			keys, _, err := c.Scan("", "", 0)
+			return keys, err
+		})
+		c.actions.MustRegister(storage.ActionSpec{
+			Name:        "stats",
+			Description: "returns the statistics of the bbolt database",
+			Result:      reflect.TypeOf(bbolt.Stats{}),
+		}, func(args ...interface{}) (interface{}, error) {
+			return c.db.Stats(), nil
+		})
+	})
+	return c.actions
+}
@@ -0,0 +1,122 @@
+package bboltstorage
+
+import (
+	"fmt"
+	"time"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Batcher = (*Store)(nil)
+
+// WriteBatch implements storage.Batcher.WriteBatch(), the batch is applied
+// in a single Update.
+func (c *Store) WriteBatch(b *storage.Batch) error {
+	values := make([][]byte, len(b.Ops))
+	for i, op := range b.Ops {
+		if op.Delete {
+			continue
+		}
+		value, err := c.values.Encode(op.Key, op.Value, "")
+		if err != nil {
+			return storage.AbortBatch(b, map[string]error{op.Key: err})
+		}
+		values[i] = value
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	expiresAt := storage.Deadline(now, c.ttl)
+	errs := make(map[string]error)
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(c.bucketName))
+		if bkt == nil {
+			return fmt.Errorf("bboltstore.WriteBatch(): could not reach the bucket: %s", c.bucketName)
+		}
+		for i, op := range b.Ops {
+			var err error
+			if op.Delete {
+				err = c.delete(tx, bkt, []byte(op.Key))
+			} else {
+				err = c.put(tx, bkt, []byte(op.Key), values[i], storage.Deadline(now, op.TTL(c.ttl)))
+			}
+			if err != nil {
+				errs[op.Key] = err
+			}
+		}
+		if len(errs) > 0 {
+			// roll back the transaction
+			return storage.AbortBatch(b, errs)
+		}
+		return nil
+	})
+	if err == nil && !expiresAt.IsZero() {
+		c.startSweeper()
+	}
+	return err
+}
+
+// SetMany implements storage.Batcher.SetMany()
+func (c *Store) SetMany(kvs []storage.KV) error {
+	return c.WriteBatch(storage.SetBatch(kvs))
+}
+
+// DeleteMany implements storage.Batcher.DeleteMany()
+func (c *Store) DeleteMany(keys []string) error {
+	return c.WriteBatch(storage.DeleteBatch(keys))
+}
+
+// GetMany implements storage.Batcher.GetMany(), the values are read in a
+// single View.
+func (c *Store) GetMany(keys []string) (map[string][]byte, error) {
+	c.RLock()
+	now := time.Now()
+	values := make(map[string][]byte, len(keys))
+	errs := make(storage.BatchError)
+	var hits [][]byte
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(c.bucketName))
+		if bkt == nil {
+			return fmt.Errorf("bboltstore.GetMany(): could not reach the bucket: %s", c.bucketName)
+		}
+		for _, key := range keys {
+			value, err := c.read(tx, bkt, []byte(key), now)
+			if err != nil {
+				errs[key] = err
+				continue
+			}
+			if value != nil {
+				values[key] = value
+				hits = append(hits, []byte(key))
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		c.recordHits(hits...)
+	}
+	c.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range values {
+		decoded, err := c.values.Decode(value)
+		if err != nil {
+			errs[key] = err
+			delete(values, key)
+			continue
+		}
+		values[key] = decoded
+	}
+	if len(errs) > 0 {
+		return values, errs
+	}
+	return values, nil
+}
@@ -0,0 +1,85 @@
+package bboltstorage
+
+import (
+	"time"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// Check is the metadata kept with every entry, see Stat.
+type Check = storage.Check
+
+var (
+	_ storage.Stater      = (*Store)(nil)
+	_ storage.CheckSetter = (*Store)(nil)
+	_ storage.Peeker      = (*Store)(nil)
+)
+
+// Stat implements storage.Stater.Stat(), it doesn't count as a read. The
+// requests are only counted when Config.Stats is enabled.
+func (c *Store) Stat(key string) (check *Check, err error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	err = c.db.View(func(tx *bbolt.Tx) error {
+		check, err = c.buckets().Stat(kvTx{tx}, key, time.Now(), c.hits.Buffered(key))
+		return err
+	})
+	return check, err
+}
+
+// SetWithCheck implements storage.CheckSetter.SetWithCheck(), the value is
+// encoded as by Set.
+func (c *Store) SetWithCheck(key string, resp []byte, check *Check) error {
+	if !check.ExpiredAt.IsZero() && !check.ExpiredAt.After(time.Now()) {
+		return nil
+	}
+	resp, err := c.values.Encode(key, resp, "")
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	err = c.db.Update(func(tx *bbolt.Tx) error {
+		return c.buckets().Restore(kvTx{tx}, key, resp, check)
+	})
+	if err == nil && !check.ExpiredAt.IsZero() {
+		c.startSweeper()
+	}
+	return err
+}
+
+// read returns a copy of the payload stored at key, nil if it is missing or
+// expired. The read is not counted, see recordHits.
+func (c *Store) read(tx *bbolt.Tx, bkt *bbolt.Bucket, key []byte, now time.Time) ([]byte, error) {
+	return c.buckets().Read(kvTx{tx}, kvBucket{bkt}, key, now)
+}
+
+// recordHits counts a read of keys when Config.Stats is enabled. The reads
+// are buffered and written by batches of boltkv.HitsFlushSize, the last
+// ones are lost on a crash. The caller holds the read lock of c, outside of
+// any transaction: the writes don't race with the flushes.
+func (c *Store) recordHits(keys ...[]byte) {
+	if !c.stats || len(keys) == 0 {
+		return
+	}
+	if c.hits.Record(keys, time.Now()) {
+		c.flushHits()
+	}
+}
+
+// flushHits writes the buffered reads, they are dropped on error.
+func (c *Store) flushHits() error {
+	hits := c.hits.Take()
+	if len(hits) == 0 {
+		return nil
+	}
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return c.buckets().WriteHits(kvTx{tx}, hits, time.Now())
+	})
+}
@@ -0,0 +1,5 @@
+package bboltstorage
+
+// DefaultCodec is the codec of the compressed stores, gzip as before the
+// value headers.
+const DefaultCodec = "gzip"
@@ -0,0 +1,46 @@
+package bboltstorage
+
+import (
+	"net/url"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.CollectorStorage = (*Store)(nil)
+
+// Visited implements storage.CollectorStorage.Visited()
+func (c *Store) Visited(requestID uint64) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return c.buckets().Visited(kvTx{tx}, requestID)
+	})
+}
+
+// IsVisited implements storage.CollectorStorage.IsVisited()
+func (c *Store) IsVisited(requestID uint64) (visited bool, err error) {
+	err = c.db.View(func(tx *bbolt.Tx) error {
+		visited = c.buckets().IsVisited(kvTx{tx}, requestID)
+		return nil
+	})
+	return
+}
+
+// Cookies implements storage.CollectorStorage.Cookies()
+func (c *Store) Cookies(u *url.URL) (cookies string) {
+	c.db.View(func(tx *bbolt.Tx) error {
+		cookies = c.buckets().Cookies(kvTx{tx}, u.Host)
+		return nil
+	})
+	return
+}
+
+// SetCookies implements storage.CollectorStorage.SetCookies(), the cookies
+// are merged with the ones already stored for the host.
+func (c *Store) SetCookies(u *url.URL, cookies string) {
+	c.db.Update(func(tx *bbolt.Tx) error {
+		return c.buckets().SetCookies(kvTx{tx}, u.Host, cookies)
+	})
+}
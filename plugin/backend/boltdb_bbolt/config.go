@@ -1,7 +1,12 @@
 package bboltstorage
 
 import (
+	"time"
+
 	"github.com/imdario/mergo"
+
+	// internal
+	"github.com/sniperkit/colly-storage/pkg/compress"
 )
 
 // Config is the configuration for this serializer
@@ -19,7 +24,33 @@ type Config struct {
 	AllocSize      bool
 	Compress       bool
 	Debug          bool
-	Stats          bool
+
+	// Codec is the name of the codec compressing the values, see
+	// compress.Codecs() and compress.Lookup() for the levels and windows,
+	// e.g. "brotli:9:18". It implies Compress, DefaultCodec if empty.
+	Codec string
+
+	// Policy decides the codec of each value from its content type and its
+	// size, overriding Codec. The values are sniffed unless SetTyped gives
+	// their type.
+	Policy *compress.Policy
+
+	// Stats records the reads in the metadata of the entries, see Stat.
+	// The reads are buffered and written by batches to counters apart from
+	// the values, the last ones are lost on a crash.
+	Stats bool
+
+	// Provider is the name of the crawler recorded in the metadata of the
+	// entries written.
+	Provider string
+
+	// TTL is the time to live of the entries written by Set, zero means
+	// they never expire.
+	TTL time.Duration
+
+	// SweepInterval is the interval between two sweeps of the expired
+	// entries, storage.DefaultSweepInterval if zero.
+	SweepInterval time.Duration
 }
 
 // DefaultConfig returns the default configuration for this serializer
@@ -5,3 +5,8 @@ const (
 	StoragePrefixPath    string = "./shared/data/store/bbolt"
 	StorageFileExtension string = ".bbolt"
 )
+
+const (
+	// bucket name suffix of the compression dictionaries, see dict.go
+	dictsBucketSuffix string = ".dicts"
+)
@@ -0,0 +1,101 @@
+package bboltstorage
+
+import (
+	"bytes"
+	"time"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+)
+
+var _ compress.DictStore = (*Store)(nil)
+
+// LoadDicts implements compress.DictStore.LoadDicts(), the dictionaries are
+// kept in their own bucket by ID.
+func (c *Store) LoadDicts() ([]compress.Dict, error) {
+	var dicts []compress.Dict
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(c.dictsBucket()))
+		if bkt == nil {
+			// created by Init
+			return nil
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			var dict compress.Dict
+			if err := dict.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			dicts = append(dicts, dict)
+			return nil
+		})
+	})
+	return dicts, err
+}
+
+// SaveDict implements compress.DictStore.SaveDict()
+func (c *Store) SaveDict(dict compress.Dict) error {
+	data, err := dict.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(c.dictsBucket()))
+		if err != nil {
+			return err
+		}
+		return bkt.Put(uint64ToBytes(uint64(dict.ID)), data)
+	})
+}
+
+// TrainDictionary trains a new version of the zstd dictionary of the
+// values of a host, see storage.KeyNamespace, from up to samples of them
+// (compress.DefaultDictSamples if zero) picked at random. Only the keys under
+// storage.NamespacePrefixes are scanned. The next values of the host are
+// compressed with it when the codec of the store is zstd, the values written
+// with the previous versions stay readable.
+func (c *Store) TrainDictionary(namespace string, samples int) (compress.Dict, error) {
+	if c.values.Dicts == nil {
+		return compress.Dict{}, ErrNoDicts
+	}
+	return c.values.Train(namespace, samples, func(n int) ([][]byte, error) {
+		c.RLock()
+		defer c.RUnlock()
+
+		var payloads [][]byte
+		err := c.db.View(func(tx *bbolt.Tx) error {
+			bkt := tx.Bucket([]byte(c.bucketName))
+			if bkt == nil {
+				return nil
+			}
+			sampler := compress.KeySampler{N: n}
+			cur := bkt.Cursor()
+			now := time.Now()
+			for _, prefix := range storage.NamespacePrefixes(namespace) {
+				p := []byte(prefix)
+				for k, _ := cur.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = cur.Next() {
+					if storage.KeyNamespace(string(k)) == namespace && !c.expired(tx, k, now) {
+						sampler.Offer(string(k))
+					}
+				}
+			}
+			for _, key := range sampler.Keys {
+				_, payload, err := storage.OpenEnvelope(bkt.Get([]byte(key)))
+				if err != nil {
+					return err
+				}
+				// the payloads share the memory of bbolt
+				payloads = append(payloads, append([]byte(nil), payload...))
+			}
+			return nil
+		})
+		return payloads, err
+	})
+}
+
+func (c *Store) dictsBucket() string {
+	return c.bucketName + dictsBucketSuffix
+}
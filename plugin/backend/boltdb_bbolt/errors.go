@@ -1 +1,11 @@
 package bboltstorage
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNoDicts is returned by TrainDictionary on the mounted stores, only
+	// the ones opened by New load their dictionaries.
+	ErrNoDicts = errors.New("bboltstorage: no dictionaries on a mounted store")
+)
@@ -2,10 +2,8 @@ package bboltstorage
 
 import (
 	"bytes"
-	"compress/gzip"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"path/filepath"
 	"sync"
 	"time"
@@ -14,7 +12,10 @@ import (
 	bbolt "github.com/coreos/bbolt"
 
 	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
 	helper "github.com/sniperkit/colly-storage/pkg/helper"
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
 )
 
 var (
@@ -32,26 +33,28 @@ type Store struct {
 	fp          string
 	debug       bool
 	stats       bool
-	compress    bool
-}
-
-type Check struct {
-	Enabled   bool
-	Key       string
-	Requests  int
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	ExpiredAt time.Time
-	Priority  bool
-	Provider  string
+	// hits buffers the reads counted when stats is set, see recordHits
+	hits boltkv.Hits
+	// values encodes and decodes the values, its dictionaries are trained
+	// by TrainDictionary
+	values   compress.Values
+	provider string
+	// ttl is the default time to live of the entries, the sweeper is
+	// started with the first entry having one
+	ttl           time.Duration
+	sweepInterval time.Duration
+	sweeper       *boltkv.Sweeper
+
+	actionsOnce sync.Once
+	actions     *storage.ActionRegistry
 }
 
 // New returns a new Store that uses a bolt database at the given path.
 func New(config *Config) (*Store, error) {
 
 	if config == nil {
-		config.StoragePath = DefaultStorageFile
-		config.BucketName = StorageBucketName
+		defaultConfig := DefaultConfig()
+		config = &defaultConfig
 	}
 
 	if config.StoragePath == "" {
@@ -66,9 +69,16 @@ func New(config *Config) (*Store, error) {
 	store := &Store{}
 	store.storagePath = config.StoragePath
 	store.bucketName = config.BucketName
-	store.compress = config.Compress
+	if store.values, err = compress.NewValues(config.Compress, config.Codec, DefaultCodec); err != nil {
+		return nil, err
+	}
+	store.values.Policy = config.Policy
 	store.debug = config.Debug
-	store.stats = config.Stats
+	// the reads are not recorded on a read-only database
+	store.stats = config.Stats && !config.ReadOnly
+	store.provider = config.Provider
+	store.ttl = config.TTL
+	store.sweepInterval = config.SweepInterval
 
 	if err := helper.EnsurePathExists(config.StoragePath); err != nil {
 		return nil, err
@@ -76,126 +86,130 @@ func New(config *Config) (*Store, error) {
 		store.fp = fmt.Sprintf("%s/%s%s", config.StoragePath, StorageBucketName, StorageFileExtension)
 	}
 
-	store.db, err = bbolt.Open(config.StoragePath, 0755, nil)
+	store.db, err = bbolt.Open(config.StoragePath, 0755, &bbolt.Options{
+		ReadOnly:   config.ReadOnly,
+		NoGrowSync: config.NoGrowSync,
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	init := func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(config.BucketName))
-		return err
+	store.db.NoSync = config.NoSync
+	if store.values.Dicts, err = compress.NewDicts(store); err != nil {
+		store.db.Close()
+		return nil, err
+	}
+	if config.ReadOnly {
+		return store, nil
 	}
 
-	if err := store.db.Update(init); err != nil {
-		return nil, err
+	if err := store.Init(); err != nil {
 		if err := store.db.Close(); err != nil {
 			return nil, err
 		}
+		return nil, err
+	}
+	if err := store.startSweeperIfNeeded(); err != nil {
+		store.db.Close()
+		return nil, err
 	}
 	return store, nil
 }
 
 // Mount returns a new Store using the provided (and opened) bolt database.
+// Init must be called to create the buckets if they don't exist yet.
 func Mount(db *bbolt.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, bucketName: StorageBucketName}
+}
+
+// Init creates the buckets used by the store if they don't exist yet.
+func (c *Store) Init() error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range append(c.buckets().Names(), c.dictsBucket()) {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// Close closes the underlying boltdb database.
+// Close stops the sweeper, writes the buffered reads, closes the
+// dictionaries and the underlying boltdb database.
 func (c *Store) Close() error {
+	c.stopSweeper()
+	c.Lock()
+	err := c.flushHits()
+	if c.values.Dicts != nil {
+		if e := c.values.Dicts.Close(); err == nil {
+			err = e
+		}
+	}
+	c.Unlock()
+	if err != nil {
+		c.db.Close()
+		return err
+	}
 	return c.db.Close()
 }
 
 // Get retrieves the response corresponding to the given key if present.
 func (c *Store) Get(key string) (resp []byte, ok bool) {
+	return c.get(key, true)
+}
+
+// Peek implements storage.Peeker.Peek(), the read isn't recorded by
+// Config.Stats.
+func (c *Store) Peek(key string) (resp []byte, ok bool) {
+	return c.get(key, false)
+}
+
+// get returns the decoded response stored at key, the read is recorded if
+// hit is set.
+func (c *Store) get(key string, hit bool) (resp []byte, ok bool) {
 	c.RLock()
 	get := func(tx *bbolt.Tx) error {
 		bkt := tx.Bucket([]byte(c.bucketName))
 		if bkt == nil {
 			return errors.New("bucket is nil")
 		}
-		resp = bkt.Get([]byte(key))
-		return nil
+		var err error
+		resp, err = c.read(tx, bkt, []byte(key), time.Now())
+		return err
 	}
-	if err := c.db.View(get); err != nil {
-		return resp, false
+	err := c.db.View(get)
+	if hit && err == nil && resp != nil {
+		c.recordHits([]byte(key))
 	}
 	c.RUnlock()
-	if c.compress {
-		var err error
-		resp, err = ungzipData(resp)
-		if err != nil {
-			return resp, false
-		}
+	if err != nil || resp == nil {
+		return resp, false
+	}
+	resp, err = c.values.Decode(resp)
+	if err != nil {
+		return resp, false
 	}
 	return resp, resp != nil
 }
 
-// Set stores a response to the store at the given key.
+// Set stores a response to the store at the given key, expiring after
+// Config.TTL when set.
 func (c *Store) Set(key string, resp []byte) error {
-	c.Lock()
-	set := func(tx *bbolt.Tx) error {
-		bkt := tx.Bucket([]byte(c.bucketName))
-		if bkt == nil {
-			return errors.New("bucket is nil")
-		}
-		if c.compress {
-			var err error
-			resp, err = gzipData(resp)
-			if err != nil {
-				return errors.New("error while compressing content...")
-			}
-		}
-		return bkt.Put([]byte(key), resp)
-	}
-	c.Unlock()
-	if err := c.db.Update(set); err != nil {
-		return err
-	}
-	return nil
+	return c.SetWithTTL(key, resp, c.ttl)
 }
 
 // Delete removes the response with the given key from the store.
 func (c *Store) Delete(key string) error {
 	c.Lock()
+	defer c.Unlock()
+
 	del := func(tx *bbolt.Tx) error {
 		bkt := tx.Bucket([]byte(c.bucketName))
 		if bkt == nil {
 			return errors.New(fmt.Sprintf("bboltstore.Delete(): could not reach the bucket: %s", c.bucketName))
 		}
-		return bkt.Delete([]byte(key))
-	}
-	if err := c.db.Update(del); err != nil {
-		return err
-	}
-	c.Unlock()
-	return nil
-}
-
-func ungzipData(data []byte) ([]byte, error) {
-	r, err := gzip.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
-	data, err = ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
-}
-
-func gzipData(data []byte) ([]byte, error) {
-	var b bytes.Buffer
-	w := gzip.NewWriter(&b)
-	_, err := w.Write(data)
-	if err != nil {
-		return nil, err
-	}
-	err = w.Close()
-	if err != nil {
-		return nil, err
+		return c.delete(tx, bkt, []byte(key))
 	}
-	return b.Bytes(), nil
+	return c.db.Update(del)
 }
 
 func fmtToJsonArr(s []byte) []byte {
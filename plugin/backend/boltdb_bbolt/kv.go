@@ -0,0 +1,40 @@
+package bboltstorage
+
+import (
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
+)
+
+// kvTx adapts a bbolt transaction to boltkv, which holds the bucket logic
+// shared with the bolt store.
+type kvTx struct{ *bbolt.Tx }
+
+func (tx kvTx) Bucket(name []byte) boltkv.Bucket {
+	if bkt := tx.Tx.Bucket(name); bkt != nil {
+		return kvBucket{bkt}
+	}
+	return nil
+}
+
+func (tx kvTx) CreateBucketIfNotExists(name []byte) (boltkv.Bucket, error) {
+	bkt, err := tx.Tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return kvBucket{bkt}, nil
+}
+
+// kvBucket adapts a bbolt bucket to boltkv.
+type kvBucket struct{ *bbolt.Bucket }
+
+func (bkt kvBucket) Cursor() boltkv.Cursor {
+	return bkt.Bucket.Cursor()
+}
+
+// buckets returns the buckets of the store.
+func (c *Store) buckets() boltkv.Buckets {
+	return boltkv.Buckets(c.bucketName)
+}
@@ -0,0 +1,46 @@
+package bboltstorage
+
+import (
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+)
+
+// Scheme is the scheme of the bbolt DSNs, e.g.
+// "bbolt://./shared/colly.bbolt?bucket=pages&compress=gzip".
+const Scheme = "bbolt"
+
+func init() {
+	storage.Register(Scheme, Open)
+}
+
+// Open implements storage.Opener. The path of the DSN is the database file.
+func Open(dsn *storage.DSN) (storage.Storage, error) {
+	config, err := DSNConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return New(config)
+}
+
+// DSNConfig returns the Config described by dsn.
+func DSNConfig(dsn *storage.DSN) (*Config, error) {
+	compressed, err := dsn.CompressCodec(compress.CheckCodec)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultConfig().MergeSingle(Config{
+		StoragePath: dsn.Path,
+		BucketName:  dsn.Bucket,
+		Compress:    compressed,
+		TTL:         dsn.TTL,
+		ReadOnly:    dsn.ReadOnly,
+	})
+	if dsn.Compress != "default" {
+		config.Codec = dsn.Compress
+	}
+	if dsn.Sync != nil {
+		config.NoSync = !*dsn.Sync
+	}
+	return &config, nil
+}
@@ -0,0 +1,87 @@
+package bboltstorage
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Scanner = (*Store)(nil)
+
+// Scan implements storage.Scanner.Scan()
+func (c *Store) Scan(prefix, startAfter string, limit int) (keys []string, next string, err error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	err = c.scan(prefix, startAfter, limit, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return keys, next, nil
+}
+
+// ScanValues implements storage.Scanner.ScanValues()
+func (c *Store) ScanValues(prefix, startAfter string, limit int) (kvs []storage.KV, next string, err error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	err = c.scan(prefix, startAfter, limit, func(k, v []byte) error {
+		_, payload, err := storage.OpenEnvelope(v)
+		if err != nil {
+			return err
+		}
+		// the value is only valid during the transaction
+		value := make([]byte, len(payload))
+		copy(value, payload)
+		if value, err = c.values.Decode(value); err != nil {
+			return err
+		}
+		kvs = append(kvs, storage.KV{Key: string(k), Value: value})
+		return nil
+	}, &next)
+	if err != nil {
+		return nil, "", err
+	}
+	return kvs, next, nil
+}
+
+// scan calls fn for the pairs of a page and sets next to the cursor of the
+// following one.
+func (c *Store) scan(prefix, startAfter string, limit int, fn func(k, v []byte) error, next *string) error {
+	*next = ""
+	return c.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(c.bucketName))
+		if bkt == nil {
+			return fmt.Errorf("bboltstore.Scan(): could not reach the bucket: %s", c.bucketName)
+		}
+		cur := bkt.Cursor()
+		p := []byte(prefix)
+		now := time.Now()
+		var last []byte
+		n := 0
+		for k, v := cur.Seek([]byte(storage.ScanStart(prefix, startAfter))); k != nil && bytes.HasPrefix(k, p); k, v = cur.Next() {
+			if c.expired(tx, k, now) {
+				continue
+			}
+			if limit > 0 && n == limit {
+				*next = string(last)
+				break
+			}
+			if err := fn(k, v); err != nil {
+				return err
+			}
+			last = k
+			n++
+		}
+		return nil
+	})
+}
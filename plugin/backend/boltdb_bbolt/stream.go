@@ -0,0 +1,54 @@
+package bboltstorage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+var _ storage.Streamer = (*Store)(nil)
+
+// SetReader implements storage.Streamer.SetReader(). Bolt needs the whole
+// value to write it, r is compressed in memory while it is read and only the
+// compressed value is held.
+func (c *Store) SetReader(key string, r io.Reader) error {
+	var buf bytes.Buffer
+	if err := c.values.EncodeStream(key, &buf, r); err != nil {
+		return err
+	}
+	return c.setEncoded(key, buf.Bytes(), c.ttl)
+}
+
+// GetReader implements storage.Streamer.GetReader(), the compressed value
+// is copied out of the database and decompressed while it is read.
+func (c *Store) GetReader(key string) (io.ReadCloser, error) {
+	c.RLock()
+	var payload []byte
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(c.bucketName))
+		if bkt == nil {
+			return errors.New("bucket is nil")
+		}
+		var err error
+		payload, err = c.read(tx, bkt, []byte(key), time.Now())
+		return err
+	})
+	if err == nil && payload != nil {
+		c.recordHits([]byte(key))
+	}
+	c.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, storage.ErrNotFound
+	}
+	return c.values.DecodeStream(payload)
+}
@@ -0,0 +1,150 @@
+package bboltstorage
+
+import (
+	"errors"
+	"time"
+
+	// external
+	bbolt "github.com/coreos/bbolt"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+	"github.com/sniperkit/colly-storage/pkg/compress"
+	"github.com/sniperkit/colly-storage/plugin/backend/internal/boltkv"
+)
+
+var _ storage.Expirer = (*Store)(nil)
+var _ storage.TypedSetter = (*Store)(nil)
+var _ storage.TypedExpirer = (*Store)(nil)
+var _ storage.EncodedSetter = (*Store)(nil)
+
+// SetWithTTL implements storage.Expirer.SetWithTTL()
+func (c *Store) SetWithTTL(key string, resp []byte, ttl time.Duration) error {
+	resp, err := c.values.Encode(key, resp, "")
+	if err != nil {
+		return err
+	}
+	return c.setEncoded(key, resp, ttl)
+}
+
+// SetTyped implements storage.TypedSetter.SetTyped(), contentType is the
+// hint of Config.Policy.
+func (c *Store) SetTyped(key string, resp []byte, contentType string) error {
+	resp, err := c.values.Encode(key, resp, contentType)
+	if err != nil {
+		return err
+	}
+	return c.setEncoded(key, resp, c.ttl)
+}
+
+// SetTypedWithTTL implements storage.TypedExpirer.SetTypedWithTTL()
+func (c *Store) SetTypedWithTTL(key string, resp []byte, contentType string, ttl time.Duration) error {
+	resp, err := c.values.Encode(key, resp, contentType)
+	if err != nil {
+		return err
+	}
+	return c.setEncoded(key, resp, ttl)
+}
+
+// SetEncoded implements storage.EncodedSetter.SetEncoded(), the response
+// is stored as it was received behind the header of its codec, bypassing
+// Config.Policy and the dictionaries.
+func (c *Store) SetEncoded(key string, resp []byte, contentEncoding string) error {
+	codec, err := compress.ContentEncodingCodec(contentEncoding)
+	if err != nil {
+		return err
+	}
+	return c.setEncoded(key, compress.WrapEncoded(codec, resp), c.ttl)
+}
+
+// setEncoded stores an encoded value at key.
+func (c *Store) setEncoded(key string, resp []byte, ttl time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+
+	expiresAt := storage.Deadline(time.Now(), ttl)
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(c.bucketName))
+		if bkt == nil {
+			return errors.New("bucket is nil")
+		}
+		return c.put(tx, bkt, []byte(key), resp, expiresAt)
+	})
+	if err == nil && !expiresAt.IsZero() {
+		c.startSweeper()
+	}
+	return err
+}
+
+// Sweep deletes the expired entries and returns how many were deleted.
+func (c *Store) Sweep() (int, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	n := 0
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		n, err = c.buckets().Sweep(kvTx{tx}, time.Now())
+		return err
+	})
+	return n, err
+}
+
+// put writes a value in its envelope and its expiry, tx must be writable.
+func (c *Store) put(tx *bbolt.Tx, bkt *bbolt.Bucket, key, value []byte, expiresAt time.Time) error {
+	return c.buckets().Put(kvTx{tx}, kvBucket{bkt}, key, value, expiresAt, c.provider)
+}
+
+// delete deletes the entry stored at key, its expiry and its reads.
+func (c *Store) delete(tx *bbolt.Tx, bkt *bbolt.Bucket, key []byte) error {
+	c.hits.Drop(key)
+	return c.buckets().Delete(kvTx{tx}, kvBucket{bkt}, key)
+}
+
+// setExpiry replaces the expiry of a key, the zero time removes it.
+func (c *Store) setExpiry(tx *bbolt.Tx, key []byte, expiresAt time.Time) error {
+	return c.buckets().SetExpiry(kvTx{tx}, key, expiresAt)
+}
+
+// expired reports whether the entry stored at key has expired at now.
+func (c *Store) expired(tx *bbolt.Tx, key []byte, now time.Time) bool {
+	return c.buckets().Expired(kvTx{tx}, key, now)
+}
+
+// startSweeperIfNeeded starts the sweeper when entries expire by default or
+// some have been written with a TTL.
+func (c *Store) startSweeperIfNeeded() error {
+	pending := c.ttl > 0
+	if !pending {
+		err := c.db.View(func(tx *bbolt.Tx) error {
+			pending = c.buckets().Pending(kvTx{tx})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if pending {
+		c.Lock()
+		c.startSweeper()
+		c.Unlock()
+	}
+	return nil
+}
+
+// startSweeper starts the sweeper if it is not running, c must be locked.
+func (c *Store) startSweeper() {
+	if c.sweeper == nil {
+		c.sweeper = boltkv.StartSweeper(c.sweepInterval, func() { c.Sweep() })
+	}
+}
+
+// stopSweeper stops the sweeper and waits for it to return.
+func (c *Store) stopSweeper() {
+	c.Lock()
+	sw := c.sweeper
+	c.Unlock()
+	if sw != nil {
+		sw.Stop()
+	}
+}
@@ -0,0 +1,106 @@
+// Package boltkv holds the bucket logic shared by the bolt and bbolt stores:
+// the expiry of the entries, their envelopes and read counters, and the
+// collector data. It is
+// written over small interfaces satisfied by the transactions of both
+// databases through the adapters of the stores.
+package boltkv
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// bucket name suffixes of the collector data, see collector.go
+	VisitedSuffix string = ".visited"
+	CookiesSuffix string = ".cookies"
+
+	// bucket name suffixes of the entry expiries, see ttl.go
+	TTLSuffix    string = ".ttl"
+	ExpirySuffix string = ".expiry"
+
+	// bucket name suffix of the read counters, see hits.go
+	HitsSuffix string = ".hits"
+
+	//-- End
+)
+
+// ErrNoBucket is returned when the values bucket of a store is missing.
+var ErrNoBucket = errors.New("boltkv: bucket is nil")
+
+// Tx is a bolt or bbolt transaction.
+type Tx interface {
+	// Bucket returns the bucket of the given name, nil if it doesn't exist.
+	Bucket(name []byte) Bucket
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+}
+
+// Bucket is a bolt or bbolt bucket.
+type Bucket interface {
+	Get(key []byte) []byte
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	Cursor() Cursor
+}
+
+// Cursor is a bolt or bbolt cursor, both satisfy it as they are.
+type Cursor interface {
+	First() (key, value []byte)
+	Next() (key, value []byte)
+	Seek(seek []byte) (key, value []byte)
+}
+
+// Buckets are the buckets of a store, named after its values bucket.
+type Buckets string
+
+// Names returns the names of all the buckets, created by the Init of the
+// stores.
+func (b Buckets) Names() []string {
+	return []string{string(b), b.VisitedBucket(), b.CookiesBucket(), b.TTLBucket(), b.ExpiryBucket(), b.HitsBucket()}
+}
+
+// VisitedBucket returns the name of the bucket of the visited requests.
+func (b Buckets) VisitedBucket() string {
+	return string(b) + VisitedSuffix
+}
+
+// CookiesBucket returns the name of the bucket of the cookies.
+func (b Buckets) CookiesBucket() string {
+	return string(b) + CookiesSuffix
+}
+
+// TTLBucket returns the name of the bucket of the deadlines, by key.
+func (b Buckets) TTLBucket() string {
+	return string(b) + TTLSuffix
+}
+
+// ExpiryBucket returns the name of the bucket of the keys, by deadline.
+func (b Buckets) ExpiryBucket() string {
+	return string(b) + ExpirySuffix
+}
+
+// HitsBucket returns the name of the bucket of the read counters, by key.
+func (b Buckets) HitsBucket() string {
+	return string(b) + HitsSuffix
+}
+
+// Values returns the values bucket, ErrNoBucket if it is missing.
+func (b Buckets) Values(tx Tx) (Bucket, error) {
+	bkt := tx.Bucket([]byte(b))
+	if bkt == nil {
+		return nil, ErrNoBucket
+	}
+	return bkt, nil
+}
+
+// Converts bytes to an integer
+func bytesToUint64(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
+
+// Converts a uint to a byte slice
+func uint64ToBytes(u uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, u)
+	return buf
+}
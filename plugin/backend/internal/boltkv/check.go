@@ -0,0 +1,70 @@
+package boltkv
+
+import (
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// Read returns a copy of the payload stored at key, nil if it is missing or
+// expired. The reads are counted apart, see Hits.
+func (b Buckets) Read(tx Tx, bkt Bucket, key []byte, now time.Time) ([]byte, error) {
+	if b.Expired(tx, key, now) {
+		return nil, nil
+	}
+	v := bkt.Get(key)
+	if v == nil {
+		return nil, nil
+	}
+	_, payload, err := storage.OpenEnvelope(v)
+	if err != nil {
+		return nil, err
+	}
+	// the value is only valid during the transaction
+	resp := make([]byte, len(payload))
+	copy(resp, payload)
+	return resp, nil
+}
+
+// Stat returns the metadata of the entry stored at key, storage.ErrNotFound
+// if it is missing or expired. The reads written to its counter and the
+// buffered ones are added to the requests of the envelope.
+func (b Buckets) Stat(tx Tx, key string, now time.Time, buffered Hit) (*storage.Check, error) {
+	bkt, err := b.Values(tx)
+	if err != nil {
+		return nil, err
+	}
+	v := bkt.Get([]byte(key))
+	if v == nil || b.Expired(tx, []byte(key), now) {
+		return nil, storage.ErrNotFound
+	}
+	c, err := storage.StatEnvelope(key, v)
+	if err != nil {
+		return nil, err
+	}
+	h, err := b.hits(tx, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	h.add(c)
+	buffered.add(c)
+	return c, nil
+}
+
+// Restore writes a value with the metadata of c and its expiry, see
+// storage.CheckSetter. tx must be writable.
+func (b Buckets) Restore(tx Tx, key string, value []byte, c *storage.Check) error {
+	bkt, err := b.Values(tx)
+	if err != nil {
+		return err
+	}
+	if err := bkt.Put([]byte(key), storage.RestoreEnvelope(key, value, c)); err != nil {
+		return err
+	}
+	// the requests of c replace the counted ones
+	if err := b.clearHits(tx, []byte(key)); err != nil {
+		return err
+	}
+	return b.SetExpiry(tx, []byte(key), c.ExpiredAt)
+}
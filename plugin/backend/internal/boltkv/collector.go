@@ -0,0 +1,41 @@
+package boltkv
+
+import (
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// Visited records a visited request, tx must be writable.
+func (b Buckets) Visited(tx Tx, requestID uint64) error {
+	bkt, err := tx.CreateBucketIfNotExists([]byte(b.VisitedBucket()))
+	if err != nil {
+		return err
+	}
+	return bkt.Put(uint64ToBytes(requestID), []byte{})
+}
+
+// IsVisited reports whether a request has been visited.
+func (b Buckets) IsVisited(tx Tx, requestID uint64) bool {
+	bkt := tx.Bucket([]byte(b.VisitedBucket()))
+	return bkt != nil && bkt.Get(uint64ToBytes(requestID)) != nil
+}
+
+// Cookies returns the cookies stored for host.
+func (b Buckets) Cookies(tx Tx, host string) string {
+	bkt := tx.Bucket([]byte(b.CookiesBucket()))
+	if bkt == nil {
+		return ""
+	}
+	return string(bkt.Get([]byte(host)))
+}
+
+// SetCookies merges cookies with the ones stored for host, tx must be
+// writable.
+func (b Buckets) SetCookies(tx Tx, host, cookies string) error {
+	bkt, err := tx.CreateBucketIfNotExists([]byte(b.CookiesBucket()))
+	if err != nil {
+		return err
+	}
+	stored := string(bkt.Get([]byte(host)))
+	return bkt.Put([]byte(host), []byte(storage.MergeCookies(stored, cookies)))
+}
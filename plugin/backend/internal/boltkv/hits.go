@@ -0,0 +1,177 @@
+package boltkv
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// HitsFlushSize is the number of reads buffered before their counters are
+// written, in a single transaction.
+const HitsFlushSize = 1000
+
+// Hit is the reads of an entry and the time of the last one.
+type Hit struct {
+	Requests int
+	Last     time.Time
+}
+
+// add adds the reads h to c, unless c is not counted.
+func (h Hit) add(c *storage.Check) {
+	if !c.Enabled || h.Requests == 0 {
+		// the values written before the envelopes are not counted
+		return
+	}
+	c.Requests += h.Requests
+	if h.Last.After(c.UpdatedAt) {
+		c.UpdatedAt = h.Last
+	}
+}
+
+// Hits buffers the reads of the entries when the stores count them, written
+// by batches to the counters of the hits bucket: the reads would rewrite the
+// values otherwise. The counters are folded back in the envelope by the
+// next write. It is safe for concurrent use.
+type Hits struct {
+	mu       sync.Mutex
+	pending  map[string]*Hit
+	buffered int
+}
+
+// Record counts a read of keys at now and reports whether HitsFlushSize
+// reads are buffered, the caller then writes them with Take and WriteHits.
+func (h *Hits) Record(keys [][]byte, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending == nil {
+		h.pending = make(map[string]*Hit)
+	}
+	for _, key := range keys {
+		p, ok := h.pending[string(key)]
+		if !ok {
+			p = &Hit{}
+			h.pending[string(key)] = p
+		}
+		p.Requests++
+		p.Last = now
+	}
+	h.buffered += len(keys)
+	return h.buffered >= HitsFlushSize
+}
+
+// Take returns the buffered reads and forgets them.
+func (h *Hits) Take() map[string]*Hit {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pending := h.pending
+	h.pending, h.buffered = nil, 0
+	return pending
+}
+
+// Buffered returns the reads of key not written yet.
+func (h *Hits) Buffered(key string) Hit {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if p, ok := h.pending[key]; ok {
+		return *p
+	}
+	return Hit{}
+}
+
+// Drop forgets the reads of key not written yet, when it is deleted.
+func (h *Hits) Drop(key []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.pending, string(key))
+}
+
+// WriteHits adds hits to the counters of their entries, the missing or
+// expired ones are skipped. tx must be writable.
+func (b Buckets) WriteHits(tx Tx, hits map[string]*Hit, now time.Time) error {
+	bkt := tx.Bucket([]byte(b))
+	if bkt == nil || len(hits) == 0 {
+		return nil
+	}
+	counters, err := tx.CreateBucketIfNotExists([]byte(b.HitsBucket()))
+	if err != nil {
+		return err
+	}
+	for key, h := range hits {
+		k := []byte(key)
+		if bkt.Get(k) == nil || b.Expired(tx, k, now) {
+			continue
+		}
+		counted, err := readHits(counters, k)
+		if err != nil {
+			return err
+		}
+		if err := counters.Put(k, encodeHits(counted.Requests+h.Requests, h.Last)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hits returns the reads written for key.
+func (b Buckets) hits(tx Tx, key []byte) (Hit, error) {
+	counters := tx.Bucket([]byte(b.HitsBucket()))
+	if counters == nil {
+		return Hit{}, nil
+	}
+	return readHits(counters, key)
+}
+
+// foldHits returns prev, the envelope stored at key, with the reads written
+// for key, and deletes the counter. tx must be writable.
+func (b Buckets) foldHits(tx Tx, key, prev []byte) ([]byte, error) {
+	h, err := b.hits(tx, key)
+	if err != nil || h.Requests == 0 {
+		return prev, err
+	}
+	if storage.IsEnvelope(prev) {
+		c, err := storage.DecodeCheck(prev)
+		if err != nil {
+			return nil, err
+		}
+		h.add(c)
+		prev = storage.EncodeEnvelope(c, nil)
+	}
+	return prev, b.clearHits(tx, key)
+}
+
+// clearHits deletes the counter of key, if any.
+func (b Buckets) clearHits(tx Tx, key []byte) error {
+	counters := tx.Bucket([]byte(b.HitsBucket()))
+	if counters == nil {
+		return nil
+	}
+	return counters.Delete(key)
+}
+
+// readHits returns the reads counted for key in counters.
+func readHits(counters Bucket, key []byte) (Hit, error) {
+	v := counters.Get(key)
+	if v == nil {
+		return Hit{}, nil
+	}
+	if len(v) != 16 {
+		return Hit{}, storage.ErrInvalidEnvelope
+	}
+	return Hit{
+		Requests: int(bytesToUint64(v)),
+		Last:     time.Unix(0, int64(bytesToUint64(v[8:]))),
+	}, nil
+}
+
+// encodeHits encodes a read counter, the number of reads and the time of the
+// last one.
+func encodeHits(requests int, last time.Time) []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf, uint64(requests))
+	binary.BigEndian.PutUint64(buf[8:], uint64(last.UnixNano()))
+	return buf
+}
@@ -0,0 +1,159 @@
+package boltkv
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
+)
+
+// The expiry of an entry is kept in two buckets: the ttl bucket maps the
+// key to its deadline, and the expiry bucket indexes the keys by deadline,
+// its keys being the deadline followed by the key.
+
+// Put writes a value in its envelope and its expiry, tx must be writable.
+func (b Buckets) Put(tx Tx, bkt Bucket, key, value []byte, expiresAt time.Time, provider string) error {
+	now := time.Now()
+	prev := bkt.Get(key)
+	if b.Expired(tx, key, now) {
+		// start over with a new entry
+		prev = nil
+		if err := b.clearHits(tx, key); err != nil {
+			return err
+		}
+	}
+	prev, err := b.foldHits(tx, key, prev)
+	if err != nil {
+		return err
+	}
+	sealed := storage.SealEnvelope(prev, string(key), value, now, expiresAt, provider)
+	if err := bkt.Put(key, sealed); err != nil {
+		return err
+	}
+	return b.SetExpiry(tx, key, expiresAt)
+}
+
+// SetExpiry replaces the expiry of a key, the zero time removes it.
+func (b Buckets) SetExpiry(tx Tx, key []byte, expiresAt time.Time) error {
+	ttl := tx.Bucket([]byte(b.TTLBucket()))
+	expiry := tx.Bucket([]byte(b.ExpiryBucket()))
+	if ttl == nil || expiry == nil {
+		if expiresAt.IsZero() {
+			return nil
+		}
+		return fmt.Errorf("boltkv: could not reach the bucket: %s", b.TTLBucket())
+	}
+
+	if deadline := ttl.Get(key); deadline != nil {
+		if err := expiry.Delete(expiryKey(deadline, key)); err != nil {
+			return err
+		}
+	}
+	if expiresAt.IsZero() {
+		return ttl.Delete(key)
+	}
+	deadline := uint64ToBytes(uint64(expiresAt.UnixNano()))
+	if err := ttl.Put(key, deadline); err != nil {
+		return err
+	}
+	return expiry.Put(expiryKey(deadline, key), []byte{})
+}
+
+// Expired reports whether the entry stored at key has expired at now.
+func (b Buckets) Expired(tx Tx, key []byte, now time.Time) bool {
+	ttl := tx.Bucket([]byte(b.TTLBucket()))
+	if ttl == nil {
+		return false
+	}
+	deadline := ttl.Get(key)
+	return deadline != nil && bytesToUint64(deadline) <= uint64(now.UnixNano())
+}
+
+// Delete deletes the entry stored at key, its expiry and its read counter.
+func (b Buckets) Delete(tx Tx, bkt Bucket, key []byte) error {
+	if err := bkt.Delete(key); err != nil {
+		return err
+	}
+	if err := b.clearHits(tx, key); err != nil {
+		return err
+	}
+	return b.SetExpiry(tx, key, time.Time{})
+}
+
+// Sweep deletes the entries expired at now and returns how many were
+// deleted, tx must be writable.
+func (b Buckets) Sweep(tx Tx, now time.Time) (int, error) {
+	bkt := tx.Bucket([]byte(b))
+	expiry := tx.Bucket([]byte(b.ExpiryBucket()))
+	if bkt == nil || expiry == nil {
+		return 0, nil
+	}
+
+	// the cursor is not used to delete, it skips keys when doing so
+	var expired [][]byte
+	cur := expiry.Cursor()
+	for k, _ := cur.First(); k != nil && bytesToUint64(k[:8]) <= uint64(now.UnixNano()); k, _ = cur.Next() {
+		expired = append(expired, append([]byte(nil), k...))
+	}
+	for _, k := range expired {
+		if err := b.Delete(tx, bkt, k[8:]); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// Pending reports whether some entries expire, the sweeper is then needed.
+func (b Buckets) Pending(tx Tx) bool {
+	expiry := tx.Bucket([]byte(b.ExpiryBucket()))
+	if expiry == nil {
+		return false
+	}
+	k, _ := expiry.Cursor().First()
+	return k != nil
+}
+
+func expiryKey(deadline, key []byte) []byte {
+	k := make([]byte, 0, len(deadline)+len(key))
+	return append(append(k, deadline...), key...)
+}
+
+// Sweeper calls a sweep function periodically until stopped.
+type Sweeper struct {
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// StartSweeper starts a Sweeper calling sweep every interval,
+// storage.DefaultSweepInterval if zero.
+func StartSweeper(interval time.Duration, sweep func()) *Sweeper {
+	if interval == 0 {
+		interval = storage.DefaultSweepInterval
+	}
+	sw := &Sweeper{done: make(chan struct{})}
+	sw.wg.Add(1)
+	go func() {
+		defer sw.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sw.done:
+				return
+			case <-ticker.C:
+				sweep()
+			}
+		}
+	}()
+	return sw
+}
+
+// Stop stops the sweeper and waits for it to return, it can be called more
+// than once.
+func (sw *Sweeper) Stop() {
+	sw.once.Do(func() { close(sw.done) })
+	sw.wg.Wait()
+}
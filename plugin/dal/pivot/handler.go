@@ -3,6 +3,7 @@ package dal_pivot
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 	// pp "github.com/k0kubun/pp"
 
 	// internal
+	storage "github.com/sniperkit/colly-storage/pkg"
 	helper "github.com/sniperkit/colly-storage/pkg/helper"
 )
 
@@ -29,6 +31,7 @@ type Store struct {
 	mapper  mapper.Mapper
 	schema  []*dal.Collection
 	conf    *Config
+	actions *storage.ActionRegistry
 }
 
 // alias
@@ -86,21 +89,42 @@ func NewDataAbstractionLayer(config *Config) (*Store, error) {
 
 	// copy config
 	s.conf = config
+	s.registerActions()
 
 	return s, nil
 }
 
-func (c *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
-	switch name {
-	case "ping":
-		c.backend.Ping(args[0].(time.Duration))
+// Action runs one of the actions listed by Actions().
+func (s *Store) Action(name string, args ...interface{}) (map[string]*interface{}, error) {
+	return s.actions.Action(name, args...)
+}
 
-	case "getCollections":
-		c.backend.ListCollections()
+// Actions implements storage.Actioner.Actions()
+func (s *Store) Actions() []storage.ActionSpec {
+	return s.actions.Actions()
+}
 
-	}
+// Do implements storage.Actioner.Do()
+func (s *Store) Do(name string, args ...interface{}) (interface{}, error) {
+	return s.actions.Do(name, args...)
+}
 
-	return nil, errors.New("Action() method is not implemented yet")
+func (s *Store) registerActions() {
+	s.actions = storage.NewActionRegistry()
+	s.actions.MustRegister(storage.ActionSpec{
+		Name:        "ping",
+		Description: "checks the backend answers within the given timeout",
+		Args:        []reflect.Type{reflect.TypeOf(time.Duration(0))},
+	}, func(args ...interface{}) (interface{}, error) {
+		return nil, s.backend.Ping(args[0].(time.Duration))
+	})
+	s.actions.MustRegister(storage.ActionSpec{
+		Name:        "getCollections",
+		Description: "lists the collections of the backend",
+		Result:      reflect.TypeOf([]string(nil)),
+	}, func(args ...interface{}) (interface{}, error) {
+		return s.backend.ListCollections()
+	})
 }
 
 func (s *Store) Get(key string) (resp []byte, ok bool) {